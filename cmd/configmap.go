@@ -17,7 +17,8 @@
 package cmd
 
 import (
-	"fmt"
+	"github.com/openstack-k8s-operators/os-diff/pkg/common"
+	"github.com/openstack-k8s-operators/os-diff/pkg/godiff"
 	"github.com/openstack-k8s-operators/os-diff/pkg/servicecfg"
 
 	"github.com/spf13/cobra"
@@ -41,17 +42,28 @@ from a configmap file:
 or
 CMD1="ssh -F ssh.config standalone podman exec a6e1ca049eee"
 ./os-diff cfgmap-diff --configmap keystone-config-data --config /etc/keystone --remote --remode-cmd $CMD`,
-	Run: func(cmd *cobra.Command, args []string) {
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cmd.SilenceUsage = true
 		if fromRemote {
 			if remoteCmd == "" {
-				fmt.Println("Error: you must provide a --remote-cmd with --remote")
-				return
+				return common.UsageError("you must provide a --remote-cmd with --remote")
 			}
 		}
-		err := servicecfg.DiffConfigMap(configMap, configPath, fromRemote, remoteCmd)
-		if err != nil {
-			fmt.Println(err)
+		if err := godiff.SetOutputFormat(outputFormat); err != nil {
+			return common.UsageError("%w", err)
 		}
+		if err := setIgnoreRules(); err != nil {
+			return err
+		}
+		setSecrets()
+		if err := setNormalize(); err != nil {
+			return err
+		}
+		godiff.SetCatalog(!noCatalog, "")
+		setDefaultsDir()
+		godiff.SetMultiValuesOrdered(orderedMultiValues)
+		setKubeConfig()
+		return withJSONReport(diffStatus(servicecfg.DiffConfigMap(configMap, configPath, fromRemote, remoteCmd)))
 	},
 }
 
@@ -60,5 +72,15 @@ func init() {
 	cfgMapDiffCmd.Flags().StringVarP(&configPath, "config", "c", "", "OpenStack service INI config file path.")
 	cfgMapDiffCmd.Flags().BoolVar(&fromRemote, "remote", false, "Get Tripleo config remotely.")
 	cfgMapDiffCmd.Flags().StringVarP(&remoteCmd, "remote-cmd", "", "", "Remote Ssh command for pulling Tripleo config.")
+	cfgMapDiffCmd.Flags().StringVarP(&ignoreFile, "ignore-file", "", "", "YAML file describing the differences to ignore, default is ignore_file from os-diff.cfg.")
+	cfgMapDiffCmd.Flags().BoolVar(&noCatalog, "no-catalog", false, "Report the differences listed in the catalog of expected differences as regular differences.")
+	cfgMapDiffCmd.Flags().BoolVar(&showSecrets, "show-secrets", false, "Do not redact passwords and URL credentials in the output and the .diff files.")
+	cfgMapDiffCmd.Flags().BoolVar(&normalize, "normalize", false, "Compare INI values by type: true/1/yes, 01/1, a,b/a, b, trailing slashes in URLs...")
+	cfgMapDiffCmd.Flags().StringVarP(&normalizeFile, "normalize-file", "", "", "YAML file setting the type of INI options (bool, int, list, dict, url, string or auto).")
+	cfgMapDiffCmd.Flags().StringVarP(&defaultsDir, "defaults-dir", "", "", "Directory with the oslo-config-generator YAML or JSON output of the services (<service>.yaml).")
+	cfgMapDiffCmd.Flags().BoolVar(&orderedMultiValues, "ordered-multi-values", false, "Compare the values of repeated INI keys (MultiStrOpt) in order instead of as unordered sets.")
+	cfgMapDiffCmd.Flags().StringVarP(&outputFormat, "output-format", "", godiff.TextOutput, "Output format: text, json or unified.")
+	cfgMapDiffCmd.Flags().StringVar(&kubeconfig, "kubeconfig", "", "Kubeconfig file of the OpenShift cluster, default is kubeconfig from os-diff.cfg, KUBECONFIG or ~/.kube/config.")
+	cfgMapDiffCmd.Flags().StringVar(&namespace, "namespace", "", "Namespace of the configmap, default is namespace from os-diff.cfg or the namespace of the kubeconfig context.")
 	rootCmd.AddCommand(cfgMapDiffCmd)
 }
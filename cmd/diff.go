@@ -17,13 +17,16 @@
 package cmd
 
 import (
-	"fmt"
 	"os"
+	"strings"
 
+	"github.com/openstack-k8s-operators/os-diff/pkg/collectcfg"
+	"github.com/openstack-k8s-operators/os-diff/pkg/common"
 	"github.com/openstack-k8s-operators/os-diff/pkg/godiff"
 	"github.com/openstack-k8s-operators/os-diff/pkg/servicecfg"
 
 	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
 )
 
 // Diff parameters
@@ -39,6 +42,21 @@ var frompod bool
 var frompodman bool
 var podname string
 var iniFilters []string
+var outputFormat string
+var ignoreFile string
+var noCatalog bool
+var showSecrets bool
+var normalize bool
+var normalizeFile string
+var defaultsDir string
+var orderedMultiValues bool
+var merge bool
+var compareComments bool
+var unorderedArrays bool
+var arrayKeys []string
+var fileFormat string
+var kubeconfig string
+var namespace string
 
 var diffCmd = &cobra.Command{
 	Use:   "diff [path1] [path2]",
@@ -67,60 +85,79 @@ CMD1=oc exec -t neutron-cd94d8ccb-vq2gk -c neutron-api --
 
 ./os-diff diff ovs_external_ids.json edpm.crd --crd --service ovs_external_ids
 
-/!\ Important: remote option is only available for files comparison.`,
-	Run: func(cmd *cobra.Command, args []string) {
+* Example for the effective configuration of an operator .conf.d directory:
+
+./os-diff diff /tmp/collect_tripleo_configs/glance/etc/glance/glance-api.conf /tmp/collect_ocp_configs/glance/etc/glance/glance.conf.d --merge
+
+* Example for a JSON report:
+
+./os-diff diff tests/podman-containers/ tests/ocp-pods/ --output-format json
+
+* Example for a patch of the differences:
+
+./os-diff diff tests/podman-containers/nova.conf tests/ocp-pods/nova.conf --output-format unified > nova.patch
+
+* Example with ignore rules for the known and expected differences:
+
+./os-diff diff tests/podman-containers/ tests/ocp-pods/ --ignore-file examples/ignore.yaml
+
+Passwords, secrets and URL credentials are redacted in the console output, the
+.diff files and the JSON report, a short hash of the value is kept so changed
+secrets are still reported. Use --show-secrets to print them.
+
+With --normalize, INI values are compared by type (oslo.config bool, int,
+list, dict and URL) so True/true/1, "a,b"/"b, a" or quoted values are equal,
+the report still shows the original values. --normalize-file sets the type
+per option.
+
+With --defaults-dir, options set to their upstream default value on one side
+and unset on the other side are reported apart as default values, the
+directory holds the oslo-config-generator --format yaml output of each
+service, named after the service: nova.yaml, keystone.json...
+
+The format of the files (ini, json, yaml...) is detected from the file names
+and contents, files of an unknown format are compared line by line. Use
+--format to force it.
+
+JSON arrays are compared by position, use --unordered-arrays to ignore the
+order of their items and --array-keys to match the objects of an array by an
+identifier field, like the dest of the kolla config_files.
+
+Differences the openstack-k8s-operators always introduce (transport_url,
+database connection, memcache servers...) are reported as expected, the
+service is guessed from the paths or set with --service. Use --no-catalog
+to report them as regular differences.
+
+/!\ Important: remote option is only available for files comparison.
+
+Exit status is 0 when no differences are found, 1 when differences are found,
+2 for a usage or configuration error and 3 for a collection error (ssh, oc...).`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cmd.SilenceUsage = true
 		if len(args) < 2 {
-			fmt.Println("Error: Insufficient arguments. Please provide at least two file names.")
-			return
+			return common.UsageError("insufficient arguments, please provide at least two file names")
 		}
-		path1 := args[0]
-		path2 := args[1]
-		configPath := CheckFilesPresence(serviceCfgFile)
-		// Fix this, in the case where the --frompod or --frompodman is used, the service should be provided but not the podname
-		// because we already have the podname associated to the service.
-
-		if crd {
-			if frompod {
-				if podname == "" {
-					panic("Please provide a pod name with --frompod option.")
-				}
-				servicecfg.DiffServiceConfigFromPod(service, path2, path1, configPath)
-			} else if frompodman {
-				if podname == "" {
-					panic("Please provide a pod name with --frompodman option.")
-				}
-				servicecfg.DiffServiceConfigFromPodman(service, path2, path1, configPath)
-			} else {
-				servicecfg.DiffServiceConfigWithCRD(service, path2, path1, configPath)
-			}
-			return
+		if err := godiff.SetOutputFormat(outputFormat); err != nil {
+			return common.UsageError("%w", err)
 		}
-		if remote {
-			godiff.CompareFilesFromRemote(path1, path2, file1Cmd, file2Cmd, debug)
-		} else {
-			fi1, err := os.Stat(path1)
-			if err != nil {
-				fmt.Println(err)
-				return
-			}
-			fi2, err := os.Stat(path2)
-			if err != nil {
-				fmt.Println(err)
-				return
-			}
-			if fi1.IsDir() || fi2.IsDir() || quiet {
-				goDiff := &godiff.GoDiffDataStruct{
-					Origin:      path1,
-					Destination: path2,
-				}
-				err := goDiff.ProcessDirectories(false)
-				if err != nil {
-					return
-				}
-			} else {
-				godiff.CompareFiles(path1, path2, true, debug, iniFilters)
-			}
+		if err := godiff.SetFormat(fileFormat); err != nil {
+			return common.UsageError("%w", err)
+		}
+		if err := setIgnoreRules(); err != nil {
+			return err
+		}
+		setSecrets()
+		if err := setNormalize(); err != nil {
+			return err
 		}
+		if !crd {
+			godiff.SetCatalog(!noCatalog, service)
+		}
+		setDefaultsDir()
+		godiff.SetMultiValuesOrdered(orderedMultiValues)
+		godiff.SetCompareComments(compareComments)
+		godiff.SetJSONArrays(unorderedArrays, arrayKeys)
+		return withJSONReport(comparePaths(args[0], args[1]))
 	},
 }
 
@@ -132,10 +169,192 @@ func init() {
 	diffCmd.Flags().BoolVar(&remote, "remote", false, "Run the diff remotely.")
 	diffCmd.Flags().BoolVar(&crd, "crd", false, "Compare a CRDs with a config file.")
 	diffCmd.Flags().StringVarP(&serviceCfgFile, "service-config", "f", "config.yaml", "Path for the Yaml config where the services are described, default is config.yaml located in /etc/os-diff/config.yaml.")
-	diffCmd.Flags().StringVarP(&service, "service", "s", "", "Service to compare with a crd, could be one of the services: cinder, glance, ovs_external_ids, edpm... Should be used with --crd option..")
+	diffCmd.Flags().StringVarP(&service, "service", "s", "", "Service to compare with a crd, could be one of the services: cinder, glance, ovs_external_ids, edpm... Without --crd, service used for the catalog of expected differences.")
 	diffCmd.Flags().StringVarP(&podname, "podname", "p", "", "Container or podname from where to get the config file.")
 	diffCmd.Flags().BoolVar(&frompod, "frompod", false, "Get config file directly from OpenShift service Pod.")
 	diffCmd.Flags().BoolVar(&frompodman, "frompodman", false, "Get config file directly from OpenStack podman container.")
+	diffCmd.Flags().StringVarP(&outputFormat, "output-format", "", godiff.TextOutput, "Output format: text, json or unified.")
+	diffCmd.Flags().StringVarP(&ignoreFile, "ignore-file", "", "", "YAML file describing the differences to ignore, default is ignore_file from os-diff.cfg.")
+	diffCmd.Flags().BoolVar(&noCatalog, "no-catalog", false, "Report the differences listed in the catalog of expected differences as regular differences.")
+	diffCmd.Flags().BoolVar(&showSecrets, "show-secrets", false, "Do not redact passwords and URL credentials in the output and the .diff files.")
+	diffCmd.Flags().BoolVar(&normalize, "normalize", false, "Compare INI values by type: true/1/yes, 01/1, a,b/a, b, trailing slashes in URLs...")
+	diffCmd.Flags().StringVarP(&normalizeFile, "normalize-file", "", "", "YAML file setting the type of INI options (bool, int, list, dict, url, string or auto).")
+	diffCmd.Flags().StringVarP(&defaultsDir, "defaults-dir", "", "", "Directory with the oslo-config-generator YAML or JSON output of the services (<service>.yaml).")
+	diffCmd.Flags().BoolVar(&orderedMultiValues, "ordered-multi-values", false, "Compare the values of repeated INI keys (MultiStrOpt) in order instead of as unordered sets.")
+	diffCmd.Flags().BoolVar(&merge, "merge", false, "Compare the effective INI configuration: each path is a comma separated list of files and/or .conf.d directories merged in oslo.config order.")
+	diffCmd.Flags().BoolVar(&compareComments, "compare-comments", false, "Report the comments and empty lines changes of the files compared line by line.")
+	diffCmd.Flags().StringVarP(&fileFormat, "format", "", "", "Format of the compared files instead of detecting it from the file names and contents: "+strings.Join(godiff.ComparerNames(), ", ")+".")
+	diffCmd.Flags().BoolVar(&unorderedArrays, "unordered-arrays", false, "Compare the JSON arrays as unordered sets instead of by position.")
+	diffCmd.Flags().StringSliceVar(&arrayKeys, "array-keys", []string{}, "Fields identifying the objects of JSON arrays, the first one set in every object is used: --array-keys dest,path ..")
 	diffCmd.Flags().StringSliceVar(&iniFilters, "filters", []string{}, "Filter for ini sections: --filters default,connection ..")
+	diffCmd.Flags().StringVar(&kubeconfig, "kubeconfig", "", "Kubeconfig file of the OpenShift cluster for --frompod, default is kubeconfig from os-diff.cfg, KUBECONFIG or ~/.kube/config.")
+	diffCmd.Flags().StringVar(&namespace, "namespace", "", "Namespace of the pod for --frompod, default is namespace from os-diff.cfg or the namespace of the kubeconfig context.")
 	rootCmd.AddCommand(diffCmd)
 }
+
+// comparePaths compares the files, directories or services given to the
+// diff command and returns its status.
+func comparePaths(path1 string, path2 string) error {
+	configPath := CheckFilesPresence(serviceCfgFile)
+	// Fix this, in the case where the --frompod or --frompodman is used, the service should be provided but not the podname
+	// because we already have the podname associated to the service.
+
+	if crd {
+		setKubeConfig()
+		var found bool
+		var err error
+		if frompod {
+			if podname == "" {
+				return common.UsageError("please provide a pod name with --frompod option")
+			}
+			found, err = servicecfg.DiffServiceConfigFromPod(service, path2, path1, configPath)
+		} else if frompodman {
+			if podname == "" {
+				return common.UsageError("please provide a pod name with --frompodman option")
+			}
+			var closeConnections func()
+			closeConnections, err = setPodmanConnector()
+			if err != nil {
+				return err
+			}
+			defer closeConnections()
+			found, err = servicecfg.DiffServiceConfigFromPodman(service, path2, path1, configPath)
+		} else {
+			found, err = servicecfg.DiffServiceConfigWithCRD(service, path2, path1, configPath)
+		}
+		return diffStatus(found, err)
+	}
+	if remote {
+		return diffStatus(godiff.CompareFilesFromRemote(path1, path2, file1Cmd, file2Cmd, debug))
+	}
+	if merge {
+		// The errors carry their exit code, a file which can't be read
+		// isn't a usage error
+		result, err := godiff.CompareMergedFiles(path1, path2, debug, iniFilters)
+		if err != nil {
+			return err
+		}
+		return diffStatus(result.HasDifferences(), nil)
+	}
+	fi1, err := os.Stat(path1)
+	if err != nil {
+		return common.UsageError("%w", err)
+	}
+	fi2, err := os.Stat(path2)
+	if err != nil {
+		return common.UsageError("%w", err)
+	}
+	if fi1.IsDir() || fi2.IsDir() || quiet {
+		goDiff := &godiff.GoDiffDataStruct{
+			Origin:      path1,
+			Destination: path2,
+		}
+		err := goDiff.ProcessDirectories(false)
+		return diffStatus(goDiff.HasDifferences(), err)
+	}
+	result, err := godiff.CompareFiles(path1, path2, true, debug, iniFilters)
+	if err != nil {
+		return err
+	}
+	return diffStatus(result.HasDifferences(), nil)
+}
+
+// setIgnoreRules loads the ignore rules from --ignore-file or from the
+// ignore_file option of os-diff.cfg.
+func setIgnoreRules() error {
+	rulesFile := ignoreFile
+	if rulesFile == "" {
+		if cfg, ok := viper.Get("config").(*common.ODConfig); ok {
+			rulesFile = cfg.Default.IgnoreFile
+		}
+	}
+	if rulesFile == "" {
+		return nil
+	}
+	rules, err := godiff.LoadIgnoreRules(rulesFile)
+	if err != nil {
+		return common.UsageError("%w", err)
+	}
+	godiff.SetIgnoreRules(rules)
+	return nil
+}
+
+// setSecrets configures the redaction of the secrets, extra option names
+// can be set with secret_keys and the key of the tags with redaction_key in
+// os-diff.cfg.
+func setSecrets() {
+	godiff.SetShowSecrets(showSecrets)
+	if cfg, ok := viper.Get("config").(*common.ODConfig); ok {
+		godiff.AddSecretKeys(cfg.Default.SecretKeys)
+		godiff.SetRedactionKey(cfg.Default.RedactionKey)
+	}
+}
+
+// setNormalize configures the INI values normalization from the flags or
+// from normalize and normalize_file in os-diff.cfg.
+func setNormalize() error {
+	all := normalize
+	rulesFile := normalizeFile
+	if cfg, ok := viper.Get("config").(*common.ODConfig); ok {
+		all = all || cfg.Default.Normalize
+		if rulesFile == "" {
+			rulesFile = cfg.Default.NormalizeFile
+		}
+	}
+	var rules []godiff.NormalizeRule
+	if rulesFile != "" {
+		var err error
+		rules, err = godiff.LoadNormalizeRules(rulesFile)
+		if err != nil {
+			return common.UsageError("%w", err)
+		}
+	}
+	godiff.SetNormalize(all, rules)
+	return nil
+}
+
+// setDefaultsDir sets the oslo-config-generator outputs directory from the
+// flag or from defaults_dir in os-diff.cfg.
+func setDefaultsDir() {
+	dir := defaultsDir
+	if cfg, ok := viper.Get("config").(*common.ODConfig); ok && dir == "" {
+		dir = cfg.Default.DefaultsDir
+	}
+	godiff.SetDefaultsDir(dir)
+}
+
+// setKubeConfig sets the kubeconfig and the namespace of the OpenShift
+// client from the flags or from kubeconfig and namespace in the Openshift
+// section of os-diff.cfg.
+func setKubeConfig() {
+	path, ns := kubeconfig, namespace
+	if cfg, ok := viper.Get("config").(*common.ODConfig); ok {
+		if path == "" {
+			path = cfg.Openshift.Kubeconfig
+		}
+		if ns == "" {
+			ns = cfg.Openshift.Namespace
+		}
+	}
+	collectcfg.SetKubeConfig(path, ns)
+}
+
+// setPodmanConnector connects servicecfg to the TripleO host of the
+// containers with the ssh_cmd, director_host, connection and container_engine
+// of the Tripleo section of os-diff.cfg. The returned function closes the
+// connections.
+func setPodmanConnector() (func(), error) {
+	config, ok := viper.Get("config").(*common.ODConfig)
+	if !ok {
+		return nil, common.UsageError("unable to load os-diff configuration: %s", osDiffConfig)
+	}
+	if err := collectcfg.SetContainerEngine(config.Tripleo.ContainerEngine); err != nil {
+		return nil, common.UsageError("%w", err)
+	}
+	connect, directorHost, closeConnections, err := tripleoConnector(config)
+	if err != nil {
+		return nil, err
+	}
+	servicecfg.SetPodmanConnector(connect, directorHost)
+	return closeConnections, nil
+}
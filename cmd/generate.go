@@ -37,6 +37,11 @@ var generateCmd = &cobra.Command{
 	./os-diff gen --service glance --config my-conf.ini --output glance.patch`,
 	Run: func(cmd *cobra.Command, args []string) {
 		if pullRemote {
+			closeConnections, err := setPodmanConnector()
+			if err != nil {
+				panic(err)
+			}
+			defer closeConnections()
 			servicecfg.GenerateConfigPatchFromRemote(serviceName, configFileName, outputFile, serviceEnable, podmanContainerName)
 		} else {
 			err := servicecfg.GenerateConfigPatchFromIni(serviceName, configFileName, outputFile, serviceEnable)
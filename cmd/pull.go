@@ -32,12 +32,14 @@ var update bool
 var updateOnly bool
 var serviceConfig string
 var filters []string
+var parallelJobs int
+var parallelPerHost int
 
 var pullCmd = &cobra.Command{
 	Use:   "pull",
-	Short: "Pull configurations from Podman or OCP",
+	Short: "Pull configurations from Podman, Docker or OCP",
 	Long: `This command pulls configuration files by services from Podman
-environment or OCP. For example:
+environment, Docker with container_engine=docker in os-diff.cfg, or OCP. For example:
 ./os-diff pull --env=tripleo
 You can set configuration in your os-diff.cfg or provide output directory via the command line:
 ./os-diff pull -e ocp -o /tmp/myconfigdir -s my-service-config-file
@@ -46,64 +48,97 @@ You can also update the config.yaml file with the information from your TripleO
 This command will add the podman and image IDs in the config.yaml or also:
 ./os-pull pull --update
 This command will populate the config.yaml file with the podman and image Ids and pull the config too.
+The services and hosts can be pulled concurrently:
+./os-diff pull --parallel 20 --parallel-per-host 4
+The OpenShift pods are read with the Kubernetes API, from the namespace of the current kubeconfig context
+unless set:
+./os-diff pull -e ocp --namespace openstack
+
+Exit status is 0 on success, 2 for a usage or configuration error and 3 when
+some configuration could not be collected.
 `,
-	Run: func(cmd *cobra.Command, args []string) {
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cmd.SilenceUsage = true
 
 		// Get config:
-		config := viper.Get("config").(*common.ODConfig)
+		config, ok := viper.Get("config").(*common.ODConfig)
+		if !ok {
+			return common.UsageError("unable to load os-diff configuration: %s", osDiffConfig)
+		}
 		if serviceConfig == "" {
 			serviceConfig = config.Default.ServiceConfigFile
 		}
 		configPath := CheckFilesPresence(serviceConfig)
+		if parallelJobs < 1 || parallelPerHost < 1 {
+			return common.UsageError("--parallel and --parallel-per-host should be at least 1")
+		}
+		collectcfg.SetParallel(parallelJobs, parallelPerHost)
+		if err := collectcfg.SetContainerEngine(config.Tripleo.ContainerEngine); err != nil {
+			return common.UsageError("%w", err)
+		}
 
 		if cloud == "ocp" {
 			// Test OCP connection:
-			if !common.TestOCConnection() {
-				fmt.Println("OC not connected, you need to logged in before running this command...")
-				return
+			setKubeConfig()
+			client, err := collectcfg.GetKubeClient()
+			if err == nil {
+				err = client.TestConnection()
 			}
-			// OCP Settings
-			localOCPDir := config.Openshift.OcpLocalConfigPath
-			err := collectcfg.FetchConfigFromEnv(configPath, localOCPDir, "", false, config.Openshift.Connection, "", "", filters, "")
 			if err != nil {
-				fmt.Println("Error while collecting config: ", err)
-				return
+				return common.CollectionError("not connected to OpenShift, you need to logged in before running this command: %w", err)
 			}
+			// OCP Settings
+			localOCPDir := config.Openshift.OcpLocalConfigPath
+			return collectcfg.FetchConfigFromEnv(configPath, localOCPDir, "", false, config.Openshift.Connection, collectcfg.LocalConnector(false), "", filters)
 		} else if cloud == "tripleo" {
 			// TRIPLEO Settings:
-			sshCmd := config.Tripleo.SshCmd
-			fullCmd, directorHost, err := common.BuildFullSshCmd(sshCmd, config.Tripleo.DirectorHost)
-			collectcfg.Sudo = config.Tripleo.Sudo
+			connect, directorHost, closeConnections, err := tripleoConnector(config)
 			if err != nil {
-				fmt.Println(err)
-				return
+				return err
 			}
+			defer closeConnections()
 			remoteConfigDir := config.Tripleo.RemoteConfigPath
 			localConfigDir := config.Tripleo.LocalConfigPath
-			if !common.TestSshConnection(fullCmd) {
-				fmt.Println("Please check your SSH configuration: " + fullCmd)
-				return
-			} else {
-				fmt.Println("SSH connection successful !")
+			director, err := connect(directorHost)
+			if err == nil {
+				_, err = director.Exec("true")
 			}
+			if err != nil {
+				return common.CollectionError("please check your SSH configuration: %s: %w", config.Tripleo.SshCmd, err)
+			}
+			fmt.Println("SSH connection successful !")
 			if update || updateOnly {
-				collectcfg.SetTripleODataEnv(configPath, fullCmd, filters, true)
+				if err := collectcfg.SetTripleODataEnv(configPath, director, filters, true); err != nil {
+					return err
+				}
 				if updateOnly {
-					return
+					return nil
 				}
 			}
-			err = collectcfg.FetchConfigFromEnv(configPath, localConfigDir, remoteConfigDir, true, config.Tripleo.Connection, fullCmd, directorHost, filters, sshCmd)
-			if err != nil {
-				fmt.Println("Error while collecting config: ", err)
-				return
-			}
-		} else {
-			fmt.Println("Error unkown cloud", cloud)
-			return
+			return collectcfg.FetchConfigFromEnv(configPath, localConfigDir, remoteConfigDir, true, config.Tripleo.Connection, connect, directorHost, filters)
 		}
+		return common.UsageError("unknown cloud: %s, could be: ocp or tripleo", cloud)
 	},
 }
 
+// tripleoConnector returns the connector to the TripleO hosts: the local
+// host for a local connection, else native SSH connections configured by
+// ssh_cmd and director_host.
+func tripleoConnector(config *common.ODConfig) (collectcfg.Connector, string, func(), error) {
+	if config.Tripleo.Connection == "local" {
+		return collectcfg.LocalConnector(config.Tripleo.Sudo), config.Tripleo.DirectorHost, func() {}, nil
+	}
+	fullCmd, directorHost, err := common.BuildFullSshCmd(config.Tripleo.SshCmd, config.Tripleo.DirectorHost)
+	if err != nil {
+		return nil, "", nil, common.UsageError("%w", err)
+	}
+	connector, err := collectcfg.NewSSHConnector(fullCmd, config.Tripleo.Sudo)
+	if err != nil {
+		return nil, "", nil, common.UsageError("invalid ssh_cmd: %w", err)
+	}
+	return connector.Connect, directorHost, connector.Close, nil
+}
+
 func init() {
 	pullCmd.Flags().StringVarP(&cloud, "env", "e", "tripleo", "Service engine, could be: ocp or tripleo.")
 	pullCmd.Flags().StringVarP(&output_dir, "output_dir", "o", "", "Output directory for the configuration files.")
@@ -111,5 +146,9 @@ func init() {
 	pullCmd.Flags().StringSliceVar(&filters, "filters", []string{}, "Filter Openstack services: --filters glance_api,nova_api,keystone ..")
 	pullCmd.Flags().BoolVar(&update, "update", false, "Update config.yaml with Podman informations.")
 	pullCmd.Flags().BoolVar(&updateOnly, "update-only", false, "Update only config.yaml with Podman informations and not pull configurations from services.")
+	pullCmd.Flags().IntVar(&parallelJobs, "parallel", 1, "Number of services and hosts pulled at the same time.")
+	pullCmd.Flags().IntVar(&parallelPerHost, "parallel-per-host", 4, "Number of services pulled at the same time from a host.")
+	pullCmd.Flags().StringVar(&kubeconfig, "kubeconfig", "", "Kubeconfig file of the OpenShift cluster, default is kubeconfig from os-diff.cfg, KUBECONFIG or ~/.kube/config.")
+	pullCmd.Flags().StringVar(&namespace, "namespace", "", "Namespace of the OpenShift services, default is namespace from os-diff.cfg or the namespace of the kubeconfig context.")
 	rootCmd.AddCommand(pullCmd)
 }
@@ -17,11 +17,13 @@
 package cmd
 
 import (
+	"errors"
 	"fmt"
 	"os"
 	"path/filepath"
 
 	"github.com/openstack-k8s-operators/os-diff/pkg/common"
+	"github.com/openstack-k8s-operators/os-diff/pkg/godiff"
 
 	"github.com/spf13/cobra"
 	"github.com/spf13/viper"
@@ -42,11 +44,38 @@ to a new Keystone pod which has been migrated.`,
 
 // Execute adds all child commands to the root command and sets flags appropriately.
 // This is called by main.main(). It only needs to happen once to the rootCmd.
+// The exit code is set from the error returned by the command, see common.ExitCode.
 func Execute() {
+	rootCmd.SilenceErrors = true
 	err := rootCmd.Execute()
+	if err != nil && !errors.Is(err, common.ErrDifferencesFound) {
+		fmt.Fprintln(os.Stderr, "Error:", err)
+	}
+	os.Exit(common.ExitCode(err))
+}
+
+// diffStatus turns the result of a comparison into the error returned by
+// the diff commands.
+func diffStatus(found bool, err error) error {
 	if err != nil {
-		os.Exit(1)
+		return err
+	}
+	if found {
+		return common.ErrDifferencesFound
+	}
+	return nil
+}
+
+// withJSONReport writes the JSON report once the comparison is done, a report
+// which can't be written replaces the status of the comparison.
+func withJSONReport(err error) error {
+	if !godiff.IsJSONOutput() {
+		return err
+	}
+	if werr := godiff.WriteJSONReport(os.Stdout); werr != nil {
+		return fmt.Errorf("failed to write the JSON report: %w", werr)
 	}
+	return err
 }
 
 func init() {
@@ -85,7 +114,7 @@ func initConfig(cmd *cobra.Command) error {
 func CheckFilesPresence(configFile string) string {
 	_, err := os.Stat(configFile)
 	if err == nil {
-		fmt.Println("Found " + configFile + " ... using it !")
+		fmt.Fprintln(os.Stderr, "Found "+configFile+" ... using it !")
 		return configFile
 	}
 
@@ -94,12 +123,12 @@ func CheckFilesPresence(configFile string) string {
 	etcConfigFile := filepath.Join("/etc/os-diff/", configFile)
 	_, err = os.Stat(etcConfigFile)
 	if err == nil {
-		fmt.Println("Found os-diff.cfg in /etc/os-diff/ ... using it !")
+		fmt.Fprintln(os.Stderr, "Found os-diff.cfg in /etc/os-diff/ ... using it !")
 		return etcConfigFile
 	}
 
 	// If config.yaml doesn't exist in both locations, raise an error and end the program
-	fmt.Println("Error: " + configFile + "not found. Unable to find it in the current working directory or /etc/os-diff.")
-	os.Exit(1)
+	fmt.Fprintln(os.Stderr, "Error: "+configFile+" not found. Unable to find it in the current working directory or /etc/os-diff.")
+	os.Exit(common.ExitUsage)
 	return ""
 }
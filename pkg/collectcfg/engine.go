@@ -0,0 +1,112 @@
+/*
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * Copyright 2023 Red Hat, Inc.
+ *
+ */
+
+package collectcfg
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+const (
+	Podman = "podman"
+	Docker = "docker"
+)
+
+// Container engine of the TripleO hosts
+var containerEngine = Podman
+
+// SetContainerEngine sets the container engine of the TripleO hosts, podman
+// or docker for the older releases, podman when empty.
+func SetContainerEngine(engine string) error {
+	switch engine {
+	case "":
+		containerEngine = Podman
+	case Podman, Docker:
+		containerEngine = engine
+	default:
+		return fmt.Errorf("unknown container engine: %s, should be %s or %s", engine, Podman, Docker)
+	}
+	return nil
+}
+
+// ContainerFile returns the content of a file of a running container, read
+// with the container engine of the TripleO hosts.
+func ContainerFile(t Transport, container string, path string) ([]byte, error) {
+	return t.Exec(containerEngine, "exec", container, "cat", path)
+}
+
+// containerListArgs returns the ps arguments listing the containers as
+// JSON: an array with podman, an object per line with docker.
+func containerListArgs(all bool) []string {
+	args := []string{"ps"}
+	if all {
+		args = append(args, "-a")
+	}
+	if containerEngine == Docker {
+		// The json format only exists since docker 23
+		return append(args, "--no-trunc", "--format", "{{json .}}")
+	}
+	return append(args, "--format", "json")
+}
+
+// ContainerNames are the names of a container, a list with podman, a comma
+// separated string with docker and podman 1.x.
+type ContainerNames []string
+
+func (n *ContainerNames) UnmarshalJSON(data []byte) error {
+	var names []string
+	if err := json.Unmarshal(data, &names); err == nil {
+		*n = names
+		return nil
+	}
+	var joined string
+	if err := json.Unmarshal(data, &joined); err != nil {
+		return err
+	}
+	*n = nil
+	for _, name := range strings.Split(joined, ",") {
+		if name = strings.TrimPrefix(strings.TrimSpace(name), "/"); name != "" {
+			*n = append(*n, name)
+		}
+	}
+	return nil
+}
+
+// parseContainers reads the containers listed by podman or docker ps, as a
+// JSON array or a JSON object per line.
+func parseContainers(output []byte) ([]PodmanContainer, error) {
+	var containers []PodmanContainer
+	output = bytes.TrimSpace(output)
+	if bytes.HasPrefix(output, []byte("[")) {
+		err := json.Unmarshal(output, &containers)
+		return containers, err
+	}
+	for _, line := range bytes.Split(output, []byte("\n")) {
+		if line = bytes.TrimSpace(line); len(line) == 0 {
+			continue
+		}
+		var container PodmanContainer
+		if err := json.Unmarshal(line, &container); err != nil {
+			return nil, err
+		}
+		containers = append(containers, container)
+	}
+	return containers, nil
+}
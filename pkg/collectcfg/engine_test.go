@@ -0,0 +1,98 @@
+/*
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * Copyright 2023 Red Hat, Inc.
+ *
+ */
+
+package collectcfg_test
+
+import (
+	"testing"
+
+	"github.com/openstack-k8s-operators/os-diff/pkg/collectcfg"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestContainerEngine(t *testing.T) {
+	tests := []struct {
+		name    string
+		engine  string
+		command string
+		output  string
+		cp      string
+	}{
+		{
+			name:    "podman",
+			engine:  "podman",
+			command: "podman ps -a --format json",
+			output:  `[{"ID": "0123456789abcdef", "Image": "nova", "Names": ["nova_api"]}]`,
+			cp:      "podman cp 0123456789ab:/etc/nova /tmp/tripleo/nova/etc",
+		},
+		{
+			name:    "podman 1.x",
+			engine:  "",
+			command: "podman ps -a --format json",
+			output:  `[{"Id": "0123456789abcdef", "Image": "nova", "Names": "nova_api"}]`,
+			cp:      "podman cp 0123456789ab:/etc/nova /tmp/tripleo/nova/etc",
+		},
+		{
+			name:    "docker",
+			engine:  "docker",
+			command: "docker ps -a --no-trunc --format '{{json .}}'",
+			output: `{"Command":"\"kolla_start\"","ID":"0123456789abcdef0123","Image":"nova","Names":"nova_api","Status":"Up 2 hours"}
+{"Command":"\"kolla_start\"","ID":"fedcba9876543210fedc","Image":"keystone","Names":"keystone,keystone_cron","Status":"Up 2 hours"}
+`,
+			cp: "docker cp 0123456789ab:/etc/nova /tmp/tripleo/nova/etc",
+		},
+	}
+	defer collectcfg.SetContainerEngine("podman")
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.NoError(t, collectcfg.SetContainerEngine(tt.engine))
+			fake := collectcfg.NewFakeTransport()
+			fake.Outputs[tt.command] = tt.output
+
+			id, err := collectcfg.GetPodmanId("nova_api", fake)
+			assert.NoError(t, err)
+			assert.Equal(t, "0123456789ab", id)
+			assert.NoError(t, collectcfg.PullPodmanFiles(id, "/etc/nova", "/tmp/tripleo/nova/etc", fake))
+			assert.Equal(t, []string{tt.command, tt.cp}, fake.Commands)
+		})
+	}
+
+	assert.Error(t, collectcfg.SetContainerEngine("containerd"))
+}
+
+func TestContainerFile(t *testing.T) {
+	defer collectcfg.SetContainerEngine("podman")
+	for _, engine := range []string{"podman", "docker"} {
+		assert.NoError(t, collectcfg.SetContainerEngine(engine))
+		fake := collectcfg.NewFakeTransport()
+		fake.Outputs[engine+" exec nova_api cat /etc/nova/nova.conf"] = "[DEFAULT]\ndebug=True\n"
+		out, err := collectcfg.ContainerFile(fake, "nova_api", "/etc/nova/nova.conf")
+		assert.NoError(t, err)
+		assert.Equal(t, "[DEFAULT]\ndebug=True\n", string(out))
+	}
+}
+
+func TestDockerContainerNames(t *testing.T) {
+	assert.NoError(t, collectcfg.SetContainerEngine("docker"))
+	defer collectcfg.SetContainerEngine("podman")
+	fake := collectcfg.NewFakeTransport()
+	fake.Outputs["docker ps -a --no-trunc --format '{{json .}}'"] = `{"ID":"fedcba9876543210","Image":"keystone","Names":"keystone, keystone_cron"}`
+
+	id, err := collectcfg.GetPodmanId("keystone_cron", fake)
+	assert.NoError(t, err)
+	assert.Equal(t, "fedcba987654", id)
+}
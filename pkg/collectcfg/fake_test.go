@@ -0,0 +1,132 @@
+/*
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * Copyright 2023 Red Hat, Inc.
+ *
+ */
+
+package collectcfg
+
+import (
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// FakeTransport is a transport for the tests: it records the commands and
+// serves files from memory. It is only built with the tests, the
+// collectcfg_test package uses it as well.
+type FakeTransport struct {
+	// Files of the host by path, the directories are implied
+	Files map[string]string
+	// Output of the commands by command line (ShellJoin), the commands not
+	// listed succeed without output unless Fail is set
+	Outputs map[string]string
+	// Command lines failing
+	Fail map[string]bool
+	// Command lines run by Exec
+	Commands []string
+	mutex    sync.Mutex
+}
+
+// NewFakeTransport returns an empty fake transport.
+func NewFakeTransport() *FakeTransport {
+	return &FakeTransport{Files: map[string]string{}, Outputs: map[string]string{}, Fail: map[string]bool{}}
+}
+
+// FakeConnector returns a connector serving the fake transports by host.
+func FakeConnector(transports map[string]*FakeTransport) Connector {
+	return func(host string) (Transport, error) {
+		if t, ok := transports[host]; ok {
+			return t, nil
+		}
+		return nil, fmt.Errorf("unknown host: %s", host)
+	}
+}
+
+func (t *FakeTransport) Exec(name string, args ...string) ([]byte, error) {
+	argv := append([]string{name}, args...)
+	cmd := ShellJoin(argv)
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	t.Commands = append(t.Commands, cmd)
+	if t.Fail[cmd] {
+		return nil, commandError(argv, fmt.Errorf("exit status 1"), "failed")
+	}
+	return []byte(t.Outputs[cmd]), nil
+}
+
+func (t *FakeTransport) CopyFrom(remotePath string, localPath string) error {
+	remotePath = path.Clean(remotePath)
+	found := false
+	for name, content := range t.Files {
+		if name != remotePath && !strings.HasPrefix(name, remotePath+"/") {
+			continue
+		}
+		found = true
+		target := filepath.Join(localPath, filepath.FromSlash(strings.TrimPrefix(name, remotePath)))
+		if err := os.MkdirAll(filepath.Dir(target), 0755); err != nil {
+			return err
+		}
+		if err := os.WriteFile(target, []byte(content), 0644); err != nil {
+			return err
+		}
+	}
+	if !found {
+		return fmt.Errorf("%s: no such file or directory", remotePath)
+	}
+	return nil
+}
+
+func (t *FakeTransport) Stat(p string) (FileInfo, error) {
+	p = path.Clean(p)
+	if content, ok := t.Files[p]; ok {
+		return FileInfo{Name: path.Base(p), Size: int64(len(content)), ModTime: time.Unix(0, 0)}, nil
+	}
+	for name := range t.Files {
+		if strings.HasPrefix(name, p+"/") {
+			return FileInfo{Name: path.Base(p), IsDir: true, ModTime: time.Unix(0, 0)}, nil
+		}
+	}
+	return FileInfo{}, fmt.Errorf("%s: no such file or directory", p)
+}
+
+func (t *FakeTransport) ListDir(p string) ([]FileInfo, error) {
+	p = path.Clean(p)
+	seen := map[string]bool{}
+	var infos []FileInfo
+	for name := range t.Files {
+		if !strings.HasPrefix(name, p+"/") {
+			continue
+		}
+		entry, _, _ := strings.Cut(strings.TrimPrefix(name, p+"/"), "/")
+		if !seen[entry] {
+			seen[entry] = true
+			info, err := t.Stat(p + "/" + entry)
+			if err != nil {
+				return nil, err
+			}
+			infos = append(infos, info)
+		}
+	}
+	if len(infos) == 0 {
+		return nil, fmt.Errorf("%s: no such directory", p)
+	}
+	sort.Slice(infos, func(i, j int) bool { return infos[i].Name < infos[j].Name })
+	return infos, nil
+}
@@ -18,11 +18,12 @@
 package collectcfg
 
 import (
-	"encoding/json"
+	"errors"
 	"fmt"
 	"os"
-	"os/exec"
 	"path"
+	"path/filepath"
+	"sort"
 	"strings"
 
 	"github.com/openstack-k8s-operators/os-diff/pkg/common"
@@ -31,13 +32,12 @@ import (
 )
 
 var config common.Config
-var Sudo bool
 
 // TripleO information structures:
 type PodmanContainer struct {
-	Image string   `json:"Image"`
-	ID    string   `json:"ID"`
-	Names []string `json:"Names"`
+	Image string         `json:"Image"`
+	ID    string         `json:"ID"`
+	Names ContainerNames `json:"Names"`
 }
 
 func dumpConfigFile(configPath string) error {
@@ -54,187 +54,293 @@ func dumpConfigFile(configPath string) error {
 	return nil
 }
 
-func PullConfigs(configDir string, tripleo bool, sshCmd string, undercloud string, filters []string) error {
-	// Pull configuration service by service
+func PullConfigs(configDir string, tripleo bool, connect Connector, undercloud string, filters []string) error {
+	// Pull configuration service by service, and host by host
+	var jobs []pullJob
+	for _, service := range selectedServices(filters) {
+		service := service
+		// The OpenShift services without hosts run in pods, the ones with
+		// hosts, on the data plane nodes, are collected from the hosts
+		if tripleo && (config.Services[service].PodmanName == "" || config.Services[service].PodmanId == "") ||
+			!tripleo && len(config.Services[service].Hosts) == 0 {
+			jobs = append(jobs, pullJob{service: service, host: undercloud, run: func() error {
+				return PullConfig(service, tripleo, configDir, connect, undercloud)
+			}})
+		} else {
+			jobs = append(jobs, hostJobs(service, configDir, connect, undercloud)...)
+		}
+	}
+	return runJobs(jobs, true)
+}
+
+// selectedServices returns the sorted names of the enabled services matching
+// the filters, all of them without filters.
+func selectedServices(filters []string) []string {
 	filterMap := make(map[string]struct{})
 	for _, filter := range filters {
 		filterMap[filter] = struct{}{}
 	}
+	var services []string
 	for service := range config.Services {
 		if config.Services[service].Enable {
 			if _, ok := filterMap[service]; ok || len(filters) == 0 {
-				if tripleo && (config.Services[service].PodmanName == "" || config.Services[service].PodmanId == "") {
-					PullConfig(service, tripleo, configDir, sshCmd)
-				} else {
-					PullConfigFromHosts(service, configDir, sshCmd, undercloud)
-				}
+				services = append(services, service)
 			}
 		}
 	}
-	return nil
+	sort.Strings(services)
+	return services
 }
 
-func PullConfig(serviceName string, tripleo bool, configDir string, sshCmd string) error {
+// joinErrors aggregates the errors of a pull, a failing service or path
+// should not stop the collection of the others. The aggregated errors are
+// flattened and the repeated ones, an unreachable host failing every step,
+// are reported once.
+func joinErrors(errs []error) error {
+	var flat pullErrors
+	seen := map[string]bool{}
+	for _, err := range errs {
+		nested := pullErrors{err}
+		errors.As(err, &nested)
+		for _, e := range nested {
+			if !seen[e.Error()] {
+				seen[e.Error()] = true
+				flat = append(flat, e)
+			}
+		}
+	}
+	if len(flat) == 0 {
+		return nil
+	}
+	if len(flat) == 1 {
+		return flat[0]
+	}
+	return &common.ExitError{Code: common.ExitCollection, Err: flat}
+}
+
+// pullErrors are the errors of the services and hosts which failed.
+type pullErrors []error
+
+func (e pullErrors) Error() string {
+	var msgs []string
+	for _, err := range e {
+		msgs = append(msgs, err.Error())
+	}
+	return fmt.Sprintf("%d errors while collecting configuration:\n%s", len(e), strings.Join(msgs, "\n"))
+}
+
+// connectHost returns the transport to a host as a collection error.
+func connectHost(connect Connector, host string) (Transport, error) {
+	t, err := connect(host)
+	if err != nil {
+		return nil, common.CollectionError("failed to connect to %s: %w", host, err)
+	}
+	return t, nil
+}
+
+func PullConfig(serviceName string, tripleo bool, configDir string, connect Connector, undercloud string) error {
 	// Pull configuration from TripleO Podman or OCP Pods
+	var errs []error
 	if tripleo {
+		t, err := connectHost(connect, undercloud)
+		if err != nil {
+			return err
+		}
 		var podmanId string
 		if config.Services[serviceName].PodmanId != "" {
 			podmanId = config.Services[serviceName].PodmanId
 		} else {
-			podmanId, _ = GetPodmanId(config.Services[serviceName].PodmanName, sshCmd)
+			podmanId, err = GetPodmanId(config.Services[serviceName].PodmanName, t)
+			if err != nil {
+				return common.CollectionError("failed to get %s id for %s: %w", containerEngine, config.Services[serviceName].PodmanName, err)
+			}
 		}
-		if len(strings.TrimSpace(podmanId)) > 0 {
-			for _, path := range config.Services[serviceName].Path {
-				dirPath := getDir(strings.TrimRight(path, "/"))
-				PullPodmanFiles(podmanId, path, configDir+"/"+serviceName+"/"+dirPath, sshCmd)
+		if len(strings.TrimSpace(podmanId)) == 0 {
+			return common.CollectionError("%s name not found for service %s: %s", containerEngine, serviceName, config.Services[serviceName].PodmanName)
+		}
+		for _, path := range config.Services[serviceName].Path {
+			dirPath := getDir(strings.TrimRight(path, "/"))
+			err := PullPodmanFiles(podmanId, path, configDir+"/"+serviceName+"/"+dirPath, t)
+			recordSource(common.ManifestFile{
+				Path:       serviceName + "/" + path,
+				Service:    serviceName,
+				Host:       undercloud,
+				Source:     path,
+				PodmanId:   podmanId,
+				PodmanName: config.Services[serviceName].PodmanName,
+				Image:      config.Services[serviceName].PodmanImage,
+			}, err)
+			if err != nil {
+				errs = append(errs, err)
 			}
-		} else {
-			fmt.Println("Error, Podman name not found, skipping ..." + config.Services[serviceName].PodmanName)
 		}
 	} else {
-		podId, _ := GetPodId(config.Services[serviceName].PodName)
-		if len(strings.TrimSpace(podId)) > 0 {
-			for _, path := range config.Services[serviceName].Path {
-				PullPodFiles(podId, config.Services[serviceName].ContainerName, path, configDir+"/"+serviceName+"/"+path)
+		podId, err := GetPodId(config.Services[serviceName].PodName, config.Services[serviceName].PodSelector)
+		if err != nil {
+			return common.CollectionError("failed to get pod id for %s: %w", config.Services[serviceName].PodName, err)
+		}
+		if len(strings.TrimSpace(podId)) == 0 {
+			return common.CollectionError("pod name not found for service %s: %s", serviceName, config.Services[serviceName].PodName)
+		}
+		for _, path := range config.Services[serviceName].Path {
+			err := PullPodFiles(podId, config.Services[serviceName].ContainerName, path, configDir+"/"+serviceName+"/"+path)
+			recordSource(common.ManifestFile{
+				Path:      serviceName + "/" + path,
+				Service:   serviceName,
+				Source:    path,
+				Pod:       podId,
+				Container: config.Services[serviceName].ContainerName,
+			}, err)
+			if err != nil {
+				errs = append(errs, err)
 			}
-		} else {
-			fmt.Println("Error, Pod name not found, skipping ..." + config.Services[serviceName].PodName)
 		}
 	}
-	return nil
+	return joinErrors(errs)
 }
 
-func GetPodmanIds(sshCmd string, all bool) ([]byte, error) {
-	var cmd string
-	if Sudo {
-		sshCmd = sshCmd + " sudo "
-	}
-	if all {
-		cmd = sshCmd + " podman ps -a --format json"
-	} else {
-		cmd = sshCmd + " podman ps --format json"
-	}
-	output, err := exec.Command("bash", "-c", cmd).Output()
-	return output, err
+// GetPodmanIds lists the containers with the container engine.
+func GetPodmanIds(t Transport, all bool) ([]byte, error) {
+	return t.Exec(containerEngine, containerListArgs(all)...)
 }
 
-func PullConfigFromHosts(service string, configDir string, sshCmd string, undercloud string) error {
+func PullConfigFromHosts(service string, configDir string, connect Connector, undercloud string) error {
 	// Pull confugiration for a given service non hosted on Podman and OCP containers
-	if len(config.Services[service].Hosts) != 0 {
-		// if the services are not on the Undercloud/Director node
-		for _, h := range config.Services[service].Hosts {
-			sshCmd = strings.Replace(sshCmd, undercloud, h, -1)
-			// check if its config files or command output
-			if config.Services[service].ServiceCommand != "" && config.Services[service].CatOutput {
-				for _, path := range config.Services[service].Path {
-					GetCommandOutput(config.Services[service].ServiceCommand, configDir+"/"+service+"/"+h+"/"+path, sshCmd)
-				}
-			} else {
-				// else if config files
-				for _, path := range config.Services[service].Path {
-					PullLocalFiles(path, configDir+"/"+service+"/"+h+"/"+path, sshCmd)
-				}
-			}
-		}
-	} else {
+	return runJobs(hostJobs(service, configDir, connect, undercloud), false)
+}
+
+// hostJobs returns the jobs pulling the configuration of a service from each
+// of its hosts, the director host when it has none.
+func hostJobs(service string, configDir string, connect Connector, undercloud string) []pullJob {
+	hosts := config.Services[service].Hosts
+	if len(hosts) == 0 {
+		// The service runs on the Undercloud/Director node
+		hosts = []string{undercloud}
+	}
+	var jobs []pullJob
+	for _, h := range hosts {
+		h := h
+		jobs = append(jobs, pullJob{service: service, host: h, run: func() error {
+			return pullConfigFromHost(service, configDir, connect, h)
+		}})
+	}
+	return jobs
+}
+
+func pullConfigFromHost(service string, configDir string, connect Connector, host string) error {
+	t, err := connectHost(connect, host)
+	if err != nil {
+		return err
+	}
+	var errs []error
+	for _, path := range config.Services[service].Path {
+		localPath := configDir + "/" + service + "/" + host + "/" + path
 		// check if its config files or command output
 		if config.Services[service].ServiceCommand != "" && config.Services[service].CatOutput {
-			for _, path := range config.Services[service].Path {
-				GetCommandOutput(config.Services[service].ServiceCommand, configDir+"/"+service+"/"+undercloud+"/"+path, sshCmd)
-			}
+			err = GetCommandOutput(config.Services[service].ServiceCommand, localPath, t)
 		} else {
-			// else if config files
-			for _, path := range config.Services[service].Path {
-				PullLocalFiles(path, configDir+"/"+service+"/"+undercloud+"/"+path, sshCmd)
-			}
+			err = PullLocalFiles(path, localPath, t)
+		}
+		source := common.ManifestFile{Path: service + "/" + host + "/" + path, Service: service, Host: host, Source: path}
+		if config.Services[service].ServiceCommand != "" && config.Services[service].CatOutput {
+			source.Source = config.Services[service].ServiceCommand
+		}
+		recordSource(source, err)
+		if err != nil {
+			errs = append(errs, err)
 		}
 	}
-	return nil
+	return joinErrors(errs)
 }
 
-func GetPodmanId(containerName string, sshCmd string) (string, error) {
-	if Sudo {
-		sshCmd = sshCmd + " sudo "
+// GetPodmanId returns the short id of the container with this name.
+func GetPodmanId(containerName string, t Transport) (string, error) {
+	output, err := GetPodmanIds(t, true)
+	if err != nil {
+		return "", err
+	}
+	data, err := buildPodmanInfo(output, []string{containerName})
+	if err != nil {
+		return "", err
 	}
-	cmd := sshCmd + " podman ps -a | awk '/" + containerName + "$/  {print $1}'"
-	output, err := common.ExecCmd(cmd)
-	return output[0], err
+	return data[containerName]["containerid"], nil
 }
 
-func GetPodId(podName string) (string, error) {
-	cmd := "oc get pods --field-selector status.phase=Running | awk '/" + podName + "-[a-f0-9-]/ {print $1}'"
-	output, err := common.ExecCmd(cmd)
-	return output[0], err
+// GetPodId returns the name of a running pod of the workload podName, or
+// of the pods matching the label selector when set.
+func GetPodId(podName string, selector string) (string, error) {
+	client, err := GetKubeClient()
+	if err != nil {
+		return "", err
+	}
+	return client.FindPod(podName, selector)
 }
 
-func GetCommandOutput(command string, localPath string, sshCmd string) error {
-	cmd := sshCmd + " " + command + " > " + localPath
-	output, err := common.ExecComplexCmd(cmd)
-	if err != nil {
-		fmt.Println(output)
-		return err
+// GetCommandOutput writes the output of a service command to a file of the
+// host.
+func GetCommandOutput(command string, localPath string, t Transport) error {
+	if _, err := t.Exec("sh", "-c", command+" > "+ShellQuote(localPath)); err != nil {
+		return common.CollectionError("failed to run: %s: %w", command, err)
 	}
-	fmt.Println(output)
 	return nil
 }
 
-func PullLocalFiles(orgPath string, destPath string, sshCmd string) error {
-	if Sudo {
-		sshCmd = sshCmd + " sudo "
+func PullLocalFiles(orgPath string, destPath string, t Transport) error {
+	if _, err := t.Stat(orgPath); err != nil {
+		return common.CollectionError("failed to copy %s: %w", orgPath, err)
 	}
-	cmd := sshCmd + " cp -R " + orgPath + " " + destPath
-	_, err := common.ExecCmd(cmd)
-	if err != nil {
-		return err
+	if _, err := t.Exec("cp", "-R", orgPath, destPath); err != nil {
+		return common.CollectionError("failed to copy %s: %w", orgPath, err)
 	}
 	return nil
 }
 
-func PullPodmanFiles(podmanId string, remotePath string, localPath string, sshCmd string) error {
-	if Sudo {
-		sshCmd = sshCmd + " sudo "
-	}
-	cmd := sshCmd + " podman cp " + podmanId + ":" + remotePath + " " + localPath
-	_, err := common.ExecCmd(cmd)
-	if err != nil {
-		return err
+func PullPodmanFiles(podmanId string, remotePath string, localPath string, t Transport) error {
+	if _, err := t.Exec(containerEngine, "cp", podmanId+":"+remotePath, localPath); err != nil {
+		return common.CollectionError("failed to copy %s from container %s: %w", remotePath, podmanId, err)
 	}
 	return nil
 }
 
 func PullPodFiles(podId string, containerName string, remotePath string, localPath string) error {
-	// Test OC connexion
-	cmd := "oc cp -c " + containerName + " " + podId + ":" + remotePath + " " + localPath
-	_, err := common.ExecCmd(cmd)
+	client, err := GetKubeClient()
+	if err == nil {
+		err = client.PodTransport(podId, containerName).CopyFrom(remotePath, localPath)
+	}
 	if err != nil {
-		return err
+		return common.CollectionError("failed to copy %s from pod %s: %w", remotePath, podId, err)
 	}
 	return nil
 }
 
-func SyncConfigDir(localPath string, remotePath string, sshCmd string, undercloud string) error {
+// SyncConfigDir copies the configuration collected in remotePath on the
+// director host, or on every host when there is no director host, to
+// localPath.
+func SyncConfigDir(localPath string, remotePath string, connect Connector, undercloud string) error {
 	// make sure localPath exists
-	var cmd string
 	err := os.MkdirAll(localPath, os.ModePerm)
 	if err != nil {
 		return err
 	}
-	if undercloud != "" {
-		cmd = "rsync -a -e '" + sshCmd + " " + undercloud + "' :" + remotePath + " " + localPath
-		common.ExecCmd(cmd)
-	} else {
-		hosts := GetListHosts(undercloud)
-
-		for _, h := range hosts {
-			if strings.Contains(sshCmd, "-F") {
-				cmd = "rsync -a -e '" + sshCmd + " " + h + "' :" + remotePath + " " + localPath
-			} else {
-				cmd = "rsync -a -e '" + sshCmd + h + "' :" + remotePath + " " + localPath
+	hosts := []string{undercloud}
+	if undercloud == "" {
+		hosts = GetListHosts(undercloud)
+	}
+	var jobs []pullJob
+	for _, h := range hosts {
+		h := h
+		jobs = append(jobs, pullJob{service: "sync", host: h, run: func() error {
+			t, err := connectHost(connect, h)
+			if err != nil {
+				return err
 			}
-			common.ExecCmd(cmd)
-		}
+			if err := t.CopyFrom(remotePath, filepath.Join(localPath, path.Base(remotePath))); err != nil {
+				return common.CollectionError("failed to sync %s from %s: %w", remotePath, h, err)
+			}
+			return nil
+		}})
 	}
-	return nil
+	return runJobs(jobs, false)
 }
 
 func GetListHosts(undercloud string) []string {
@@ -250,73 +356,74 @@ func GetListHosts(undercloud string) []string {
 	return hosts
 }
 
-func CleanUp(remotePath string, sshCmd string) error {
+func CleanUp(remotePath string, t Transport) error {
 	if remotePath == "" || remotePath == "/" {
-		return fmt.Errorf("Clean up Error - Empty or wrong path: " + remotePath + ". Please make sure you provided a correct path.")
+		return fmt.Errorf("Clean up Error - Empty or wrong path: %s. Please make sure you provided a correct path.", remotePath)
 	}
-	if Sudo {
-		sshCmd = sshCmd + " sudo "
+	if _, err := t.Exec("rm", "-rf", remotePath); err != nil {
+		return common.CollectionError("failed to clean up %s: %w", remotePath, err)
 	}
-	cmd := sshCmd + " rm -rf " + remotePath
-	common.ExecCmd(cmd)
 	return nil
 }
 
-func CreateServicesTrees(configDir string, sshCmd string, undercloud string, filters []string) (string, error) {
-	filterMap := make(map[string]struct{})
-	for _, filter := range filters {
-		filterMap[filter] = struct{}{}
-	}
-
-	for service := range config.Services {
-		if config.Services[service].Enable {
-			if _, ok := filterMap[service]; ok || len(filters) == 0 {
-				if len(config.Services[service].Hosts) != 0 {
-					for _, h := range config.Services[service].Hosts {
-						// Create trees for each hosts describe in config Yaml file
-						sshCmd = strings.Replace(sshCmd, undercloud, h, -1)
-						for _, path := range config.Services[service].Path {
-							output, err := CreateServiceTree(service, path, configDir, sshCmd, h)
-							if err != nil {
-								return output, err
-							}
-						}
-					}
-				} else {
-					for _, path := range config.Services[service].Path {
-						output, err := CreateServiceTree(service, path, configDir, sshCmd, "")
-						if err != nil {
-							return output, err
-						}
+func CreateServicesTrees(configDir string, connect Connector, undercloud string, filters []string) (string, error) {
+	var jobs []pullJob
+	for _, service := range selectedServices(filters) {
+		service := service
+		hosts := config.Services[service].Hosts
+		if len(hosts) == 0 {
+			hosts = []string{""}
+		}
+		// Create trees for each hosts describe in config Yaml file
+		for _, h := range hosts {
+			h := h
+			host := h
+			if host == "" {
+				host = undercloud
+			}
+			jobs = append(jobs, pullJob{service: service, host: host, run: func() error {
+				t, err := connectHost(connect, host)
+				if err != nil {
+					return err
+				}
+				var errs []error
+				for _, path := range config.Services[service].Path {
+					if _, err := CreateServiceTree(service, path, configDir, t, h); err != nil {
+						errs = append(errs, err)
 					}
 				}
-			}
+				return joinErrors(errs)
+			}})
 		}
 	}
-	return "", nil
+	return "", runJobs(jobs, false)
 }
 
-func CreateServiceTree(serviceName string, path string, configDir string, sshCmd string, host string) (string, error) {
+func CreateServiceTree(serviceName string, path string, configDir string, t Transport, host string) (string, error) {
 	fullPath := configDir + "/" + serviceName + "/" + host + "/" + getDir(path)
-	if Sudo {
-		sshCmd = sshCmd + " sudo "
+	output, err := t.Exec("mkdir", "-p", fullPath)
+	if err != nil {
+		return string(output), common.CollectionError("failed to create %s: %w", fullPath, err)
 	}
-	cmd := sshCmd + " mkdir -p " + fullPath
-	output, err := common.ExecCmdSimple(cmd)
-	return output, err
+	return string(output), nil
 }
 
 func getDir(s string) string {
 	return path.Dir(s)
 }
 
+// FetchConfigFromEnv collects the configuration of the services. With a
+// local connection the files are collected to localDir directly, else they
+// are collected to remoteDir on the hosts, copied to localDir and removed.
+// The manifest of the collected files is written at the root of the
+// collection.
 func FetchConfigFromEnv(configPath string,
-	localDir string, remoteDir string, tripleo bool, connection, fullCmd string, undercloud string, filters []string, sshCmd string) error {
+	localDir string, remoteDir string, tripleo bool, connection string, connect Connector, undercloud string, filters []string) error {
 
 	var local bool
 	cfg, err := common.LoadServiceConfigFile(configPath)
 	if err != nil {
-		return err
+		return common.UsageError("failed to load %s: %w", configPath, err)
 	}
 	config = cfg
 
@@ -326,24 +433,42 @@ func FetchConfigFromEnv(configPath string,
 		local = false
 	}
 
+	// Pull what can be pulled even if some trees or pulls failed
+	var errs []error
+	resetSources()
 	if local {
-		output, err := CreateServicesTrees(localDir, fullCmd, undercloud, filters)
-		if err != nil {
-			fmt.Println(output)
-			return err
+		if _, err := CreateServicesTrees(localDir, connect, undercloud, filters); err != nil {
+			errs = append(errs, err)
 		}
-		PullConfigs(localDir, tripleo, fullCmd, undercloud, filters)
-	} else {
-		output, err := CreateServicesTrees(remoteDir, fullCmd, undercloud, filters)
-		if err != nil {
-			fmt.Println(output)
-			return err
+		if err := PullConfigs(localDir, tripleo, connect, undercloud, filters); err != nil {
+			errs = append(errs, err)
 		}
-		PullConfigs(remoteDir, tripleo, fullCmd, undercloud, filters)
-		SyncConfigDir(localDir, remoteDir, sshCmd, undercloud)
-		CleanUp(remoteDir, fullCmd)
+		if err := writeManifest(localDir, tripleo); err != nil {
+			errs = append(errs, err)
+		}
+		return joinErrors(errs)
 	}
-	return nil
+	if _, err := CreateServicesTrees(remoteDir, connect, undercloud, filters); err != nil {
+		errs = append(errs, err)
+	}
+	// Sync and clean up what has been collected even if some pulls failed
+	if err := PullConfigs(remoteDir, tripleo, connect, undercloud, filters); err != nil {
+		errs = append(errs, err)
+	}
+	if err := SyncConfigDir(localDir, remoteDir, connect, undercloud); err != nil {
+		errs = append(errs, err)
+	}
+	if err := writeManifest(filepath.Join(localDir, path.Base(remoteDir)), tripleo); err != nil {
+		errs = append(errs, err)
+	}
+	t, err := connectHost(connect, undercloud)
+	if err == nil {
+		err = CleanUp(remoteDir, t)
+	}
+	if err != nil {
+		errs = append(errs, err)
+	}
+	return joinErrors(errs)
 }
 
 func buildPodmanInfo(output []byte, filters []string) (map[string]map[string]string, error) {
@@ -352,18 +477,20 @@ func buildPodmanInfo(output []byte, filters []string) (map[string]map[string]str
 	for _, filter := range filters {
 		filterMap[filter] = struct{}{}
 	}
-	var containers []PodmanContainer
-	err := json.Unmarshal(output, &containers)
+	containers, err := parseContainers(output)
 	if err != nil {
-		fmt.Println("Error parsing JSON:", err)
 		return nil, err
 	}
 	data := make(map[string]map[string]string)
 	for _, container := range containers {
+		id := container.ID
+		if len(id) > 12 {
+			id = id[:12]
+		}
 		for _, name := range container.Names {
 			if _, ok := filterMap[name]; ok || len(filters) == 0 {
 				data[name] = map[string]string{
-					"containerid": container.ID[:12],
+					"containerid": id,
 					"image":       container.Image,
 				}
 			}
@@ -372,17 +499,20 @@ func buildPodmanInfo(output []byte, filters []string) (map[string]map[string]str
 	return data, nil
 }
 
-func SetTripleODataEnv(configPath string, sshCmd string, filters []string, all bool) error {
+func SetTripleODataEnv(configPath string, t Transport, filters []string, all bool) error {
 	// Get Podman informations:
-	output, err := GetPodmanIds(sshCmd, all)
+	output, err := GetPodmanIds(t, all)
 	if err != nil {
-		return err
+		return common.CollectionError("failed to list %s containers: %w", containerEngine, err)
+	}
+	data, err := buildPodmanInfo(output, filters)
+	if err != nil {
+		return common.CollectionError("failed to parse %s containers: %w", containerEngine, err)
 	}
-	data, _ := buildPodmanInfo(output, filters)
 	// Load config.yaml
 	config, err = common.LoadServiceConfigFile(configPath)
 	if err != nil {
-		return err
+		return common.UsageError("failed to load %s: %w", configPath, err)
 	}
 	// Update or add data to config
 	for name, info := range data {
@@ -0,0 +1,261 @@
+/*
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * Copyright 2023 Red Hat, Inc.
+ *
+ */
+
+package collectcfg
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/tools/clientcmd"
+	"k8s.io/client-go/tools/remotecommand"
+	"sigs.k8s.io/yaml"
+)
+
+// PodExecutor runs a command in a container of a pod.
+type PodExecutor func(namespace string, pod string, container string, argv []string, stdout io.Writer, stderr io.Writer) error
+
+// KubeClient collects the configuration of the OpenShift pods with the
+// Kubernetes API.
+type KubeClient struct {
+	clientset kubernetes.Interface
+	namespace string
+	executor  PodExecutor
+}
+
+// Kubeconfig file and namespace of the client, the defaults of the
+// kubeconfig (KUBECONFIG, ~/.kube/config and its current context) when empty
+var kubeconfigPath string
+var kubeNamespace string
+
+var kubeClient *KubeClient
+var kubeClientMutex sync.Mutex
+
+// SetKubeConfig sets the kubeconfig file and the namespace of the client
+// returned by GetKubeClient.
+func SetKubeConfig(kubeconfig string, namespace string) {
+	kubeClientMutex.Lock()
+	defer kubeClientMutex.Unlock()
+	kubeconfigPath = kubeconfig
+	kubeNamespace = namespace
+	kubeClient = nil
+}
+
+// SetKubeClient sets the client returned by GetKubeClient, for the tests.
+func SetKubeClient(client *KubeClient) {
+	kubeClientMutex.Lock()
+	defer kubeClientMutex.Unlock()
+	kubeClient = client
+}
+
+// GetKubeClient returns the client of the configured kubeconfig, created
+// on first use.
+func GetKubeClient() (*KubeClient, error) {
+	kubeClientMutex.Lock()
+	defer kubeClientMutex.Unlock()
+	if kubeClient == nil {
+		client, err := NewKubeClient(kubeconfigPath, kubeNamespace)
+		if err != nil {
+			return nil, err
+		}
+		kubeClient = client
+	}
+	return kubeClient, nil
+}
+
+// NewKubeClient returns a client for a kubeconfig file and a namespace, the
+// namespace of the kubeconfig current context when empty.
+func NewKubeClient(kubeconfig string, namespace string) (*KubeClient, error) {
+	rules := clientcmd.NewDefaultClientConfigLoadingRules()
+	rules.ExplicitPath = kubeconfig
+	clientConfig := clientcmd.NewNonInteractiveDeferredLoadingClientConfig(rules, &clientcmd.ConfigOverrides{})
+	restConfig, err := clientConfig.ClientConfig()
+	if err != nil {
+		return nil, fmt.Errorf("failed to load the kubeconfig: %w", err)
+	}
+	if namespace == "" {
+		namespace, _, err = clientConfig.Namespace()
+		if err != nil {
+			return nil, fmt.Errorf("failed to get the namespace of the kubeconfig: %w", err)
+		}
+	}
+	clientset, err := kubernetes.NewForConfig(restConfig)
+	if err != nil {
+		return nil, err
+	}
+	return NewKubeClientForClientset(clientset, namespace, apiExecutor(clientset, restConfig)), nil
+}
+
+// NewKubeClientForClientset returns a client using a clientset, a fake one
+// in the tests, and a command executor.
+func NewKubeClientForClientset(clientset kubernetes.Interface, namespace string, executor PodExecutor) *KubeClient {
+	return &KubeClient{clientset: clientset, namespace: namespace, executor: executor}
+}
+
+// apiExecutor runs the commands with the exec subresource of the pods, as
+// oc exec does.
+func apiExecutor(clientset kubernetes.Interface, restConfig *rest.Config) PodExecutor {
+	return func(namespace string, pod string, container string, argv []string, stdout io.Writer, stderr io.Writer) error {
+		req := clientset.CoreV1().RESTClient().Post().
+			Resource("pods").Namespace(namespace).Name(pod).SubResource("exec").
+			VersionedParams(&corev1.PodExecOptions{
+				Container: container,
+				Command:   argv,
+				Stdout:    true,
+				Stderr:    true,
+			}, scheme.ParameterCodec)
+		executor, err := remotecommand.NewSPDYExecutor(restConfig, "POST", req.URL())
+		if err != nil {
+			return err
+		}
+		return executor.StreamWithContext(context.TODO(), remotecommand.StreamOptions{Stdout: stdout, Stderr: stderr})
+	}
+}
+
+// Namespace returns the namespace of the client.
+func (c *KubeClient) Namespace() string {
+	return c.namespace
+}
+
+// TestConnection checks the pods of the namespace can be listed.
+func (c *KubeClient) TestConnection() error {
+	_, err := c.clientset.CoreV1().Pods(c.namespace).List(context.TODO(), metav1.ListOptions{Limit: 1})
+	if err != nil {
+		return fmt.Errorf("failed to list the pods of namespace %s: %w", c.namespace, err)
+	}
+	return nil
+}
+
+// FindPod returns a running pod of the workload (deployment, stateful set...)
+// or the pod named name, or of the pods matching the label selector when
+// set. The replicas of a workload share their configuration, the first one
+// by name is returned, several workloads matching is an error.
+func (c *KubeClient) FindPod(name string, selector string) (string, error) {
+	pods, err := c.clientset.CoreV1().Pods(c.namespace).List(context.TODO(), metav1.ListOptions{LabelSelector: selector})
+	if err != nil {
+		return "", fmt.Errorf("failed to list the pods of namespace %s: %w", c.namespace, err)
+	}
+	var matches []corev1.Pod
+	workloads := map[string]bool{}
+	for _, pod := range pods.Items {
+		if pod.Status.Phase != corev1.PodRunning || pod.DeletionTimestamp != nil {
+			continue
+		}
+		if pod.Name == name {
+			return pod.Name, nil
+		}
+		workload := workloadName(&pod)
+		if selector != "" || workload == name {
+			matches = append(matches, pod)
+			workloads[workload] = true
+		}
+	}
+	if len(matches) == 0 {
+		if selector != "" {
+			return "", fmt.Errorf("no running pod matching %s in namespace %s", selector, c.namespace)
+		}
+		return "", fmt.Errorf("no running pod %s in namespace %s", name, c.namespace)
+	}
+	if len(workloads) > 1 {
+		var names []string
+		for workload := range workloads {
+			names = append(names, workload)
+		}
+		sort.Strings(names)
+		return "", fmt.Errorf("pods of several workloads match in namespace %s: %s", c.namespace, strings.Join(names, ", "))
+	}
+	sort.Slice(matches, func(i, j int) bool { return matches[i].Name < matches[j].Name })
+	return matches[0].Name, nil
+}
+
+// Suffix of the jobs of a cron job, its scheduled time
+var cronJobSuffix = regexp.MustCompile(`-[0-9]+$`)
+
+// workloadName returns the name of the workload owning a pod: its
+// controller, the deployment of a replica set, the cron job of a job, or
+// the pod itself.
+func workloadName(pod *corev1.Pod) string {
+	owner := metav1.GetControllerOf(pod)
+	if owner == nil {
+		return pod.Name
+	}
+	switch owner.Kind {
+	case "ReplicaSet":
+		if hash, ok := pod.Labels["pod-template-hash"]; ok {
+			return strings.TrimSuffix(owner.Name, "-"+hash)
+		}
+	case "Job":
+		return cronJobSuffix.ReplaceAllString(owner.Name, "")
+	}
+	return owner.Name
+}
+
+// ConfigMap returns a config map as YAML, as oc get -o yaml does.
+func (c *KubeClient) ConfigMap(name string) ([]byte, error) {
+	configMap, err := c.clientset.CoreV1().ConfigMaps(c.namespace).Get(context.TODO(), name, metav1.GetOptions{})
+	if err != nil {
+		return nil, err
+	}
+	configMap.APIVersion = "v1"
+	configMap.Kind = "ConfigMap"
+	return yaml.Marshal(configMap)
+}
+
+// PodTransport returns the transport running the commands in a container of
+// a pod.
+func (c *KubeClient) PodTransport(pod string, container string) Transport {
+	return &podTransport{client: c, pod: pod, container: container}
+}
+
+type podTransport struct {
+	client    *KubeClient
+	pod       string
+	container string
+}
+
+func (t *podTransport) Exec(name string, args ...string) ([]byte, error) {
+	argv := append([]string{name}, args...)
+	var stdout, stderr bytes.Buffer
+	if err := t.client.executor(t.client.namespace, t.pod, t.container, argv, &stdout, &stderr); err != nil {
+		return stdout.Bytes(), commandError(argv, err, stderr.String())
+	}
+	return stdout.Bytes(), nil
+}
+
+// CopyFrom needs tar in the container, as oc cp does.
+func (t *podTransport) CopyFrom(remotePath string, localPath string) error {
+	return copyWithTar(t, remotePath, localPath)
+}
+
+func (t *podTransport) Stat(p string) (FileInfo, error) {
+	return statWithCommand(t, p)
+}
+
+func (t *podTransport) ListDir(p string) ([]FileInfo, error) {
+	return listDirWithCommand(t, p)
+}
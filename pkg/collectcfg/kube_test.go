@@ -0,0 +1,172 @@
+/*
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * Copyright 2023 Red Hat, Inc.
+ *
+ */
+
+package collectcfg_test
+
+import (
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/openstack-k8s-operators/os-diff/pkg/collectcfg"
+	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+func pod(name string, phase corev1.PodPhase, ownerKind string, ownerName string, labels map[string]string) *corev1.Pod {
+	p := &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "openstack", Labels: labels},
+		Status:     corev1.PodStatus{Phase: phase},
+	}
+	if ownerKind != "" {
+		controller := true
+		p.OwnerReferences = []metav1.OwnerReference{{Kind: ownerKind, Name: ownerName, Controller: &controller}}
+	}
+	return p
+}
+
+func testPods() []runtime.Object {
+	return []runtime.Object{
+		pod("keystone-7c9f8d6b5-abcde", corev1.PodRunning, "ReplicaSet", "keystone-7c9f8d6b5", map[string]string{"pod-template-hash": "7c9f8d6b5", "service": "keystone"}),
+		pod("keystone-7c9f8d6b5-fghij", corev1.PodRunning, "ReplicaSet", "keystone-7c9f8d6b5", map[string]string{"pod-template-hash": "7c9f8d6b5", "service": "keystone"}),
+		pod("keystone-cron-28512345-xyzab", corev1.PodRunning, "Job", "keystone-cron-28512345", map[string]string{"service": "keystone"}),
+		pod("nova-api-0", corev1.PodPending, "StatefulSet", "nova-api", nil),
+		pod("nova-api-1", corev1.PodRunning, "StatefulSet", "nova-api", nil),
+		pod("ovn-controller-x1y2z", corev1.PodRunning, "DaemonSet", "ovn-controller", nil),
+		pod("glance-default-external-api-0", corev1.PodRunning, "StatefulSet", "glance-default-external-api", nil),
+		&corev1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "keystone-7c9f8d6b5-abcde", Namespace: "other"}, Status: corev1.PodStatus{Phase: corev1.PodRunning}},
+		&corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Name: "keystone-config-data", Namespace: "openstack"}, Data: map[string]string{"keystone.conf": "[DEFAULT]\ndebug=True\n"}},
+	}
+}
+
+// localExecutor runs the pod commands on the local host.
+func localExecutor(namespace string, pod string, container string, argv []string, stdout io.Writer, stderr io.Writer) error {
+	cmd := exec.Command(argv[0], argv[1:]...)
+	cmd.Stdout = stdout
+	cmd.Stderr = stderr
+	return cmd.Run()
+}
+
+func TestFindPod(t *testing.T) {
+	client := collectcfg.NewKubeClientForClientset(fake.NewSimpleClientset(testPods()...), "openstack", localExecutor)
+	assert.NoError(t, client.TestConnection())
+
+	tests := []struct {
+		name     string
+		pod      string
+		selector string
+		expected string
+		err      string
+	}{
+		{"deployment", "keystone", "", "keystone-7c9f8d6b5-abcde", ""},
+		{"other workload with the same prefix", "keystone-cron", "", "keystone-cron-28512345-xyzab", ""},
+		{"stateful set, pending pod skipped", "nova-api", "", "nova-api-1", ""},
+		{"daemon set", "ovn-controller", "", "ovn-controller-x1y2z", ""},
+		{"pod name", "glance-default-external-api-0", "", "glance-default-external-api-0", ""},
+		{"no running pod", "glance", "", "", "no running pod glance in namespace openstack"},
+		{"selector", "", "service=keystone", "", "pods of several workloads match in namespace openstack: keystone, keystone-cron"},
+		{"selector without match", "", "service=nova", "", "no running pod matching service=nova in namespace openstack"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			name, err := client.FindPod(tt.pod, tt.selector)
+			if tt.err != "" {
+				assert.EqualError(t, err, tt.err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.expected, name)
+		})
+	}
+}
+
+func TestKubeConfigMap(t *testing.T) {
+	client := collectcfg.NewKubeClientForClientset(fake.NewSimpleClientset(testPods()...), "openstack", localExecutor)
+	data, err := client.ConfigMap("keystone-config-data")
+	assert.NoError(t, err)
+	assert.Contains(t, string(data), "kind: ConfigMap\n")
+	assert.Contains(t, string(data), "keystone.conf: |\n    [DEFAULT]\n    debug=True\n")
+
+	_, err = client.ConfigMap("missing")
+	assert.Error(t, err)
+}
+
+func TestPullFromPods(t *testing.T) {
+	// The pod files are the local ones
+	dir := t.TempDir()
+	etc := filepath.Join(dir, "pod", "etc", "keystone")
+	assert.NoError(t, os.MkdirAll(etc, 0755))
+	assert.NoError(t, os.WriteFile(filepath.Join(etc, "keystone.conf"), []byte("[DEFAULT]\n"), 0640))
+	ovs := filepath.Join(dir, "node", "etc", "openvswitch")
+	assert.NoError(t, os.MkdirAll(ovs, 0755))
+	assert.NoError(t, os.WriteFile(filepath.Join(ovs, "system-id.conf"), []byte("compute-0\n"), 0644))
+
+	configPath := filepath.Join(dir, "config.yaml")
+	assert.NoError(t, os.WriteFile(configPath, []byte(`services:
+  keystone:
+    enable: true
+    pod_name: keystone
+    container_name: keystone-api
+    path:
+      - `+etc+`
+  nova:
+    enable: true
+    pod_name: nova-api
+    container_name: nova-api
+    path:
+      - /etc/nova
+  ovs:
+    enable: true
+    hosts:
+      - compute-0
+    path:
+      - `+ovs+`
+`), 0644))
+
+	var execs []string
+	executor := func(namespace string, pod string, container string, argv []string, stdout io.Writer, stderr io.Writer) error {
+		execs = append(execs, namespace+"/"+pod+"/"+container+": "+strings.Join(argv, " "))
+		return localExecutor(namespace, pod, container, argv, stdout, stderr)
+	}
+	collectcfg.SetKubeClient(collectcfg.NewKubeClientForClientset(fake.NewSimpleClientset(testPods()...), "openstack", executor))
+	defer collectcfg.SetKubeConfig("", "")
+	collectcfg.SetProgressOutput(nil)
+	defer collectcfg.SetProgressOutput(os.Stdout)
+
+	localDir := filepath.Join(dir, "ocp")
+	err := collectcfg.FetchConfigFromEnv(configPath, localDir, "", false, "local", collectcfg.LocalConnector(false), "", nil)
+	// /etc/nova doesn't exist locally
+	assert.ErrorContains(t, err, "failed to copy /etc/nova from pod nova-api-1")
+
+	data, err := os.ReadFile(filepath.Join(localDir, "keystone", etc, "keystone.conf"))
+	assert.NoError(t, err)
+	assert.Equal(t, "[DEFAULT]\n", string(data))
+	// The services with hosts are collected from the hosts, not from pods
+	data, err = os.ReadFile(filepath.Join(localDir, "ovs", "compute-0", ovs, "system-id.conf"))
+	assert.NoError(t, err)
+	assert.Equal(t, "compute-0\n", string(data))
+	assert.Equal(t, []string{
+		"openstack/keystone-7c9f8d6b5-abcde/keystone-api: tar -C " + filepath.Dir(etc) + " -cf - keystone",
+		"openstack/nova-api-1/nova-api: tar -C /etc -cf - nova",
+	}, execs)
+}
@@ -0,0 +1,153 @@
+/*
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * Copyright 2023 Red Hat, Inc.
+ *
+ */
+
+package collectcfg
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/openstack-k8s-operators/os-diff/pkg/common"
+)
+
+// Sources of the current pull, the Path of an entry being the collected
+// path relative to the collection directory
+var pulledSources []common.ManifestFile
+var pulledSourcesMutex sync.Mutex
+
+// recordSource keeps where a path has been collected from, and why it
+// failed, for the manifest.
+func recordSource(source common.ManifestFile, err error) {
+	source.Path = path.Clean(source.Path)
+	if err != nil {
+		source.Error = err.Error()
+	}
+	pulledSourcesMutex.Lock()
+	defer pulledSourcesMutex.Unlock()
+	pulledSources = append(pulledSources, source)
+}
+
+func resetSources() {
+	pulledSourcesMutex.Lock()
+	defer pulledSourcesMutex.Unlock()
+	pulledSources = nil
+}
+
+// BuildManifest lists the files collected in root with their checksum and
+// the source they come from, followed by the sources which failed.
+func BuildManifest(root string, cloud string, sources []common.ManifestFile) (*common.Manifest, error) {
+	manifest := &common.Manifest{Version: 1, Cloud: cloud, CollectedAt: time.Now().UTC(), Files: []common.ManifestFile{}}
+	err := filepath.Walk(root, func(p string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(root, p)
+		if err != nil {
+			return err
+		}
+		rel = filepath.ToSlash(rel)
+		if !info.Mode().IsRegular() || rel == common.ManifestFileName {
+			return nil
+		}
+		var file common.ManifestFile
+		if source := sourceOf(rel, sources); source != nil {
+			file = *source
+			// The collected path may be a directory
+			file.Source = path.Join(source.Source, strings.TrimPrefix(rel, source.Path))
+		}
+		file.Path = rel
+		file.Size = info.Size()
+		file.Mode = fmt.Sprintf("%04o", info.Mode().Perm())
+		file.SHA256, err = fileSHA256(p)
+		if err != nil {
+			file.Error = err.Error()
+		}
+		manifest.Files = append(manifest.Files, file)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	for _, source := range sources {
+		if source.Error != "" {
+			manifest.Files = append(manifest.Files, source)
+		}
+	}
+	sort.SliceStable(manifest.Files, func(i, j int) bool { return manifest.Files[i].Path < manifest.Files[j].Path })
+	return manifest, nil
+}
+
+// sourceOf returns the source whose collected path is or holds rel.
+func sourceOf(rel string, sources []common.ManifestFile) *common.ManifestFile {
+	var found *common.ManifestFile
+	for i := range sources {
+		source := &sources[i]
+		if source.Error != "" {
+			continue
+		}
+		if rel == source.Path || strings.HasPrefix(rel, source.Path+"/") {
+			if found == nil || len(source.Path) > len(found.Path) {
+				found = source
+			}
+		}
+	}
+	return found
+}
+
+func fileSHA256(p string) (string, error) {
+	file, err := os.Open(p)
+	if err != nil {
+		return "", err
+	}
+	defer file.Close()
+	h := sha256.New()
+	if _, err := io.Copy(h, file); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// writeManifest writes the manifest of the current pull to root.
+func writeManifest(root string, tripleo bool) error {
+	cloud := "ocp"
+	if tripleo {
+		cloud = "tripleo"
+	}
+	pulledSourcesMutex.Lock()
+	sources := append([]common.ManifestFile{}, pulledSources...)
+	pulledSourcesMutex.Unlock()
+	if err := os.MkdirAll(root, os.ModePerm); err != nil {
+		return err
+	}
+	manifest, err := BuildManifest(root, cloud, sources)
+	if err == nil {
+		err = common.WriteManifest(root, manifest)
+	}
+	if err != nil {
+		return common.CollectionError("failed to write the manifest of %s: %w", root, err)
+	}
+	return nil
+}
@@ -0,0 +1,59 @@
+/*
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * Copyright 2023 Red Hat, Inc.
+ *
+ */
+
+package collectcfg_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/openstack-k8s-operators/os-diff/pkg/collectcfg"
+	"github.com/openstack-k8s-operators/os-diff/pkg/common"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBuildManifest(t *testing.T) {
+	root := t.TempDir()
+	assert.NoError(t, os.MkdirAll(filepath.Join(root, "nova", "etc", "nova"), 0755))
+	assert.NoError(t, os.WriteFile(filepath.Join(root, "nova", "etc", "nova", "nova.conf"), []byte("[DEFAULT]\n"), 0640))
+	assert.NoError(t, os.WriteFile(filepath.Join(root, common.ManifestFileName), []byte("{}"), 0644))
+
+	sources := []common.ManifestFile{
+		{Path: "nova/etc/nova", Service: "nova", Host: "standalone", Source: "/etc/nova", PodmanId: "0123456789ab", PodmanName: "nova_api", Image: "nova-api:17.1"},
+		{Path: "cinder/etc/cinder", Service: "cinder", Host: "standalone", Source: "/etc/cinder", Error: "failed to copy /etc/cinder"},
+	}
+	manifest, err := collectcfg.BuildManifest(root, "tripleo", sources)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, manifest.Version)
+	assert.Equal(t, "tripleo", manifest.Cloud)
+	assert.Equal(t, []common.ManifestFile{
+		sources[1],
+		{
+			Path:       "nova/etc/nova/nova.conf",
+			Service:    "nova",
+			Host:       "standalone",
+			Source:     "/etc/nova/nova.conf",
+			PodmanId:   "0123456789ab",
+			PodmanName: "nova_api",
+			Image:      "nova-api:17.1",
+			SHA256:     "62fb8fa57dc94a6c4edf086733f49415e772884ac3373bd5a1c64bdcf9d21898",
+			Size:       10,
+			Mode:       "0640",
+		},
+	}, manifest.Files)
+}
@@ -0,0 +1,131 @@
+/*
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * Copyright 2023 Red Hat, Inc.
+ *
+ */
+
+package collectcfg
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"sync"
+)
+
+// Number of pull jobs running at the same time
+var parallel = 1
+
+// Number of pull jobs running at the same time on a host, the SSH servers
+// limit the sessions of a connection (MaxSessions, 10 by default)
+var hostParallel = 4
+
+// Where the progress of the pulls is written
+var progressOutput io.Writer = os.Stdout
+
+// SetParallel sets the number of pull jobs running at the same time and on
+// the same host, values lower than 1 are ignored.
+func SetParallel(jobs int, perHost int) {
+	if jobs > 0 {
+		parallel = jobs
+	}
+	if perHost > 0 {
+		hostParallel = perHost
+	}
+}
+
+// SetProgressOutput sets where the progress of the pulls is written, nil
+// disables it.
+func SetProgressOutput(w io.Writer) {
+	progressOutput = w
+}
+
+// pullJob is a unit of collection: a service on a host.
+type pullJob struct {
+	service string
+	host    string
+	run     func() error
+}
+
+func (j pullJob) String() string {
+	if j.host == "" {
+		return j.service
+	}
+	return j.service + " on " + j.host
+}
+
+// runJobs runs the jobs with at most parallel jobs at once and hostParallel
+// per host. The progress is reported in the order of the jobs whatever the
+// order they end in, and so are the errors, the failing jobs don't stop the
+// others.
+func runJobs(jobs []pullJob, report bool) error {
+	workers := parallel
+	if workers > len(jobs) {
+		workers = len(jobs)
+	}
+	var hostsMutex sync.Mutex
+	hosts := map[string]chan struct{}{}
+	hostSlot := func(host string) chan struct{} {
+		hostsMutex.Lock()
+		defer hostsMutex.Unlock()
+		if _, ok := hosts[host]; !ok {
+			hosts[host] = make(chan struct{}, hostParallel)
+		}
+		return hosts[host]
+	}
+
+	errs := make([]error, len(jobs))
+	done := make([]chan struct{}, len(jobs))
+	for i := range done {
+		done[i] = make(chan struct{})
+	}
+	indexes := make(chan int)
+	var wg sync.WaitGroup
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range indexes {
+				slot := hostSlot(jobs[i].host)
+				slot <- struct{}{}
+				errs[i] = jobs[i].run()
+				<-slot
+				close(done[i])
+			}
+		}()
+	}
+	go func() {
+		for i := range jobs {
+			indexes <- i
+		}
+		close(indexes)
+	}()
+
+	var failed []error
+	for i, job := range jobs {
+		<-done[i]
+		if errs[i] != nil {
+			failed = append(failed, errs[i])
+		}
+		if report && progressOutput != nil {
+			status := "ok"
+			if errs[i] != nil {
+				status = "failed"
+			}
+			fmt.Fprintf(progressOutput, "[%d/%d] %s: %s\n", i+1, len(jobs), job, status)
+		}
+	}
+	wg.Wait()
+	return joinErrors(failed)
+}
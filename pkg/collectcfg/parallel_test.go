@@ -0,0 +1,107 @@
+/*
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * Copyright 2023 Red Hat, Inc.
+ *
+ */
+
+package collectcfg_test
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/openstack-k8s-operators/os-diff/pkg/collectcfg"
+	"github.com/openstack-k8s-operators/os-diff/pkg/common"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParallelPull(t *testing.T) {
+	dir := t.TempDir()
+	configPath := filepath.Join(dir, "config.yaml")
+	assert.NoError(t, os.WriteFile(configPath, []byte(`services:
+  nova_compute:
+    enable: true
+    path:
+      - /etc/nova
+      - /etc/libvirt
+    hosts:
+      - compute-0
+      - compute-1
+      - compute-2
+      - compute-3
+  ovs:
+    enable: true
+    path:
+      - /etc/openvswitch
+    hosts:
+      - compute-0
+  disabled:
+    enable: false
+    path:
+      - /etc/disabled
+    hosts:
+      - compute-0
+`), 0644))
+
+	transports := map[string]*collectcfg.FakeTransport{}
+	for i := 0; i < 4; i++ {
+		fake := collectcfg.NewFakeTransport()
+		fake.Files["/etc/nova/nova.conf"] = "[DEFAULT]\n"
+		fake.Files["/etc/libvirt/qemu.conf"] = "user = \"qemu\"\n"
+		fake.Files["/etc/openvswitch/conf.db"] = "{}\n"
+		transports[fmt.Sprintf("compute-%d", i)] = fake
+	}
+	delete(transports["compute-2"].Files, "/etc/libvirt/qemu.conf")
+	delete(transports, "compute-3")
+
+	var progress bytes.Buffer
+	collectcfg.SetProgressOutput(&progress)
+	defer collectcfg.SetProgressOutput(os.Stdout)
+	collectcfg.SetParallel(3, 2)
+	defer collectcfg.SetParallel(1, 4)
+
+	localDir := filepath.Join(dir, "tripleo")
+	err := collectcfg.FetchConfigFromEnv(configPath, localDir, "", false, "local",
+		collectcfg.FakeConnector(transports), "", nil)
+	assert.Equal(t, common.ExitCollection, common.ExitCode(err))
+	assert.ErrorContains(t, err, "failed to copy /etc/libvirt")
+	// The unreachable host failing the tree and the pull is reported once
+	assert.Equal(t, 1, strings.Count(err.Error(), "failed to connect to compute-3"))
+	assert.Equal(t, `[1/5] nova_compute on compute-0: ok
+[2/5] nova_compute on compute-1: ok
+[3/5] nova_compute on compute-2: failed
+[4/5] nova_compute on compute-3: failed
+[5/5] ovs on compute-0: ok
+`, progress.String())
+
+	// The manifest records the failed collections
+	manifest, err := common.LoadManifest(filepath.Join(localDir, common.ManifestFileName))
+	assert.NoError(t, err)
+	assert.Equal(t, "ocp", manifest.Cloud)
+	failed := manifest.Failure(filepath.Join(localDir, "nova_compute", "compute-2", "etc", "libvirt", "qemu.conf"))
+	if assert.NotNil(t, failed) {
+		assert.Equal(t, "compute-2", failed.Host)
+		assert.Equal(t, "/etc/libvirt", failed.Source)
+		assert.Contains(t, failed.Error, "failed to copy /etc/libvirt")
+	}
+
+	// Each host got the commands of its services only
+	assert.Contains(t, transports["compute-1"].Commands, "cp -R /etc/nova "+localDir+"/nova_compute/compute-1//etc/nova")
+	assert.NotContains(t, fmt.Sprint(transports["compute-1"].Commands), "openvswitch")
+	assert.NotContains(t, fmt.Sprint(transports["compute-0"].Commands), "disabled")
+}
@@ -0,0 +1,520 @@
+/*
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * Copyright 2023 Red Hat, Inc.
+ *
+ */
+
+package collectcfg
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/crypto/ssh"
+	"golang.org/x/crypto/ssh/agent"
+	"golang.org/x/crypto/ssh/knownhosts"
+)
+
+// SSHOptions are the settings of an SSH connection, from the ssh command
+// line (-F, -i, -p, -l, -o, -J, user@host) completed by the OpenSSH
+// configuration file.
+type SSHOptions struct {
+	ConfigFile            string
+	Host                  string
+	HostName              string
+	User                  string
+	Port                  string
+	IdentityFiles         []string
+	StrictHostKeyChecking string
+	UserKnownHostsFile    string
+	ProxyJump             string
+	ConnectTimeout        string
+}
+
+// ssh options followed by an argument, ignored by os-diff
+var sshArgOptions = "bBcDeEILmOQRSwW"
+
+// ParseSshCmd reads the options of an ssh command line like the ssh_cmd of
+// os-diff.cfg: "ssh -F ssh.config standalone" or "ssh -i key stack@host".
+func ParseSshCmd(sshCmd string) (SSHOptions, error) {
+	var opts SSHOptions
+	args := strings.Fields(sshCmd)
+	if len(args) == 0 || filepath.Base(args[0]) != "ssh" {
+		return opts, fmt.Errorf("not an ssh command: %s", sshCmd)
+	}
+	for i := 1; i < len(args); i++ {
+		arg := args[i]
+		if !strings.HasPrefix(arg, "-") || len(arg) < 2 {
+			if opts.Host != "" {
+				return opts, fmt.Errorf("unexpected argument %s in: %s", arg, sshCmd)
+			}
+			if user, host, ok := strings.Cut(arg, "@"); ok {
+				opts.User, opts.Host = user, host
+			} else {
+				opts.Host = arg
+			}
+			continue
+		}
+		flag := arg[1]
+		if !strings.ContainsRune("FiplJo"+sshArgOptions, rune(flag)) {
+			// Options without argument: -q, -t, -A...
+			continue
+		}
+		value := arg[2:]
+		if value == "" {
+			if i++; i >= len(args) {
+				return opts, fmt.Errorf("missing argument of %s in: %s", arg, sshCmd)
+			}
+			value = args[i]
+		}
+		switch flag {
+		case 'F':
+			opts.ConfigFile = value
+		case 'i':
+			opts.IdentityFiles = append(opts.IdentityFiles, value)
+		case 'p':
+			opts.Port = value
+		case 'l':
+			opts.User = value
+		case 'J':
+			opts.ProxyJump = value
+		case 'o':
+			key, v, ok := strings.Cut(value, "=")
+			if !ok {
+				key, v, _ = strings.Cut(value, " ")
+			}
+			opts.set(strings.TrimSpace(key), strings.TrimSpace(v))
+		}
+	}
+	return opts, nil
+}
+
+// set sets an option if it is not already set, the first value wins as in
+// OpenSSH, except for the identity files which add up.
+func (o *SSHOptions) set(key string, value string) {
+	setOnce := func(field *string) {
+		if *field == "" {
+			*field = value
+		}
+	}
+	switch strings.ToLower(key) {
+	case "hostname":
+		setOnce(&o.HostName)
+	case "user":
+		setOnce(&o.User)
+	case "port":
+		setOnce(&o.Port)
+	case "identityfile":
+		o.IdentityFiles = append(o.IdentityFiles, value)
+	case "stricthostkeychecking":
+		setOnce(&o.StrictHostKeyChecking)
+	case "userknownhostsfile":
+		setOnce(&o.UserKnownHostsFile)
+	case "proxyjump":
+		setOnce(&o.ProxyJump)
+	case "connecttimeout":
+		setOnce(&o.ConnectTimeout)
+	}
+}
+
+// ForHost returns the options to connect to a host, the options of the
+// command line are kept and completed by the Host sections of the
+// configuration file matching the host.
+func (o SSHOptions) ForHost(host string) (SSHOptions, error) {
+	opts := o
+	opts.IdentityFiles = append([]string{}, o.IdentityFiles...)
+	if host != "" {
+		opts.Host = host
+	}
+	if opts.Host == "" {
+		return opts, fmt.Errorf("no host to connect to")
+	}
+	configFile := opts.ConfigFile
+	if configFile == "" {
+		if home, err := os.UserHomeDir(); err == nil {
+			configFile = filepath.Join(home, ".ssh", "config")
+		}
+		if _, err := os.Stat(configFile); err != nil {
+			configFile = ""
+		}
+	}
+	if configFile != "" {
+		if err := opts.loadConfigFile(configFile); err != nil {
+			return opts, err
+		}
+	}
+	if opts.HostName == "" {
+		opts.HostName = opts.Host
+	}
+	opts.HostName = strings.ReplaceAll(opts.HostName, "%h", opts.Host)
+	if opts.Port == "" {
+		opts.Port = "22"
+	}
+	if opts.User == "" {
+		opts.User = os.Getenv("USER")
+	}
+	return opts, nil
+}
+
+func (o *SSHOptions) loadConfigFile(configFile string) error {
+	file, err := os.Open(configFile)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	// Options before the first Host section apply to every host
+	matching := true
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		key, value, _ := strings.Cut(line, " ")
+		if k, v, ok := strings.Cut(line, "="); ok && !strings.Contains(k, " ") {
+			key, value = k, v
+		}
+		value = strings.Trim(strings.TrimSpace(value), `"`)
+		switch strings.ToLower(key) {
+		case "host":
+			matching = sshHostMatch(strings.Fields(value), o.Host)
+		case "match":
+			// Match blocks are not supported
+			matching = false
+		default:
+			if matching {
+				o.set(key, value)
+			}
+		}
+	}
+	return scanner.Err()
+}
+
+// sshHostMatch matches a host with the patterns of a Host line, a pattern
+// starting with ! excludes the host.
+func sshHostMatch(patterns []string, host string) bool {
+	matched := false
+	for _, pattern := range patterns {
+		negated := strings.HasPrefix(pattern, "!")
+		if ok, _ := filepath.Match(strings.TrimPrefix(pattern, "!"), host); ok {
+			if negated {
+				return false
+			}
+			matched = true
+		}
+	}
+	return matched
+}
+
+// SSHTransport runs the commands on a host over a native SSH connection.
+type SSHTransport struct {
+	client *ssh.Client
+	// Jump host connection, closed with the transport
+	jump *ssh.Client
+	Sudo bool
+}
+
+// DialSSH opens an SSH connection with the options of a host.
+func DialSSH(opts SSHOptions, sudo bool) (*SSHTransport, error) {
+	cfg, closeAgent, err := opts.clientConfig()
+	if err != nil {
+		return nil, err
+	}
+	// The agent is only needed by the handshake
+	defer closeAgent()
+	addr := net.JoinHostPort(opts.HostName, opts.Port)
+	t := &SSHTransport{Sudo: sudo}
+	if opts.ProxyJump != "" && opts.ProxyJump != "none" {
+		jumpOpts, err := jumpOptions(opts)
+		if err != nil {
+			return nil, err
+		}
+		jump, err := DialSSH(jumpOpts, false)
+		if err != nil {
+			return nil, fmt.Errorf("failed to connect to the jump host %s: %w", opts.ProxyJump, err)
+		}
+		conn, err := jump.client.Dial("tcp", addr)
+		if err != nil {
+			jump.Close()
+			return nil, err
+		}
+		c, chans, reqs, err := ssh.NewClientConn(conn, addr, cfg)
+		if err != nil {
+			jump.Close()
+			return nil, err
+		}
+		t.client = ssh.NewClient(c, chans, reqs)
+		t.jump = jump.client
+		return t, nil
+	}
+	t.client, err = ssh.Dial("tcp", addr, cfg)
+	if err != nil {
+		return nil, err
+	}
+	return t, nil
+}
+
+// jumpOptions returns the options of the first jump host, [user@]host[:port].
+func jumpOptions(opts SSHOptions) (SSHOptions, error) {
+	jump := strings.Split(opts.ProxyJump, ",")[0]
+	base := SSHOptions{ConfigFile: opts.ConfigFile}
+	if user, host, ok := strings.Cut(jump, "@"); ok {
+		base.User, jump = user, host
+	}
+	if host, port, err := net.SplitHostPort(jump); err == nil {
+		base.Port, jump = port, host
+	}
+	return base.ForHost(jump)
+}
+
+// clientConfig returns the configuration of the SSH client and the function
+// closing the connection to the ssh agent.
+func (o SSHOptions) clientConfig() (*ssh.ClientConfig, func(), error) {
+	auth, closeAgent, err := o.authMethods()
+	if err != nil {
+		return nil, nil, err
+	}
+	hostKeyCallback, err := o.hostKeyCallback()
+	if err != nil {
+		closeAgent()
+		return nil, nil, err
+	}
+	timeout := 30 * time.Second
+	if seconds, err := strconv.Atoi(o.ConnectTimeout); err == nil {
+		timeout = time.Duration(seconds) * time.Second
+	}
+	return &ssh.ClientConfig{
+		User:            o.User,
+		Auth:            auth,
+		HostKeyCallback: hostKeyCallback,
+		Timeout:         timeout,
+	}, closeAgent, nil
+}
+
+// authMethods returns the keys of the ssh agent and the identity files, the
+// default ones when none is set, and the function closing the connection to
+// the agent. Encrypted keys are skipped.
+func (o SSHOptions) authMethods() ([]ssh.AuthMethod, func(), error) {
+	var methods []ssh.AuthMethod
+	closeAgent := func() {}
+	if sock := os.Getenv("SSH_AUTH_SOCK"); sock != "" {
+		if conn, err := net.Dial("unix", sock); err == nil {
+			methods = append(methods, ssh.PublicKeysCallback(agent.NewClient(conn).Signers))
+			closeAgent = func() { conn.Close() }
+		}
+	}
+	files := o.IdentityFiles
+	if len(files) == 0 {
+		files = []string{"~/.ssh/id_rsa", "~/.ssh/id_ecdsa", "~/.ssh/id_ed25519"}
+	}
+	var signers []ssh.Signer
+	for _, file := range files {
+		data, err := os.ReadFile(expandHome(file))
+		if err != nil {
+			continue
+		}
+		signer, err := ssh.ParsePrivateKey(data)
+		if err != nil {
+			var missing *ssh.PassphraseMissingError
+			if errors.As(err, &missing) {
+				continue
+			}
+			closeAgent()
+			return nil, nil, fmt.Errorf("failed to load the SSH key %s: %w", file, err)
+		}
+		signers = append(signers, signer)
+	}
+	if len(signers) > 0 {
+		methods = append(methods, ssh.PublicKeys(signers...))
+	}
+	if len(methods) == 0 {
+		return nil, nil, fmt.Errorf("no SSH key or agent to connect to %s", o.Host)
+	}
+	return methods, closeAgent, nil
+}
+
+// hostKeyCallback checks the host keys with the known hosts files, unless
+// StrictHostKeyChecking is disabled. accept-new accepts the unknown hosts.
+func (o SSHOptions) hostKeyCallback() (ssh.HostKeyCallback, error) {
+	strict := strings.ToLower(o.StrictHostKeyChecking)
+	if strict == "no" || strict == "off" {
+		return ssh.InsecureIgnoreHostKey(), nil
+	}
+	var files []string
+	knownHosts := o.UserKnownHostsFile
+	if knownHosts == "" {
+		knownHosts = "~/.ssh/known_hosts"
+	}
+	for _, file := range strings.Fields(knownHosts) {
+		if file = expandHome(file); fileExists(file) {
+			files = append(files, file)
+		}
+	}
+	check := func(hostname string, remote net.Addr, key ssh.PublicKey) error {
+		return &knownhosts.KeyError{}
+	}
+	if len(files) > 0 {
+		callback, err := knownhosts.New(files...)
+		if err != nil {
+			return nil, err
+		}
+		check = callback
+	}
+	return func(hostname string, remote net.Addr, key ssh.PublicKey) error {
+		err := check(hostname, remote, key)
+		var keyErr *knownhosts.KeyError
+		if errors.As(err, &keyErr) && len(keyErr.Want) == 0 {
+			if strict == "accept-new" {
+				return nil
+			}
+			return fmt.Errorf("host key of %s not found in %s", hostname, knownHosts)
+		}
+		return err
+	}, nil
+}
+
+func expandHome(p string) string {
+	if strings.HasPrefix(p, "~/") {
+		if home, err := os.UserHomeDir(); err == nil {
+			return filepath.Join(home, p[2:])
+		}
+	}
+	return p
+}
+
+func fileExists(p string) bool {
+	_, err := os.Stat(p)
+	return err == nil
+}
+
+func (t *SSHTransport) Exec(name string, args ...string) ([]byte, error) {
+	argv := withSudo(t.Sudo, name, args)
+	session, err := t.client.NewSession()
+	if err != nil {
+		return nil, err
+	}
+	defer session.Close()
+	var stdout, stderr bytes.Buffer
+	session.Stdout = &stdout
+	session.Stderr = &stderr
+	if err := session.Run(ShellJoin(argv)); err != nil {
+		return stdout.Bytes(), commandError(argv, err, stderr.String())
+	}
+	return stdout.Bytes(), nil
+}
+
+func (t *SSHTransport) CopyFrom(remotePath string, localPath string) error {
+	return copyWithTar(t, remotePath, localPath)
+}
+
+func (t *SSHTransport) Stat(p string) (FileInfo, error) {
+	return statWithCommand(t, p)
+}
+
+func (t *SSHTransport) ListDir(p string) ([]FileInfo, error) {
+	return listDirWithCommand(t, p)
+}
+
+// Close closes the SSH connection.
+func (t *SSHTransport) Close() error {
+	err := t.client.Close()
+	if t.jump != nil {
+		t.jump.Close()
+	}
+	return err
+}
+
+// SSHConnector opens a native SSH transport per host with the options of an
+// ssh command line, the connections are reused and shared by the concurrent
+// pulls.
+type SSHConnector struct {
+	options     SSHOptions
+	sudo        bool
+	mutex       sync.Mutex
+	connections map[string]*sshConnection
+}
+
+// sshConnection is the connection to a host, or its error, once dialed.
+type sshConnection struct {
+	dialed    chan struct{}
+	transport *SSHTransport
+	err       error
+}
+
+// NewSSHConnector returns a connector for the ssh command line of
+// os-diff.cfg, its host is the default one.
+func NewSSHConnector(sshCmd string, sudo bool) (*SSHConnector, error) {
+	options, err := ParseSshCmd(sshCmd)
+	if err != nil {
+		return nil, err
+	}
+	return &SSHConnector{options: options, sudo: sudo, connections: map[string]*sshConnection{}}, nil
+}
+
+// Connect returns the transport to a host, the host of the ssh command line
+// when empty. A host is dialed once, the pulls connecting to an unreachable
+// host get the error of the first attempt.
+func (c *SSHConnector) Connect(host string) (Transport, error) {
+	c.mutex.Lock()
+	conn, ok := c.connections[host]
+	if !ok {
+		conn = &sshConnection{dialed: make(chan struct{})}
+		c.connections[host] = conn
+	}
+	c.mutex.Unlock()
+	if ok {
+		<-conn.dialed
+	} else {
+		conn.transport, conn.err = c.dial(host)
+		close(conn.dialed)
+	}
+	if conn.err != nil {
+		return nil, conn.err
+	}
+	return conn.transport, nil
+}
+
+func (c *SSHConnector) dial(host string) (*SSHTransport, error) {
+	opts, err := c.options.ForHost(host)
+	if err != nil {
+		return nil, err
+	}
+	t, err := DialSSH(opts, c.sudo)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to %s: %w", opts.Host, err)
+	}
+	return t, nil
+}
+
+// Close closes the connections.
+func (c *SSHConnector) Close() {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	for host, conn := range c.connections {
+		<-conn.dialed
+		if conn.transport != nil {
+			conn.transport.Close()
+		}
+		delete(c.connections, host)
+	}
+}
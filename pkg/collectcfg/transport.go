@@ -0,0 +1,280 @@
+/*
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * Copyright 2023 Red Hat, Inc.
+ *
+ */
+
+package collectcfg
+
+import (
+	"archive/tar"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Transport runs the collection commands on a host and copies its files.
+type Transport interface {
+	// Exec runs a command with its arguments, without shell, and returns
+	// its standard output. The error holds the standard error.
+	Exec(name string, args ...string) ([]byte, error)
+	// CopyFrom copies a file or a directory of the host to localPath, as
+	// cp -R would when localPath does not exist.
+	CopyFrom(remotePath string, localPath string) error
+	// Stat returns the type, size and modification time of a path.
+	Stat(path string) (FileInfo, error)
+	// ListDir returns the entries of a directory.
+	ListDir(path string) ([]FileInfo, error)
+}
+
+// FileInfo describes a file of a host.
+type FileInfo struct {
+	Name    string
+	IsDir   bool
+	Size    int64
+	ModTime time.Time
+}
+
+// Connector returns the transport to a host, the empty host being the
+// director host.
+type Connector func(host string) (Transport, error)
+
+// LocalTransport runs the commands on the local host, with sudo if set.
+type LocalTransport struct {
+	Sudo bool
+}
+
+// LocalConnector returns the local transport whatever the host, for
+// os-diff running on the host of the services.
+func LocalConnector(sudo bool) Connector {
+	t := &LocalTransport{Sudo: sudo}
+	return func(host string) (Transport, error) {
+		return t, nil
+	}
+}
+
+func (t *LocalTransport) Exec(name string, args ...string) ([]byte, error) {
+	argv := withSudo(t.Sudo, name, args)
+	cmd := exec.Command(argv[0], argv[1:]...)
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	output, err := cmd.Output()
+	if err != nil {
+		return output, commandError(argv, err, stderr.String())
+	}
+	return output, nil
+}
+
+func (t *LocalTransport) CopyFrom(remotePath string, localPath string) error {
+	return copyWithTar(t, remotePath, localPath)
+}
+
+func (t *LocalTransport) Stat(p string) (FileInfo, error) {
+	if t.Sudo {
+		return statWithCommand(t, p)
+	}
+	info, err := os.Stat(p)
+	if err != nil {
+		return FileInfo{}, err
+	}
+	return fileInfo(info), nil
+}
+
+func (t *LocalTransport) ListDir(p string) ([]FileInfo, error) {
+	if t.Sudo {
+		return listDirWithCommand(t, p)
+	}
+	entries, err := os.ReadDir(p)
+	if err != nil {
+		return nil, err
+	}
+	var infos []FileInfo
+	for _, entry := range entries {
+		info, err := entry.Info()
+		if err != nil {
+			return nil, err
+		}
+		infos = append(infos, fileInfo(info))
+	}
+	return infos, nil
+}
+
+func fileInfo(info os.FileInfo) FileInfo {
+	return FileInfo{Name: info.Name(), IsDir: info.IsDir(), Size: info.Size(), ModTime: info.ModTime()}
+}
+
+func withSudo(sudo bool, name string, args []string) []string {
+	argv := append([]string{name}, args...)
+	if sudo {
+		argv = append([]string{"sudo"}, argv...)
+	}
+	return argv
+}
+
+func commandError(argv []string, err error, stderr string) error {
+	if stderr = strings.TrimSpace(stderr); stderr != "" {
+		return fmt.Errorf("%s: %w: %s", ShellJoin(argv), err, stderr)
+	}
+	return fmt.Errorf("%s: %w", ShellJoin(argv), err)
+}
+
+// Characters which don't need quotes in a shell word
+var shellSafe = regexp.MustCompile(`^[A-Za-z0-9_@%+=:,./-]+$`)
+
+// ShellQuote quotes a word for a POSIX shell.
+func ShellQuote(word string) string {
+	if shellSafe.MatchString(word) {
+		return word
+	}
+	return "'" + strings.ReplaceAll(word, "'", `'"'"'`) + "'"
+}
+
+// ShellJoin returns the shell command line running argv.
+func ShellJoin(argv []string) string {
+	quoted := make([]string, len(argv))
+	for i, word := range argv {
+		quoted[i] = ShellQuote(word)
+	}
+	return strings.Join(quoted, " ")
+}
+
+// copyWithTar archives remotePath with tar on the host and extracts it to
+// localPath, keeping the permissions and modification times.
+func copyWithTar(t Transport, remotePath string, localPath string) error {
+	remotePath = path.Clean(remotePath)
+	data, err := t.Exec("tar", "-C", path.Dir(remotePath), "-cf", "-", path.Base(remotePath))
+	if err != nil {
+		return err
+	}
+	return extractTar(bytes.NewReader(data), path.Base(remotePath), localPath)
+}
+
+// extractTar writes the entries of an archive whose top entry is base to
+// localPath.
+func extractTar(r io.Reader, base string, localPath string) error {
+	tr := tar.NewReader(r)
+	for {
+		hdr, err := tr.Next()
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		name := path.Clean(hdr.Name)
+		if name != base && !strings.HasPrefix(name, base+"/") {
+			return fmt.Errorf("unexpected entry %s in the archive of %s", hdr.Name, base)
+		}
+		target := filepath.Join(localPath, filepath.FromSlash(strings.TrimPrefix(name, base)))
+		mode := os.FileMode(hdr.Mode).Perm()
+		switch hdr.Typeflag {
+		case tar.TypeDir:
+			if err := os.MkdirAll(target, mode|0700); err != nil {
+				return err
+			}
+		case tar.TypeReg:
+			if err := os.MkdirAll(filepath.Dir(target), 0755); err != nil {
+				return err
+			}
+			file, err := os.OpenFile(target, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, mode|0600)
+			if err != nil {
+				return err
+			}
+			_, err = io.Copy(file, tr)
+			if closeErr := file.Close(); err == nil {
+				err = closeErr
+			}
+			if err != nil {
+				return err
+			}
+		case tar.TypeSymlink:
+			os.Remove(target)
+			if err := os.Symlink(hdr.Linkname, target); err != nil {
+				return err
+			}
+			continue
+		default:
+			continue
+		}
+		if err := os.Chtimes(target, hdr.ModTime, hdr.ModTime); err != nil {
+			return err
+		}
+	}
+}
+
+// statWithCommand runs stat on the host, for the transports which can't
+// read the files directly.
+func statWithCommand(t Transport, p string) (FileInfo, error) {
+	output, err := t.Exec("stat", "-L", "-c", "%F|%s|%Y|%n", p)
+	if err != nil {
+		return FileInfo{}, err
+	}
+	info, err := parseFileInfo(strings.TrimSpace(string(output)))
+	if err != nil {
+		return FileInfo{}, err
+	}
+	info.Name = path.Base(info.Name)
+	return info, nil
+}
+
+func listDirWithCommand(t Transport, p string) ([]FileInfo, error) {
+	output, err := t.Exec("find", "-L", p, "-mindepth", "1", "-maxdepth", "1", "-printf", "%y|%s|%T@|%f\\n")
+	if err != nil {
+		return nil, err
+	}
+	var infos []FileInfo
+	for _, line := range strings.Split(strings.TrimSpace(string(output)), "\n") {
+		if line == "" {
+			continue
+		}
+		info, err := parseFileInfo(line)
+		if err != nil {
+			return nil, err
+		}
+		infos = append(infos, info)
+	}
+	return infos, nil
+}
+
+// parseFileInfo reads a "type|size|mtime|name" line, the type being a stat
+// %F type or a find %y letter.
+func parseFileInfo(line string) (FileInfo, error) {
+	parts := strings.SplitN(line, "|", 4)
+	if len(parts) != 4 {
+		return FileInfo{}, fmt.Errorf("unexpected file information: %s", line)
+	}
+	size, err := strconv.ParseInt(parts[1], 10, 64)
+	if err != nil {
+		return FileInfo{}, fmt.Errorf("unexpected file size: %s", line)
+	}
+	seconds, err := strconv.ParseFloat(parts[2], 64)
+	if err != nil {
+		return FileInfo{}, fmt.Errorf("unexpected modification time: %s", line)
+	}
+	return FileInfo{
+		Name:    parts[3],
+		IsDir:   parts[0] == "directory" || parts[0] == "d",
+		Size:    size,
+		ModTime: time.Unix(int64(seconds), 0),
+	}, nil
+}
@@ -0,0 +1,215 @@
+/*
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * Copyright 2023 Red Hat, Inc.
+ *
+ */
+
+package collectcfg_test
+
+import (
+	"io"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/openstack-k8s-operators/os-diff/pkg/collectcfg"
+	"github.com/openstack-k8s-operators/os-diff/pkg/common"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestShellJoin(t *testing.T) {
+	tests := []struct {
+		name     string
+		argv     []string
+		expected string
+	}{
+		{"plain", []string{"podman", "cp", "abc:/etc/nova", "/tmp/nova"}, "podman cp abc:/etc/nova /tmp/nova"},
+		{"spaces", []string{"cp", "-R", "/etc/my dir", "/tmp/x"}, "cp -R '/etc/my dir' /tmp/x"},
+		{"quote", []string{"echo", "it's"}, `echo 'it'"'"'s'`},
+		{"shell characters", []string{"ls", "*;rm"}, "ls '*;rm'"},
+		{"empty", []string{"echo", ""}, "echo ''"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, collectcfg.ShellJoin(tt.argv))
+		})
+	}
+}
+
+func TestParseSshCmd(t *testing.T) {
+	opts, err := collectcfg.ParseSshCmd("ssh -F ssh.config standalone")
+	assert.NoError(t, err)
+	assert.Equal(t, collectcfg.SSHOptions{ConfigFile: "ssh.config", Host: "standalone"}, opts)
+
+	opts, err = collectcfg.ParseSshCmd("ssh -q -i /home/user/.ssh/id_rsa -p 2222 -o StrictHostKeyChecking=no stack@my.undercloud.local")
+	assert.NoError(t, err)
+	assert.Equal(t, collectcfg.SSHOptions{
+		Host:                  "my.undercloud.local",
+		User:                  "stack",
+		Port:                  "2222",
+		IdentityFiles:         []string{"/home/user/.ssh/id_rsa"},
+		StrictHostKeyChecking: "no",
+	}, opts)
+
+	_, err = collectcfg.ParseSshCmd("ssh standalone podman ps")
+	assert.Error(t, err)
+	_, err = collectcfg.ParseSshCmd("ssh -F")
+	assert.Error(t, err)
+	_, err = collectcfg.ParseSshCmd("rsh standalone")
+	assert.Error(t, err)
+}
+
+func TestSSHOptionsForHost(t *testing.T) {
+	configFile := filepath.Join(t.TempDir(), "ssh.config")
+	assert.NoError(t, os.WriteFile(configFile, []byte(`Host standalone
+  HostName 192.168.122.100
+  User root
+  IdentityFile ~/.ssh/id_rsa
+  StrictHostKeyChecking no
+
+Host compute-* !compute-2
+  HostName %h.ctlplane.localdomain
+  Port 2022
+
+Host *
+  User stack
+  UserKnownHostsFile /dev/null
+`), 0644))
+
+	base := collectcfg.SSHOptions{ConfigFile: configFile, Host: "standalone", Port: "22022"}
+	opts, err := base.ForHost("")
+	assert.NoError(t, err)
+	assert.Equal(t, "192.168.122.100", opts.HostName)
+	assert.Equal(t, "root", opts.User)
+	// The command line options win over the configuration file
+	assert.Equal(t, "22022", opts.Port)
+	assert.Equal(t, []string{"~/.ssh/id_rsa"}, opts.IdentityFiles)
+	assert.Equal(t, "no", opts.StrictHostKeyChecking)
+
+	opts, err = collectcfg.SSHOptions{ConfigFile: configFile}.ForHost("compute-0")
+	assert.NoError(t, err)
+	assert.Equal(t, "compute-0.ctlplane.localdomain", opts.HostName)
+	assert.Equal(t, "2022", opts.Port)
+	assert.Equal(t, "stack", opts.User)
+	assert.Equal(t, "/dev/null", opts.UserKnownHostsFile)
+
+	opts, err = collectcfg.SSHOptions{ConfigFile: configFile}.ForHost("compute-2")
+	assert.NoError(t, err)
+	assert.Equal(t, "compute-2", opts.HostName)
+	assert.Equal(t, "22", opts.Port)
+}
+
+func TestDialSSHClosesTheAgent(t *testing.T) {
+	agentSock := filepath.Join(t.TempDir(), "agent.sock")
+	agent, err := net.Listen("unix", agentSock)
+	assert.NoError(t, err)
+	defer agent.Close()
+	t.Setenv("SSH_AUTH_SOCK", agentSock)
+
+	// Nothing listens on the port of the host
+	closed, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.NoError(t, err)
+	_, port, _ := net.SplitHostPort(closed.Addr().String())
+	closed.Close()
+
+	opts := collectcfg.SSHOptions{Host: "standalone", HostName: "127.0.0.1", Port: port, StrictHostKeyChecking: "no"}
+	_, err = collectcfg.DialSSH(opts, false)
+	assert.Error(t, err)
+
+	conn, err := agent.Accept()
+	assert.NoError(t, err)
+	defer conn.Close()
+	assert.NoError(t, conn.SetReadDeadline(time.Now().Add(5*time.Second)))
+	_, err = conn.Read(make([]byte, 1))
+	// The connection to the agent is closed, not left open until the timeout
+	assert.ErrorIs(t, err, io.EOF)
+}
+
+func TestLocalTransport(t *testing.T) {
+	dir := t.TempDir()
+	src := filepath.Join(dir, "etc dir", "nova")
+	assert.NoError(t, os.MkdirAll(filepath.Join(src, "nova.conf.d"), 0755))
+	assert.NoError(t, os.WriteFile(filepath.Join(src, "nova.conf"), []byte("[DEFAULT]\n"), 0640))
+	assert.NoError(t, os.WriteFile(filepath.Join(src, "nova.conf.d", "01-nova.conf"), []byte("[api]\n"), 0644))
+
+	transport := &collectcfg.LocalTransport{}
+	output, err := transport.Exec("cat", filepath.Join(src, "nova.conf"))
+	assert.NoError(t, err)
+	assert.Equal(t, "[DEFAULT]\n", string(output))
+
+	_, err = transport.Exec("cat", filepath.Join(dir, "missing"))
+	assert.ErrorContains(t, err, "No such file")
+
+	dest := filepath.Join(dir, "local copy", "nova")
+	assert.NoError(t, transport.CopyFrom(src, dest))
+	data, err := os.ReadFile(filepath.Join(dest, "nova.conf.d", "01-nova.conf"))
+	assert.NoError(t, err)
+	assert.Equal(t, "[api]\n", string(data))
+	info, err := os.Stat(filepath.Join(dest, "nova.conf"))
+	assert.NoError(t, err)
+	assert.Equal(t, os.FileMode(0640), info.Mode().Perm())
+
+	stat, err := transport.Stat(src)
+	assert.NoError(t, err)
+	assert.True(t, stat.IsDir)
+	entries, err := transport.ListDir(src)
+	assert.NoError(t, err)
+	assert.Len(t, entries, 2)
+	assert.Equal(t, "nova.conf", entries[0].Name)
+	assert.Equal(t, int64(10), entries[0].Size)
+}
+
+func TestPullWithFakeTransport(t *testing.T) {
+	fake := collectcfg.NewFakeTransport()
+	fake.Outputs["podman ps -a --format json"] = `[{"ID": "0123456789abcdef", "Image": "nova", "Names": ["nova_api"]}]`
+	fake.Files["/etc/my service/app.conf"] = "[DEFAULT]\n"
+
+	id, err := collectcfg.GetPodmanId("nova_api", fake)
+	assert.NoError(t, err)
+	assert.Equal(t, "0123456789ab", id)
+
+	assert.NoError(t, collectcfg.PullPodmanFiles(id, "/etc/nova", "/tmp/tripleo/nova/etc", fake))
+	assert.NoError(t, collectcfg.PullLocalFiles("/etc/my service", "/tmp/tripleo/app/standalone/etc/my service", fake))
+	assert.NoError(t, collectcfg.GetCommandOutput("ovs-vsctl list Open_vSwitch", "/tmp/tripleo/ovs/out put", fake))
+	assert.Equal(t, []string{
+		"podman ps -a --format json",
+		"podman cp 0123456789ab:/etc/nova /tmp/tripleo/nova/etc",
+		"cp -R '/etc/my service' '/tmp/tripleo/app/standalone/etc/my service'",
+		`sh -c 'ovs-vsctl list Open_vSwitch > '"'"'/tmp/tripleo/ovs/out put'"'"''`,
+	}, fake.Commands)
+
+	err = collectcfg.PullLocalFiles("/etc/missing", "/tmp/tripleo/missing", fake)
+	assert.Equal(t, 3, common.ExitCode(err))
+
+	fake.Fail["podman cp 0123456789ab:/etc/cinder /tmp/tripleo/cinder/etc"] = true
+	err = collectcfg.PullPodmanFiles(id, "/etc/cinder", "/tmp/tripleo/cinder/etc", fake)
+	assert.ErrorContains(t, err, "failed to copy /etc/cinder from container 0123456789ab")
+}
+
+func TestSyncConfigDir(t *testing.T) {
+	fake := collectcfg.NewFakeTransport()
+	fake.Files["/tmp/tripleo/nova/etc/nova/nova.conf"] = "[DEFAULT]\n"
+	connect := collectcfg.FakeConnector(map[string]*collectcfg.FakeTransport{"standalone": fake})
+
+	local := t.TempDir()
+	assert.NoError(t, collectcfg.SyncConfigDir(local, "/tmp/tripleo", connect, "standalone"))
+	data, err := os.ReadFile(filepath.Join(local, "tripleo", "nova", "etc", "nova", "nova.conf"))
+	assert.NoError(t, err)
+	assert.Equal(t, "[DEFAULT]\n", string(data))
+
+	err = collectcfg.SyncConfigDir(local, "/tmp/tripleo", connect, "controller-0")
+	assert.ErrorContains(t, err, "failed to connect to controller-0")
+}
@@ -28,8 +28,14 @@ var oDConfig ODConfig
 // OS Diff Config Structure
 type ODConfig struct {
 	Default struct {
-		LocalConfigDir    string `ini:"local_config_dir"`
-		ServiceConfigFile string `ini:"service_config_file"`
+		LocalConfigDir    string   `ini:"local_config_dir"`
+		ServiceConfigFile string   `ini:"service_config_file"`
+		IgnoreFile        string   `ini:"ignore_file"`
+		SecretKeys        []string `ini:"secret_keys" delim:","`
+		RedactionKey      string   `ini:"redaction_key"`
+		Normalize         bool     `ini:"normalize"`
+		NormalizeFile     string   `ini:"normalize_file"`
+		DefaultsDir       string   `ini:"defaults_dir"`
 	} `ini:"Default"`
 
 	Tripleo struct {
@@ -45,6 +51,8 @@ type ODConfig struct {
 	Openshift struct {
 		OcpLocalConfigPath string `ini:"ocp_local_config_path"`
 		Connection         string `ini:"connection"`
+		Kubeconfig         string `ini:"kubeconfig"`
+		Namespace          string `ini:"namespace"`
 	} `ini:"Openshift"`
 }
 
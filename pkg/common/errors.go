@@ -0,0 +1,76 @@
+/*
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * Copyright 2023 Red Hat, Inc.
+ *
+ */
+
+package common
+
+import (
+	"errors"
+	"fmt"
+)
+
+// os-diff exit codes
+const (
+	ExitNoDifference = 0
+	ExitDifferences  = 1
+	ExitUsage        = 2
+	ExitCollection   = 3
+)
+
+// ErrDifferencesFound is returned by the commands when the comparison went
+// fine but differences have been found.
+var ErrDifferencesFound = errors.New("differences found")
+
+// ExitError is an error carrying the exit code os-diff should end with.
+type ExitError struct {
+	Code int
+	Err  error
+}
+
+func (e *ExitError) Error() string {
+	return e.Err.Error()
+}
+
+func (e *ExitError) Unwrap() error {
+	return e.Err
+}
+
+// UsageError is a wrong argument, configuration or input file error.
+func UsageError(format string, a ...interface{}) error {
+	return &ExitError{Code: ExitUsage, Err: fmt.Errorf(format, a...)}
+}
+
+// CollectionError is an error while getting data from the clouds
+// (ssh, podman, oc...).
+func CollectionError(format string, a ...interface{}) error {
+	return &ExitError{Code: ExitCollection, Err: fmt.Errorf(format, a...)}
+}
+
+// ExitCode returns the exit code for an error returned by a command,
+// errors without a code are considered as usage errors.
+func ExitCode(err error) int {
+	if err == nil {
+		return ExitNoDifference
+	}
+	if errors.Is(err, ErrDifferencesFound) {
+		return ExitDifferences
+	}
+	var exitErr *ExitError
+	if errors.As(err, &exitErr) {
+		return exitErr.Code
+	}
+	return ExitUsage
+}
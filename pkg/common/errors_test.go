@@ -0,0 +1,57 @@
+/*
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * Copyright 2023 Red Hat, Inc.
+ *
+ */
+package common_test
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"testing"
+
+	"github.com/openstack-k8s-operators/os-diff/pkg/common"
+)
+
+// Test case for function ExitCode
+func TestExitCode(t *testing.T) {
+	tests := []struct {
+		name     string
+		err      error
+		expected int
+	}{
+		{"No error", nil, common.ExitNoDifference},
+		{"Differences found", common.ErrDifferencesFound, common.ExitDifferences},
+		{"Usage error", common.UsageError("wrong argument"), common.ExitUsage},
+		{"Collection error", common.CollectionError("ssh failed"), common.ExitCollection},
+		{"Wrapped collection error", fmt.Errorf("pull: %w", common.CollectionError("ssh failed")), common.ExitCollection},
+		{"Unknown error", errors.New("boom"), common.ExitUsage},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if code := common.ExitCode(test.err); code != test.expected {
+				t.Errorf("Expected exit code %d, but got %d", test.expected, code)
+			}
+		})
+	}
+}
+
+func TestCollectionErrorUnwrap(t *testing.T) {
+	err := common.CollectionError("failed to copy: %w", os.ErrNotExist)
+	if !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("Expected the collection error to wrap %v", os.ErrNotExist)
+	}
+}
@@ -0,0 +1,184 @@
+/*
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * Copyright 2023 Red Hat, Inc.
+ *
+ */
+
+package common
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// ManifestFileName is the name of the manifest written by os-diff pull at
+// the root of the collected configuration.
+const ManifestFileName = "os-diff-manifest.json"
+
+// Manifest records what os-diff pull collected, from where and when.
+type Manifest struct {
+	Version     int            `json:"version"`
+	Cloud       string         `json:"cloud"`
+	CollectedAt time.Time      `json:"collected_at"`
+	Files       []ManifestFile `json:"files"`
+	// Directory holding the manifest, set when loaded
+	Root string `json:"-"`
+}
+
+// ManifestFile is a collected file, or a collection which failed, Path being
+// relative to the manifest directory.
+type ManifestFile struct {
+	Path       string `json:"path"`
+	Service    string `json:"service,omitempty"`
+	Host       string `json:"host,omitempty"`
+	Source     string `json:"source,omitempty"`
+	PodmanId   string `json:"podman_id,omitempty"`
+	PodmanName string `json:"podman_name,omitempty"`
+	Image      string `json:"image,omitempty"`
+	Pod        string `json:"pod,omitempty"`
+	Container  string `json:"container,omitempty"`
+	SHA256     string `json:"sha256,omitempty"`
+	Size       int64  `json:"size"`
+	Mode       string `json:"mode,omitempty"`
+	Error      string `json:"error,omitempty"`
+}
+
+// Describe returns where a file has been collected from, for the reports.
+func (f *ManifestFile) Describe() string {
+	var parts []string
+	if f.Service != "" {
+		parts = append(parts, "service "+f.Service)
+	}
+	if f.Host != "" {
+		parts = append(parts, "host "+f.Host)
+	}
+	if f.PodmanId != "" {
+		parts = append(parts, "container "+strings.TrimSpace(f.PodmanName+" "+f.PodmanId))
+	}
+	if f.Image != "" {
+		parts = append(parts, "image "+f.Image)
+	}
+	if f.Pod != "" {
+		parts = append(parts, "pod "+f.Pod)
+	}
+	if f.Container != "" {
+		parts = append(parts, "container "+f.Container)
+	}
+	if f.Source != "" {
+		parts = append(parts, "path "+f.Source)
+	}
+	if f.Error != "" {
+		parts = append(parts, "error: "+f.Error)
+	} else if f.SHA256 != "" {
+		parts = append(parts, "sha256 "+f.SHA256)
+	}
+	return strings.Join(parts, ", ")
+}
+
+// WriteManifest writes the manifest to ManifestFileName in dir.
+func WriteManifest(dir string, manifest *Manifest) error {
+	data, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(filepath.Join(dir, ManifestFileName), append(data, '\n'), 0644)
+}
+
+// LoadManifest reads a manifest file.
+func LoadManifest(manifestPath string) (*Manifest, error) {
+	data, err := os.ReadFile(manifestPath)
+	if err != nil {
+		return nil, err
+	}
+	var manifest Manifest
+	if err := json.Unmarshal(data, &manifest); err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", manifestPath, err)
+	}
+	manifest.Root = filepath.Dir(manifestPath)
+	return &manifest, nil
+}
+
+// FindManifest returns the manifest of the collection holding p, looked up
+// in p and its parent directories, nil when there is none.
+func FindManifest(p string) *Manifest {
+	dir, err := filepath.Abs(p)
+	if err != nil {
+		return nil
+	}
+	for {
+		if manifest, err := LoadManifest(filepath.Join(dir, ManifestFileName)); err == nil {
+			return manifest
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return nil
+		}
+		dir = parent
+	}
+}
+
+// Lookup returns the entry of a collected file, nil when the manifest
+// doesn't know it.
+func (m *Manifest) Lookup(p string) *ManifestFile {
+	rel, ok := m.relPath(p)
+	if !ok {
+		return nil
+	}
+	for i := range m.Files {
+		if m.Files[i].Path == rel {
+			return &m.Files[i]
+		}
+	}
+	return nil
+}
+
+// Failure returns the failed collection a missing path should have come
+// from, nil when there is none.
+func (m *Manifest) Failure(p string) *ManifestFile {
+	rel, ok := m.relPath(p)
+	if !ok {
+		return nil
+	}
+	var found *ManifestFile
+	for i := range m.Files {
+		file := &m.Files[i]
+		if file.Error == "" {
+			continue
+		}
+		if rel == file.Path || strings.HasPrefix(rel, path.Clean(file.Path)+"/") {
+			if found == nil || len(file.Path) > len(found.Path) {
+				found = file
+			}
+		}
+	}
+	return found
+}
+
+// relPath returns p relative to the manifest directory, with slashes.
+func (m *Manifest) relPath(p string) (string, bool) {
+	abs, err := filepath.Abs(p)
+	if err != nil {
+		return "", false
+	}
+	rel, err := filepath.Rel(m.Root, abs)
+	if err != nil || rel == ".." || strings.HasPrefix(rel, "../") {
+		return "", false
+	}
+	return filepath.ToSlash(rel), true
+}
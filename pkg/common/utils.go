@@ -29,8 +29,6 @@ import (
 	"gopkg.in/yaml.v3"
 )
 
-var config Config
-
 // Service YAML Config Structure
 type Service struct {
 	Enable             bool              `yaml:"enable"`
@@ -38,6 +36,7 @@ type Service struct {
 	PodmanImage        string            `yaml:"podman_image"`
 	PodmanName         string            `yaml:"podman_name"`
 	PodName            string            `yaml:"pod_name"`
+	PodSelector        string            `yaml:"pod_selector"`
 	ContainerName      string            `yaml:"container_name"`
 	StrictPodNameMatch bool              `yaml:"strict_pod_name_match"`
 	Path               []string          `yaml:"path"`
@@ -79,15 +78,6 @@ func ExecComplexCmd(cmd string) (string, error) {
 	return string(output), nil
 }
 
-func TestOCConnection() bool {
-	cmd := "oc whoami"
-	_, err := ExecCmd(cmd)
-	if err != nil {
-		return false
-	}
-	return true
-}
-
 func TestSshConnection(sshCmd string) bool {
 	cmd := sshCmd + " ls"
 	_, err := ExecCmd(cmd)
@@ -155,6 +145,8 @@ func GetNestedFieldValue(data interface{}, keyName string) interface{} {
 }
 
 func LoadServiceConfigFile(configPath string) (Config, error) {
+	// Decode into a new config, the services of another file must not leak
+	var config Config
 	file, err := os.Open(configPath)
 	if err != nil {
 		fmt.Println("Error opening file:", err)
@@ -193,9 +185,15 @@ func SnakeToCamel(s string) string {
 	return result
 }
 
+// IsIni returns true if the first line which is not empty or a comment
+// starts a section header.
 func IsIni(data []byte) bool {
-	if data[0] == '[' {
-		return true
+	for _, line := range strings.Split(string(data), "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") || strings.HasPrefix(line, ";") {
+			continue
+		}
+		return strings.HasPrefix(line, "[")
 	}
 	return false
 }
@@ -203,7 +201,6 @@ func IsIni(data []byte) bool {
 func IsYaml(data []byte) bool {
 	var yamlData interface{}
 	if err := yaml.Unmarshal(data, &yamlData); err != nil {
-		fmt.Println("Error unmarshaling YAML:", err)
 		return false
 	}
 	return true
@@ -217,12 +214,12 @@ func IsJson(data []byte) bool {
 
 func DetectType(value []byte) string {
 	switch {
+	case IsJson(value):
+		return "json"
 	case IsIni(value):
 		return "ini"
 	case IsYaml(value):
 		return "yaml"
-	case IsJson(value):
-		return "json"
 	default:
 		return "raw"
 	}
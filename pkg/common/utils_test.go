@@ -17,6 +17,8 @@
 package common_test
 
 import (
+	"os"
+	"path/filepath"
 	"testing"
 
 	"github.com/openstack-k8s-operators/os-diff/pkg/common"
@@ -121,6 +123,21 @@ func TestIsIni(t *testing.T) {
 			data:     []byte{'[', 'a', 'b', 'c'},
 			expected: true,
 		},
+		{
+			name:     "Section after comments",
+			data:     []byte("# nova.conf\n\n; generated\n[DEFAULT]\n"),
+			expected: true,
+		},
+		{
+			name:     "Empty data",
+			data:     []byte{},
+			expected: false,
+		},
+		{
+			name:     "Key before the first section",
+			data:     []byte("key=value\n[DEFAULT]\n"),
+			expected: false,
+		},
 	}
 
 	for _, test := range tests {
@@ -318,3 +335,26 @@ func TestBuildFullSshCmdWrongHostWithConfig(t *testing.T) {
 		t.Errorf("Unexpected error, got: %v, want: %s", err, expectedError)
 	}
 }
+
+func TestLoadServiceConfigFile(t *testing.T) {
+	dir := t.TempDir()
+	first := filepath.Join(dir, "first.yaml")
+	second := filepath.Join(dir, "second.yaml")
+	if err := os.WriteFile(first, []byte("services:\n  nova:\n    enable: true\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(second, []byte("services:\n  glance:\n    enable: true\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := common.LoadServiceConfigFile(first); err != nil {
+		t.Fatal(err)
+	}
+	config, err := common.LoadServiceConfigFile(second)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// The services of the first file must not leak into the second one
+	if _, ok := config.Services["nova"]; ok || len(config.Services) != 1 {
+		t.Errorf("Expected only the glance service but got %v", config.Services)
+	}
+}
@@ -0,0 +1,226 @@
+/*
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * Copyright 2023 Red Hat, Inc.
+ *
+ */
+package godiff
+
+import (
+	"path/filepath"
+	"strings"
+)
+
+// CatalogVersion is bumped every time the catalog of expected differences
+// changes, it is written in the JSON report.
+const CatalogVersion = "1"
+
+// ExpectedRule is an INI option the openstack-k8s-operators always set
+// differently from TripleO. Section and Key are glob patterns.
+type ExpectedRule struct {
+	Section string
+	Key     string
+	Reason  string
+}
+
+const (
+	reasonDatabase  = "database endpoint managed by the operators"
+	reasonMessaging = "messaging endpoint managed by the operators"
+	reasonMemcache  = "memcached endpoint managed by the operators"
+	reasonKeystone  = "keystone endpoint managed by the operators"
+	reasonLogging   = "operators log to stdout"
+	reasonBind      = "bind address set by the operators in the pods"
+	reasonHost      = "host name differs between the nodes and the pods"
+	reasonEndpoint  = "service endpoint managed by the operators"
+)
+
+// commonExpected applies to every service of the catalog.
+var commonExpected = []ExpectedRule{
+	{Section: "DEFAULT", Key: "transport_url", Reason: reasonMessaging},
+	{Section: "oslo_messaging_notifications", Key: "transport_url", Reason: reasonMessaging},
+	{Section: "database", Key: "connection", Reason: reasonDatabase},
+	{Section: "api_database", Key: "connection", Reason: reasonDatabase},
+	{Section: "cache", Key: "memcache_servers", Reason: reasonMemcache},
+	{Section: "keystone_authtoken", Key: "memcached_servers", Reason: reasonMemcache},
+	{Section: "keystone_authtoken", Key: "www_authenticate_uri", Reason: reasonKeystone},
+	{Section: "keystone_authtoken", Key: "auth_uri", Reason: reasonKeystone},
+	{Section: "keystone_authtoken", Key: "auth_url", Reason: reasonKeystone},
+	{Section: "keystone_authtoken", Key: "region_name", Reason: reasonKeystone},
+	{Section: "DEFAULT", Key: "log_dir", Reason: reasonLogging},
+	{Section: "DEFAULT", Key: "log_file", Reason: reasonLogging},
+	{Section: "DEFAULT", Key: "bind_host", Reason: reasonBind},
+	{Section: "DEFAULT", Key: "host", Reason: reasonHost},
+}
+
+// expectedCatalog is keyed by the service names used in config.yaml.
+var expectedCatalog = map[string][]ExpectedRule{
+	"keystone": withCommon(
+		ExpectedRule{Section: "DEFAULT", Key: "public_endpoint", Reason: reasonEndpoint},
+		ExpectedRule{Section: "DEFAULT", Key: "admin_endpoint", Reason: reasonEndpoint},
+	),
+	"glance": withCommon(
+		ExpectedRule{Section: "DEFAULT", Key: "bind_port", Reason: reasonBind},
+		ExpectedRule{Section: "DEFAULT", Key: "worker_self_reference_url", Reason: reasonEndpoint},
+	),
+	"cinder": withCommon(
+		ExpectedRule{Section: "DEFAULT", Key: "osapi_volume_listen", Reason: reasonBind},
+		ExpectedRule{Section: "DEFAULT", Key: "glance_api_servers", Reason: reasonEndpoint},
+		ExpectedRule{Section: "nova", Key: "auth_url", Reason: reasonKeystone},
+	),
+	"nova": withCommon(
+		ExpectedRule{Section: "DEFAULT", Key: "my_ip", Reason: reasonHost},
+		ExpectedRule{Section: "DEFAULT", Key: "osapi_compute_listen", Reason: reasonBind},
+		ExpectedRule{Section: "DEFAULT", Key: "metadata_listen", Reason: reasonBind},
+		ExpectedRule{Section: "vnc", Key: "server_listen", Reason: reasonBind},
+		ExpectedRule{Section: "vnc", Key: "novncproxy_base_url", Reason: reasonEndpoint},
+		ExpectedRule{Section: "glance", Key: "api_servers", Reason: reasonEndpoint},
+		ExpectedRule{Section: "neutron", Key: "auth_url", Reason: reasonKeystone},
+		ExpectedRule{Section: "placement", Key: "auth_url", Reason: reasonKeystone},
+		ExpectedRule{Section: "cinder", Key: "auth_url", Reason: reasonKeystone},
+	),
+	"neutron": withCommon(
+		ExpectedRule{Section: "nova", Key: "auth_url", Reason: reasonKeystone},
+		ExpectedRule{Section: "placement", Key: "auth_url", Reason: reasonKeystone},
+		ExpectedRule{Section: "ovn", Key: "ovn_nb_connection", Reason: reasonDatabase},
+		ExpectedRule{Section: "ovn", Key: "ovn_sb_connection", Reason: reasonDatabase},
+	),
+	"placement": withCommon(
+		ExpectedRule{Section: "placement_database", Key: "connection", Reason: reasonDatabase},
+	),
+	"heat": withCommon(
+		ExpectedRule{Section: "heat_api", Key: "bind_host", Reason: reasonBind},
+		ExpectedRule{Section: "heat_api_cfn", Key: "bind_host", Reason: reasonBind},
+		ExpectedRule{Section: "trustee", Key: "auth_url", Reason: reasonKeystone},
+		ExpectedRule{Section: "clients_keystone", Key: "auth_uri", Reason: reasonKeystone},
+	),
+	"barbican": withCommon(
+		ExpectedRule{Section: "DEFAULT", Key: "sql_connection", Reason: reasonDatabase},
+		ExpectedRule{Section: "DEFAULT", Key: "host_href", Reason: reasonEndpoint},
+	),
+	"manila": withCommon(
+		ExpectedRule{Section: "DEFAULT", Key: "osapi_share_listen", Reason: reasonBind},
+	),
+	"octavia": withCommon(
+		ExpectedRule{Section: "api_settings", Key: "bind_host", Reason: reasonBind},
+		ExpectedRule{Section: "service_auth", Key: "auth_url", Reason: reasonKeystone},
+	),
+	"ironic": withCommon(
+		ExpectedRule{Section: "api", Key: "host_ip", Reason: reasonBind},
+		ExpectedRule{Section: "service_catalog", Key: "auth_url", Reason: reasonKeystone},
+	),
+	"swift": withCommon(
+		ExpectedRule{Section: "filter:authtoken", Key: "auth_url", Reason: reasonKeystone},
+		ExpectedRule{Section: "filter:authtoken", Key: "www_authenticate_uri", Reason: reasonKeystone},
+		ExpectedRule{Section: "filter:cache", Key: "memcache_servers", Reason: reasonMemcache},
+	),
+}
+
+func withCommon(rules ...ExpectedRule) []ExpectedRule {
+	return append(append([]ExpectedRule{}, commonExpected...), rules...)
+}
+
+var useCatalog = true
+var catalogService string
+
+// SetCatalog enables or disables the catalog of expected differences,
+// service forces the catalog entry to use instead of guessing it from the
+// compared paths.
+func SetCatalog(enabled bool, service string) {
+	useCatalog = enabled
+	catalogService = service
+}
+
+// CatalogService guesses the service of a file from its path: its name
+// (keystone.conf, glance-api.conf), its /etc/<service>/ directory, or the
+// closest directory named after a service of the catalog.
+func CatalogService(path string) string {
+	return guessService(path, func(service string) bool {
+		_, ok := expectedCatalog[service]
+		return ok
+	})
+}
+
+// guessService returns the service forced with SetCatalog, or the service
+// for which known returns true named by, in order: the file name without
+// extension or its prefix before a dash or an underscore, the directory
+// following etc, the closest parent directory.
+func guessService(path string, known func(string) bool) string {
+	if catalogService != "" {
+		return catalogService
+	}
+	parts := strings.Split(filepath.ToSlash(filepath.Clean(path)), "/")
+	base := strings.TrimSuffix(parts[len(parts)-1], filepath.Ext(path))
+	if known(base) {
+		return base
+	}
+	if i := strings.IndexAny(base, "-_"); i > 0 && known(base[:i]) {
+		return base[:i]
+	}
+	dirs := parts[:len(parts)-1]
+	for i := len(dirs) - 2; i >= 0; i-- {
+		if dirs[i] == "etc" && known(dirs[i+1]) {
+			return dirs[i+1]
+		}
+	}
+	for i := len(dirs) - 1; i >= 0; i-- {
+		if known(dirs[i]) {
+			return dirs[i]
+		}
+	}
+	return ""
+}
+
+// MatchExpected returns the reason why the difference is expected for the
+// service, or an empty string.
+func MatchExpected(service string, e Entry) string {
+	if e.Path == "" {
+		return ""
+	}
+	for _, rule := range expectedCatalog[service] {
+		if globMatch(rule.Section, e.Section) && globMatch(rule.Key, e.Path) {
+			return rule.Reason
+		}
+	}
+	return ""
+}
+
+// Classify moves the differences which are not actual drift out of the
+// result entries: the differences matching the ignore rules, options set to
+// their default value on one side only, then the expected differences of the
+// catalog. The service is guessed from path. It is called once by each
+// comparison, after the comparer.
+func Classify(result *Result, path string) {
+	ignoreRules.Apply(result)
+	ApplyDefaults(result, DefaultsService(path))
+	ApplyCatalog(result, CatalogService(path))
+}
+
+// ApplyCatalog moves the INI differences listed in the catalog for the
+// service to the expected entries of the result. Only the oslo.config INI
+// files are covered, the catalog has no entry for the MySQL my.cnf files.
+func ApplyCatalog(result *Result, service string) {
+	if !useCatalog || result == nil || result.Format != "ini" || service == "" {
+		return
+	}
+	entries := []Entry{}
+	for _, e := range result.Entries {
+		if reason := MatchExpected(service, e); reason != "" {
+			e.Reason = reason
+			log.Info("Expected difference: [", e.Section, "] ", e.Path, " in: ", result.Origin, " (", reason, ")")
+			result.Expected = append(result.Expected, e)
+		} else {
+			entries = append(entries, e)
+		}
+	}
+	result.Entries = entries
+}
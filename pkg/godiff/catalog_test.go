@@ -0,0 +1,85 @@
+/*
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * Copyright 2023 Red Hat, Inc.
+ *
+ */
+package godiff_test
+
+import (
+	"testing"
+
+	"github.com/openstack-k8s-operators/os-diff/pkg/godiff"
+	"github.com/stretchr/testify/assert"
+)
+
+// Test case for function CatalogService
+func TestCatalogService(t *testing.T) {
+	testCases := []struct {
+		path     string
+		expected string
+	}{
+		{"/tmp/collect_tripleo_configs/nova/etc/nova/nova.conf", "nova"},
+		{"/tmp/collect_tripleo_configs/keystone/etc/httpd/conf/httpd.conf", "keystone"},
+		{"tests/podman/keystone.conf", "keystone"},
+		{"/etc/my.cnf", ""},
+		{"/tmp/nova/glance/glance-api.conf", "glance"},
+		{"/tmp/nova-vs-glance/glance/glance-api.conf", "glance"},
+		{"/tmp/nova/collected/etc/cinder/api-paste.ini", "cinder"},
+		{"/tmp/nova/keystone/etc/httpd/conf/httpd.conf", "keystone"},
+		{"nova_compute.conf", "nova"},
+	}
+	for _, tc := range testCases {
+		assert.Equal(t, tc.expected, godiff.CatalogService(tc.path), tc.path)
+	}
+
+	godiff.SetCatalog(true, "glance")
+	defer godiff.SetCatalog(true, "")
+	assert.Equal(t, "glance", godiff.CatalogService("tests/podman/keystone.conf"))
+}
+
+// Test case for function MatchExpected
+func TestMatchExpected(t *testing.T) {
+	transportURL := godiff.Entry{Kind: godiff.Changed, Section: "DEFAULT", Path: "transport_url", OldValue: "a", NewValue: "b"}
+	assert.NotEmpty(t, godiff.MatchExpected("keystone", transportURL))
+	assert.Empty(t, godiff.MatchExpected("unknown", transportURL))
+	assert.NotEmpty(t, godiff.MatchExpected("nova", godiff.Entry{Kind: godiff.Changed, Section: "vnc", Path: "server_listen"}))
+	assert.Empty(t, godiff.MatchExpected("keystone", godiff.Entry{Kind: godiff.Changed, Section: "vnc", Path: "server_listen"}))
+	// Missing sections are never expected
+	assert.Empty(t, godiff.MatchExpected("keystone", godiff.Entry{Kind: godiff.Removed, Section: "database"}))
+}
+
+// Test case for function ApplyCatalog
+func TestApplyCatalog(t *testing.T) {
+	origin := []byte("[DEFAULT]\ndebug=True\n[database]\nconnection=mysql://a\n")
+	dest := []byte("[DEFAULT]\ndebug=False\n[database]\nconnection=mysql://b\n")
+
+	result, err := godiff.CompareIni(origin, dest, "keystone.conf", "keystone.conf", false, []string{})
+	assert.NoError(t, err)
+	godiff.ApplyCatalog(result, "keystone")
+	assert.Equal(t, []godiff.Entry{
+		{Kind: godiff.Changed, Section: "DEFAULT", Path: "debug", OldValue: "True", NewValue: "False", OldLine: 2, NewLine: 2},
+	}, result.Entries)
+	assert.Len(t, result.Expected, 1)
+	assert.Equal(t, "connection", result.Expected[0].Path)
+	assert.NotEmpty(t, result.Expected[0].Reason)
+	assert.Equal(t, []string{"Expected difference: [database] connection (database endpoint managed by the operators)\n"}, result.ExpectedReport())
+
+	godiff.SetCatalog(false, "")
+	defer godiff.SetCatalog(true, "")
+	result, err = godiff.CompareIni(origin, dest, "keystone.conf", "keystone.conf", false, []string{})
+	assert.NoError(t, err)
+	godiff.ApplyCatalog(result, "keystone")
+	assert.Len(t, result.Entries, 2)
+	assert.Empty(t, result.Expected)
+}
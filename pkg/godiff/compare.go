@@ -64,7 +64,7 @@ func PrintReport(report []string) error {
 	return nil
 }
 
-func CompareJSONFiles(origin []byte, dest []byte) ([]string, error) {
+func CompareJSONFiles(origin []byte, dest []byte) (*Result, error) {
 	// Unmarshal the JSON files into interface{}
 	var originData, destData interface{}
 	err := json.Unmarshal(origin, &originData)
@@ -75,15 +75,18 @@ func CompareJSONFiles(origin []byte, dest []byte) ([]string, error) {
 	if err != nil {
 		return nil, fmt.Errorf("Error unmarshalling %s, error: %s", dest, err)
 	}
-	report, err := CompareJSON(originData, destData, "")
+	entries, err := CompareJSON(originData, destData, "")
 	if err != nil {
 		return nil, err
 	}
-	return report, nil
+	result := NewResult("", "", "json")
+	result.setContent(origin, dest)
+	result.Entries = append(result.Entries, entries...)
+	return result, nil
 }
 
-func CompareFiles(origin string, dest string, print bool, verbose bool, iniFilters []string) ([]string, error) {
-	var report []string
+func CompareFiles(origin string, dest string, print bool, verbose bool, iniFilters []string) (*Result, error) {
+	var result *Result
 	if print {
 		log.SetOutput(ioutil.Discard)
 	}
@@ -92,84 +95,70 @@ func CompareFiles(origin string, dest string, print bool, verbose bool, iniFilte
 	orgContent, err := ioutil.ReadFile(origin)
 	if err != nil {
 		log.Error("Failed to read file", origin, "\n")
-		return nil, errors.New("Failed to open file: '" + origin + "'. " + err.Error())
+		return nil, common.CollectionError("failed to read %s: %w", origin, err)
 	}
 	destContent, err := ioutil.ReadFile(dest)
 	if err != nil {
-		log.Error("Failed to read file", origin, "\n")
-		return nil, errors.New("Failed to open file: '" + dest + "'. " + err.Error())
+		log.Error("Failed to read file", dest, "\n")
+		return nil, common.CollectionError("failed to read %s: %w", dest, err)
 	}
 	// Detect type
-	if common.IsIni(orgContent) && common.IsIni(destContent) {
-		log.Info("Files detected as Ini files, start to process contents")
-		report, err = CompareIni(orgContent, destContent, origin, dest, verbose, iniFilters)
-		// if error occur, try to make a basic diff
-		if err != nil {
-			log.Warn(
-				"Error while processing files: ",
-				origin, " and ",
-				dest, " try to compare as a standard type...")
-			report, _ = CompareRawData(orgContent, destContent, origin, dest)
-		}
-	} else if common.IsJson(orgContent) && common.IsJson(destContent) {
-		log.Info("Files detected as JSON files, start to process contents")
-		report, err = CompareJSONFiles(orgContent, destContent)
-		if err != nil {
-			log.Warn(
-				"Error while processing files: ",
-				origin, " and ",
-				dest, " try to compare as a standard type...")
-			report, _ = CompareRawData(orgContent, destContent, origin, dest)
-		}
-	} else if common.IsYaml(orgContent) && common.IsYaml(destContent) {
-		log.Info("Files detected as YAML files, start to process contents")
-		report, err = CompareYAML(orgContent, destContent)
-		if err != nil {
-			log.Warn(
-				"Error while processing files: ",
-				origin, " and ",
-				dest, " try to compare as a standard type...")
-			report, _ = CompareRawData(orgContent, destContent, origin, dest)
-		}
-	} else {
+	comparer := SelectComparer(origin, orgContent, destContent)
+	if comparer == rawComparer {
 		log.Info("No specific type detected, process to a standard line by line comparison...")
-		// Check for differences
-		report, _ = CompareRawData(orgContent, destContent, origin, dest)
+	} else {
+		log.Info("Files detected as ", comparer.Name, " files, start to process contents")
 	}
+	opts := CompareOptions{Origin: origin, Destination: dest, Verbose: verbose, IniFilters: iniFilters}
+	result, err = comparer.Compare(orgContent, destContent, opts)
+	// if error occur, try to make a basic diff
+	if err != nil || result == nil {
+		log.Warn(
+			"Error while processing files: ",
+			origin, " and ",
+			dest, " try to compare as a standard type...")
+		result, _ = CompareRawData(orgContent, destContent, origin, dest)
+	}
+	result.Origin = origin
+	result.Destination = dest
+	Classify(result, origin)
 	filePath := origin + ".diff"
-	if len(report) != 0 {
+	if result.HasDifferences() {
+		report := result.Report()
+		if IsUnifiedOutput() {
+			report = result.UnifiedDiff()
+		}
 		err = WriteReport(report, filePath)
 		if err != nil {
 			log.Error("Error while trying to create diff file in the file system: ", filePath)
 			fmt.Println(err)
 		}
-		if print {
-			PrintReport(report)
-		}
 	}
-	return report, nil
+	if print {
+		printResult(result)
+	}
+	RecordResult(result, common.DetectType(orgContent))
+	return result, nil
 }
 
-func CompareFilesFromRemote(origin string, dest string, originRemoteCmd string, destRemoteCmd string, verbose bool) error {
+func CompareFilesFromRemote(origin string, dest string, originRemoteCmd string, destRemoteCmd string, verbose bool) (bool, error) {
 	// Get Config
 	originConfigContent, err := GetConfigFromRemote(originRemoteCmd, origin)
 	if err != nil {
-		return err
+		return false, err
 	}
 	destConfigContent, err := GetConfigFromRemote(destRemoteCmd, dest)
 	if err != nil {
-		return err
+		return false, err
 	}
 
 	// Set empty iniFilters
 	inifilters := []string{}
-	report, err := CompareIni(originConfigContent, destConfigContent, origin, dest, verbose, inifilters)
+	result, err := CompareIni(originConfigContent, destConfigContent, origin, dest, verbose, inifilters)
 	if err != nil {
-		return err
-	}
-	if report != nil {
-		PrintReport(report)
+		return false, err
 	}
-	return nil
-
+	Classify(result, origin)
+	PublishResult(result, common.DetectType(originConfigContent))
+	return result.HasDifferences(), nil
 }
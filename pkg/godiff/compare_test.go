@@ -19,9 +19,11 @@ package godiff_test
 import (
 	"io/ioutil"
 	"os"
+	"path/filepath"
 	"reflect"
 	"testing"
 
+	"github.com/openstack-k8s-operators/os-diff/pkg/common"
 	"github.com/openstack-k8s-operators/os-diff/pkg/godiff"
 	"github.com/stretchr/testify/assert"
 )
@@ -100,11 +102,11 @@ func TestCompareJSONFiles(t *testing.T) {
 	originJSON := []byte(`{"key1": "value1"}`)
 	destJSON := []byte(`{"key1": "value2"}`)
 
-	expectedReport := []string{"-value1 +value2"}
+	expectedReport := []string{"-key1: value1\n+key1: value2\n"}
 	expectedErr := error(nil)
 
-	report, err := godiff.CompareJSONFiles(originJSON, destJSON)
-	if !reflect.DeepEqual(report, expectedReport) {
+	result, err := godiff.CompareJSONFiles(originJSON, destJSON)
+	if report := result.Report(); !reflect.DeepEqual(report, expectedReport) {
 		t.Errorf("Report does not match expected value. Got: %v, Want: %v", report, expectedReport)
 	}
 
@@ -119,3 +121,18 @@ func TestCompareJSONFiles(t *testing.T) {
 		t.Errorf("Expected error when unmarshalling invalid JSON but got nil")
 	}
 }
+
+// A file which can't be read is a collection error, as for the merged files
+func TestCompareFilesExitCodes(t *testing.T) {
+	dir := t.TempDir()
+	conf := filepath.Join(dir, "nova.conf")
+	assert.NoError(t, os.WriteFile(conf, []byte("[DEFAULT]\ndebug=True\n"), 0644))
+
+	_, err := godiff.CompareFiles(conf, dir, false, false, nil)
+	assert.ErrorContains(t, err, "failed to read "+dir)
+	assert.Equal(t, common.ExitCollection, common.ExitCode(err))
+
+	_, err = godiff.CompareFiles(dir, conf, false, false, nil)
+	assert.ErrorContains(t, err, "failed to read "+dir)
+	assert.Equal(t, common.ExitCollection, common.ExitCode(err))
+}
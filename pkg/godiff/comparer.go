@@ -0,0 +1,248 @@
+/*
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * Copyright 2023 Red Hat, Inc.
+ *
+ */
+package godiff
+
+import (
+	"bytes"
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"github.com/openstack-k8s-operators/os-diff/pkg/common"
+	"gopkg.in/yaml.v3"
+)
+
+// CompareOptions are the settings of a file comparison passed to the
+// comparers.
+type CompareOptions struct {
+	Origin      string
+	Destination string
+	Verbose     bool
+	IniFilters  []string
+}
+
+// Comparer compares the files of a format.
+type Comparer struct {
+	// Name of the format, used by --format and as the format in the reports
+	Name string
+	// Glob patterns of the file names handled by the comparer: "*.json",
+	// "httpd.conf"...
+	Patterns []string
+	// Detect returns true if the content is in the format of the comparer,
+	// nil when the format can only be selected by name or with --format.
+	Detect func(data []byte) bool
+	// Compare returns the differences between the origin and destination
+	// contents, the ignore rules are applied by the caller.
+	Compare func(origin []byte, dest []byte, opts CompareOptions) (*Result, error)
+}
+
+// rawComparer compares the files line by line, it is used when no other
+// comparer matches or when a comparer fails.
+var rawComparer = &Comparer{
+	Name: "raw",
+	Compare: func(origin []byte, dest []byte, opts CompareOptions) (*Result, error) {
+		return CompareRawData(origin, dest, opts.Origin, opts.Destination)
+	},
+}
+
+// comparers are tried in order when sniffing the content of the files.
+var comparers = []*Comparer{
+	{
+		Name:     "policy",
+		Patterns: []string{"policy.yaml", "policy.json", "*policy*.yaml", "*policy*.json"},
+		Detect:   IsOsloPolicy,
+		Compare: func(origin []byte, dest []byte, opts CompareOptions) (*Result, error) {
+			return CompareOsloPolicy(origin, dest)
+		},
+	},
+	{
+		Name:     "mycnf",
+		Patterns: []string{"my.cnf", "*.cnf"},
+		Detect:   IsMyCnf,
+		Compare: func(origin []byte, dest []byte, opts CompareOptions) (*Result, error) {
+			return CompareMyCnf(origin, dest)
+		},
+	},
+	{
+		Name:     "rabbitmq",
+		Patterns: []string{"rabbitmq.conf", "rabbitmq*.conf"},
+		Detect:   IsRabbitmqConf,
+		Compare: func(origin []byte, dest []byte, opts CompareOptions) (*Result, error) {
+			return CompareRabbitmqConf(origin, dest)
+		},
+	},
+	{
+		Name:     "erlang",
+		Patterns: []string{"rabbitmq.config", "advanced.config", "*.config"},
+		Detect:   IsErlangConfig,
+		Compare: func(origin []byte, dest []byte, opts CompareOptions) (*Result, error) {
+			return CompareErlangConfig(origin, dest)
+		},
+	},
+	{
+		// sysconfig and container environment files are only detected by
+		// content, their names vary.
+		Name:     "env",
+		Patterns: []string{"*.env", ".env", "environment"},
+		Detect:   IsEnvFile,
+		Compare: func(origin []byte, dest []byte, opts CompareOptions) (*Result, error) {
+			return CompareEnv(origin, dest)
+		},
+	},
+	{
+		Name:     "json",
+		Patterns: []string{"*.json"},
+		Detect:   common.IsJson,
+		Compare: func(origin []byte, dest []byte, opts CompareOptions) (*Result, error) {
+			return CompareJSONFiles(origin, dest)
+		},
+	},
+	{
+		Name:     "ini",
+		Patterns: []string{"*.ini", "*.conf"},
+		Detect:   common.IsIni,
+		Compare: func(origin []byte, dest []byte, opts CompareOptions) (*Result, error) {
+			return CompareIni(origin, dest, opts.Origin, opts.Destination, opts.Verbose, opts.IniFilters)
+		},
+	},
+	{
+		Name:     "httpd",
+		Patterns: []string{"httpd.conf", "*.conf"},
+		Detect:   IsHttpd,
+		Compare: func(origin []byte, dest []byte, opts CompareOptions) (*Result, error) {
+			return CompareHttpd(origin, dest)
+		},
+	},
+	{
+		Name:     "yaml",
+		Patterns: []string{"*.yaml", "*.yml"},
+		Detect:   isYAMLDocument,
+		Compare: func(origin []byte, dest []byte, opts CompareOptions) (*Result, error) {
+			return CompareYAML(origin, dest)
+		},
+	},
+	rawComparer,
+}
+
+var forcedFormat string
+
+// RegisterComparer adds a comparer before the raw one, or replaces the
+// comparer with the same name.
+func RegisterComparer(comparer *Comparer) {
+	for i, c := range comparers {
+		if c.Name == comparer.Name {
+			comparers[i] = comparer
+			return
+		}
+	}
+	comparers = append(comparers[:len(comparers)-1], comparer, rawComparer)
+}
+
+// GetComparer returns the comparer of a format, or nil.
+func GetComparer(name string) *Comparer {
+	for _, c := range comparers {
+		if c.Name == name {
+			return c
+		}
+	}
+	return nil
+}
+
+// ComparerNames returns the formats of the registered comparers.
+func ComparerNames() []string {
+	var names []string
+	for _, c := range comparers {
+		names = append(names, c.Name)
+	}
+	return names
+}
+
+// SetFormat forces the format of the compared files instead of detecting
+// it, an empty format restores the detection.
+func SetFormat(format string) error {
+	if format != "" && GetComparer(format) == nil {
+		return fmt.Errorf("unknown format: %s, should be one of: %s", format, strings.Join(ComparerNames(), ", "))
+	}
+	forcedFormat = format
+	return nil
+}
+
+// SelectComparer returns the comparer for a pair of files: the one set with
+// SetFormat, else the comparer matching the file name whose detector accepts
+// both contents, else the first comparer accepting both contents, else the
+// comparer matching the file name, else the raw comparer.
+func SelectComparer(path string, origin []byte, dest []byte) *Comparer {
+	if forcedFormat != "" {
+		return GetComparer(forcedFormat)
+	}
+	detect := func(c *Comparer) bool {
+		return c.Detect != nil && c.Detect(origin) && c.Detect(dest)
+	}
+	byName := matchingComparers(path)
+	for _, c := range byName {
+		if detect(c) {
+			return c
+		}
+	}
+	for _, c := range comparers {
+		if detect(c) {
+			return c
+		}
+	}
+	if len(byName) > 0 {
+		return byName[0]
+	}
+	return rawComparer
+}
+
+// DetectFormat returns the format of a single file, as SelectComparer would
+// for a pair of identical files.
+func DetectFormat(path string, data []byte) string {
+	return SelectComparer(path, data, data).Name
+}
+
+func matchingComparers(path string) []*Comparer {
+	var matching []*Comparer
+	name := filepath.Base(path)
+	for _, c := range comparers {
+		for _, pattern := range c.Patterns {
+			if ok, _ := filepath.Match(pattern, name); ok {
+				matching = append(matching, c)
+				break
+			}
+		}
+	}
+	return matching
+}
+
+// isYAMLDocument returns true for YAML documents holding a mapping or a
+// sequence, plain text is also a valid YAML scalar.
+func isYAMLDocument(data []byte) bool {
+	if len(bytes.TrimSpace(data)) == 0 || !common.IsYaml(data) {
+		return false
+	}
+	docs, err := decodeYAML(data)
+	if err != nil || len(docs) == 0 {
+		return false
+	}
+	for _, doc := range docs {
+		if doc = resolveAlias(doc); doc.Kind != yaml.MappingNode && doc.Kind != yaml.SequenceNode {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,95 @@
+/*
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * Copyright 2023 Red Hat, Inc.
+ *
+ */
+package godiff_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/openstack-k8s-operators/os-diff/pkg/godiff"
+	"github.com/stretchr/testify/assert"
+)
+
+// Test case for function SelectComparer
+func TestSelectComparer(t *testing.T) {
+	tests := []struct {
+		name     string
+		path     string
+		origin   string
+		dest     string
+		expected string
+	}{
+		{"ini content", "nova.conf", "[DEFAULT]\ndebug=True\n", "[DEFAULT]\n", "ini"},
+		{"ini after comments", "config", "# generated\n\n[DEFAULT]\n", "[DEFAULT]\n", "ini"},
+		{"json array", "data", `[{"a": 1}]`, `[]`, "json"},
+		{"json by name", "config.json", `{"a": 1}`, `{"a": 2}`, "json"},
+		{"yaml content", "pod", "a: 1\n", "a: 2\n", "yaml"},
+		{"yaml by name despite content", "invalid.yaml", "a: [", "a: 2\n", "yaml"},
+		{"httpd conf", "10-keystone_wsgi.conf", "<VirtualHost *:5000>\n</VirtualHost>\n", "Listen 5000\n", "httpd"},
+		{"sysconfig", "libvirtd", "LIBVIRTD_ARGS=\"--listen\"\n", "# empty\nKRB5_KTNAME=/etc/krb5.keytab\n", "env"},
+		{"podman env", "nova_compute-env", `["A=1"]`, `["A=2"]`, "env"},
+		{"my.cnf", "galera.cnf", "[mysqld]\nskip-name-resolve\n", "[galera]\nwsrep_on=ON\n", "mycnf"},
+		{"rabbitmq.conf", "rabbitmq.conf", "loopback_users.guest = false\n", "listeners.tcp.default = 5672\n", "rabbitmq"},
+		{"rabbitmq.config", "rabbitmq.config", "[{rabbit, []}].\n", "[].\n", "erlang"},
+		{"plain text", "motd", "Welcome\n", "Hello\n", "raw"},
+		{"empty files", "empty", "", "", "raw"},
+		{"empty conf", "nova.conf", "", "[DEFAULT]\n", "ini"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			comparer := godiff.SelectComparer(tt.path, []byte(tt.origin), []byte(tt.dest))
+			assert.Equal(t, tt.expected, comparer.Name)
+		})
+	}
+}
+
+func TestRegisterComparer(t *testing.T) {
+	assert.Error(t, godiff.SetFormat("toml"))
+	godiff.RegisterComparer(&godiff.Comparer{
+		Name:     "toml",
+		Patterns: []string{"*.toml"},
+		Compare: func(origin []byte, dest []byte, opts godiff.CompareOptions) (*godiff.Result, error) {
+			result := godiff.NewResult(opts.Origin, opts.Destination, "toml")
+			if string(origin) != string(dest) {
+				result.Add(godiff.Entry{Kind: godiff.Changed, Path: "content"})
+			}
+			return result, nil
+		},
+	})
+	names := godiff.ComparerNames()
+	assert.Equal(t, "raw", names[len(names)-1])
+	assert.Contains(t, names, "toml")
+	assert.Equal(t, "toml", godiff.DetectFormat("a.toml", []byte("a = 1\n")))
+
+	// The format forced with SetFormat wins over the detection
+	assert.NoError(t, godiff.SetFormat("raw"))
+	defer godiff.SetFormat("")
+	assert.Equal(t, "raw", godiff.DetectFormat("a.json", []byte("{}")))
+}
+
+func TestCompareFilesEmpty(t *testing.T) {
+	dir := t.TempDir()
+	origin := filepath.Join(dir, "origin")
+	dest := filepath.Join(dir, "dest")
+	assert.NoError(t, os.WriteFile(origin, []byte{}, 0644))
+	assert.NoError(t, os.WriteFile(dest, []byte("line\n"), 0644))
+	result, err := godiff.CompareFiles(origin, dest, false, false, []string{})
+	assert.NoError(t, err)
+	assert.Equal(t, "raw", result.Format)
+	assert.True(t, result.HasDifferences())
+}
@@ -0,0 +1,220 @@
+/*
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * Copyright 2023 Red Hat, Inc.
+ *
+ */
+package godiff
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// OptionDefault is the default value of an oslo.config option, Multi is set
+// for the MultiStrOpt options.
+type OptionDefault struct {
+	Value string
+	Type  ValueType
+	Multi bool
+}
+
+// OptionDefaults holds the option defaults of a service by section and
+// option name.
+type OptionDefaults map[string]map[string]OptionDefault
+
+// oslo-config-generator --format yaml (or json) output, only the fields
+// used by os-diff.
+type generatorOutput struct {
+	Options map[string]struct {
+		Opts []struct {
+			Name    string      `yaml:"name"`
+			Type    string      `yaml:"type"`
+			Default interface{} `yaml:"default"`
+		} `yaml:"opts"`
+	} `yaml:"options"`
+}
+
+var defaultsExtensions = []string{".yaml", ".yml", ".json"}
+
+var defaultsDir string
+var serviceDefaults = map[string]OptionDefaults{}
+
+// SetDefaultsDir sets the directory holding the oslo-config-generator
+// output of the services, one <service>.yaml or <service>.json file per
+// service as named in config.yaml. An empty dir disables the defaults.
+func SetDefaultsDir(dir string) {
+	defaultsDir = dir
+	serviceDefaults = map[string]OptionDefaults{}
+}
+
+// LoadOptionDefaults reads an oslo-config-generator output in the YAML or
+// JSON format.
+func LoadOptionDefaults(file string) (OptionDefaults, error) {
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+	var output generatorOutput
+	if err := yaml.Unmarshal(data, &output); err != nil {
+		return nil, fmt.Errorf("failed to load option defaults %s: %w", file, err)
+	}
+	defaults := OptionDefaults{}
+	for section, group := range output.Options {
+		defaults[section] = map[string]OptionDefault{}
+		for _, opt := range group.Opts {
+			multi := strings.HasPrefix(opt.Type, "multi valued")
+			if opt.Default == nil && !multi {
+				// No default, the option is unset
+				continue
+			}
+			// The default of a MultiStrOpt without default is an
+			// empty list, as for default: []
+			defaults[section][optionName(opt.Name)] = OptionDefault{
+				Value: defaultString(opt.Default),
+				Type:  generatorType(opt.Type),
+				Multi: multi,
+			}
+		}
+	}
+	return defaults, nil
+}
+
+// DefaultsFile returns the oslo-config-generator file of the service in the
+// defaults directory, or an empty string.
+func DefaultsFile(service string) string {
+	if defaultsDir == "" || service == "" {
+		return ""
+	}
+	for _, ext := range defaultsExtensions {
+		file := filepath.Join(defaultsDir, service+ext)
+		if _, err := os.Stat(file); err == nil {
+			return file
+		}
+	}
+	return ""
+}
+
+// DefaultsService guesses the service of a file from its path, only the
+// services with a defaults file are considered.
+func DefaultsService(path string) string {
+	return guessService(path, func(service string) bool {
+		return DefaultsFile(service) != ""
+	})
+}
+
+func defaultsFor(service string) OptionDefaults {
+	if defaults, ok := serviceDefaults[service]; ok {
+		return defaults
+	}
+	var defaults OptionDefaults
+	if file := DefaultsFile(service); file != "" {
+		var err error
+		defaults, err = LoadOptionDefaults(file)
+		if err != nil {
+			log.Error(err)
+		}
+	}
+	serviceDefaults[service] = defaults
+	return defaults
+}
+
+// IsDefault returns true if the value is the default of the option once
+// normalized for the option type.
+func (d OptionDefaults) IsDefault(section string, key string, value string) bool {
+	opt, ok := d[section][optionName(key)]
+	if !ok {
+		return false
+	}
+	return NormalizeValue(opt.Type, value) == NormalizeValue(opt.Type, opt.Value)
+}
+
+// ApplyDefaults moves the INI options set to their default value on one side
+// and unset on the other side to the defaults entries of the result: the
+// effective configuration is the same. Only the oslo.config INI files are
+// covered, the generator output has no option of the MySQL my.cnf files.
+func ApplyDefaults(result *Result, service string) {
+	if result == nil || result.Format != "ini" {
+		return
+	}
+	defaults := defaultsFor(service)
+	if defaults == nil {
+		return
+	}
+	entries := []Entry{}
+	for _, e := range result.Entries {
+		isDefault := false
+		switch e.Kind {
+		case Removed:
+			isDefault = e.Path != "" && defaults.IsDefault(e.Section, e.Path, e.OldValue)
+		case Added:
+			isDefault = e.Path != "" && defaults.IsDefault(e.Section, e.Path, e.NewValue)
+		}
+		if isDefault {
+			e.Reason = "explicit default value vs unset"
+			log.Info("Default value: [", e.Section, "] ", e.Path, " in: ", result.Origin)
+			result.Defaults = append(result.Defaults, e)
+		} else {
+			entries = append(entries, e)
+		}
+	}
+	result.Entries = entries
+}
+
+// optionName returns the name of the option as written in the INI files.
+func optionName(name string) string {
+	return strings.ReplaceAll(name, "-", "_")
+}
+
+func generatorType(t string) ValueType {
+	switch {
+	case strings.HasPrefix(t, "boolean"):
+		return TypeBool
+	case strings.HasPrefix(t, "integer"), strings.HasPrefix(t, "port"):
+		return TypeInt
+	case strings.HasPrefix(t, "list"):
+		return TypeList
+	case strings.HasPrefix(t, "dict"):
+		return TypeDict
+	case strings.HasPrefix(t, "uri"):
+		return TypeURL
+	}
+	return TypeString
+}
+
+// defaultString formats a default as it would be written in an INI file.
+func defaultString(value interface{}) string {
+	switch v := value.(type) {
+	case nil:
+		return ""
+	case []interface{}:
+		var items []string
+		for _, item := range v {
+			items = append(items, defaultString(item))
+		}
+		return strings.Join(items, ",")
+	case map[string]interface{}:
+		var items []string
+		for k, item := range v {
+			items = append(items, k+":"+defaultString(item))
+		}
+		sort.Strings(items)
+		return strings.Join(items, ",")
+	}
+	return fmt.Sprintf("%v", value)
+}
@@ -0,0 +1,108 @@
+/*
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * Copyright 2023 Red Hat, Inc.
+ *
+ */
+package godiff_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/openstack-k8s-operators/os-diff/pkg/godiff"
+	"github.com/stretchr/testify/assert"
+)
+
+// Extract of oslo-config-generator --format yaml
+const generatorYAML = `
+generator_options:
+  namespace:
+  - keystone
+options:
+  DEFAULT:
+    opts:
+    - name: debug
+      type: boolean value
+      default: false
+    - name: max_param_size
+      type: integer value
+      default: 64
+    - name: log-dir
+      type: string value
+      default: null
+  cache:
+    opts:
+    - name: backend_argument
+      type: multi valued
+      default: []
+    - name: memcache_servers
+      type: list value
+      default:
+      - localhost:11211
+`
+
+const generatorJSON = `{"options": {"DEFAULT": {"opts": [{"name": "debug", "type": "boolean value", "default": false}]}}}`
+
+// Test case for function LoadOptionDefaults
+func TestLoadOptionDefaults(t *testing.T) {
+	dir := t.TempDir()
+	assert.NoError(t, os.WriteFile(filepath.Join(dir, "keystone.yaml"), []byte(generatorYAML), 0644))
+	assert.NoError(t, os.WriteFile(filepath.Join(dir, "nova.json"), []byte(generatorJSON), 0644))
+
+	defaults, err := godiff.LoadOptionDefaults(filepath.Join(dir, "keystone.yaml"))
+	assert.NoError(t, err)
+	assert.Equal(t, godiff.OptionDefault{Value: "false", Type: godiff.TypeBool}, defaults["DEFAULT"]["debug"])
+	assert.Equal(t, godiff.OptionDefault{Value: "localhost:11211", Type: godiff.TypeList}, defaults["cache"]["memcache_servers"])
+	// Options without default are unset, MultiStrOpts are empty lists
+	_, ok := defaults["DEFAULT"]["log_dir"]
+	assert.False(t, ok)
+	assert.Equal(t, godiff.OptionDefault{Type: godiff.TypeString, Multi: true}, defaults["cache"]["backend_argument"])
+	assert.True(t, defaults.IsDefault("DEFAULT", "debug", "False"))
+	assert.True(t, defaults.IsDefault("DEFAULT", "max_param_size", "64"))
+	assert.False(t, defaults.IsDefault("DEFAULT", "max_param_size", "128"))
+
+	defaults, err = godiff.LoadOptionDefaults(filepath.Join(dir, "nova.json"))
+	assert.NoError(t, err)
+	assert.True(t, defaults.IsDefault("DEFAULT", "debug", "false"))
+
+	godiff.SetDefaultsDir(dir)
+	defer godiff.SetDefaultsDir("")
+	assert.Equal(t, "nova", godiff.DefaultsService("/tmp/collect_tripleo_configs/nova/etc/nova/nova.conf"))
+	assert.Equal(t, "keystone", godiff.DefaultsService("tests/podman/keystone.conf"))
+	assert.Equal(t, "", godiff.DefaultsService("tests/podman/glance.conf"))
+}
+
+// Test case for function ApplyDefaults
+func TestApplyDefaults(t *testing.T) {
+	dir := t.TempDir()
+	assert.NoError(t, os.WriteFile(filepath.Join(dir, "keystone.yaml"), []byte(generatorYAML), 0644))
+	godiff.SetDefaultsDir(dir)
+	defer godiff.SetDefaultsDir("")
+
+	origin := []byte("[DEFAULT]\ndebug=False\nmax_param_size=128\n")
+	dest := []byte("[DEFAULT]\n[cache]\nmemcache_servers=localhost:11211\n")
+	result, err := godiff.CompareIni(origin, dest, "keystone.conf", "keystone.conf", false, []string{})
+	assert.NoError(t, err)
+	godiff.ApplyDefaults(result, "keystone")
+	assert.Equal(t, []godiff.Entry{
+		{Kind: godiff.Removed, Section: "DEFAULT", Path: "max_param_size", OldValue: "128", OldLine: 3},
+		{Kind: godiff.Added, Section: "cache", NewLine: 2},
+	}, result.Entries)
+	assert.Len(t, result.Defaults, 2)
+	assert.Equal(t, []string{
+		"Default value: [DEFAULT] debug=False only set in origin\n",
+		"Default value: [cache] memcache_servers=localhost:11211 only set in destination\n",
+	}, result.DefaultsReport())
+}
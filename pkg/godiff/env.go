@@ -0,0 +1,229 @@
+/*
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * Copyright 2023 Red Hat, Inc.
+ *
+ */
+package godiff
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// envVar is a variable of an environment file with its line number.
+type envVar struct {
+	key   string
+	value string
+	line  int
+}
+
+// Variable names, the dots and dashes cover the properties files
+var envKey = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_.-]*$`)
+
+var envExport = regexp.MustCompile(`^export\s+`)
+
+// CompareEnv compares shell style KEY=VALUE files: /etc/sysconfig files,
+// container environment files or the JSON list of a `podman inspect
+// --format '{{json .Config.Env}}'`. Values are compared once unquoted, the
+// last assignment of a variable wins.
+func CompareEnv(origin []byte, dest []byte) (*Result, error) {
+	vars1, err := parseEnv(origin, false)
+	if err != nil {
+		return nil, fmt.Errorf("Error parsing environment file: %s", err)
+	}
+	vars2, err := parseEnv(dest, false)
+	if err != nil {
+		return nil, fmt.Errorf("Error parsing environment file: %s", err)
+	}
+	result := NewResult("", "", "env")
+	result.setContent(origin, dest)
+	last1 := envLast(vars1)
+	last2 := envLast(vars2)
+	for i, v := range vars1 {
+		if last1[v.key] != i {
+			// Overridden by a later assignment
+			continue
+		}
+		j, ok := last2[v.key]
+		if !ok {
+			result.Add(Entry{Kind: Removed, Path: v.key, OldValue: v.value, OldLine: v.line})
+		} else if v2 := vars2[j]; v.value != v2.value {
+			result.Add(Entry{Kind: Changed, Path: v.key, OldValue: v.value, NewValue: v2.value, OldLine: v.line, NewLine: v2.line})
+		}
+	}
+	for j, v := range vars2 {
+		if _, ok := last1[v.key]; !ok && last2[v.key] == j {
+			result.Add(Entry{Kind: Added, Path: v.key, NewValue: v.value, NewLine: v.line})
+		}
+	}
+	return result, nil
+}
+
+// IsEnvFile returns true if every line of the content is a comment or a
+// KEY=VALUE assignment, or for a JSON list of KEY=VALUE strings. Spaces
+// around the "=", accepted by systemd, are not detected: "key = value"
+// files are usually in other formats.
+func IsEnvFile(data []byte) bool {
+	vars, err := parseEnv(data, true)
+	return err == nil && len(vars) > 0
+}
+
+// ParseEnv returns the variables of an environment file.
+func ParseEnv(data []byte) (map[string]string, error) {
+	vars, err := parseEnv(data, false)
+	if err != nil {
+		return nil, err
+	}
+	env := map[string]string{}
+	for _, v := range vars {
+		env[v.key] = v.value
+	}
+	return env, nil
+}
+
+// envLast returns the index of the last assignment of each variable.
+func envLast(vars []envVar) map[string]int {
+	last := map[string]int{}
+	for i, v := range vars {
+		last[v.key] = i
+	}
+	return last
+}
+
+// parseEnv reads the variables of an environment file, the strict mode
+// rejects the spaces before the "=".
+func parseEnv(data []byte, strict bool) ([]envVar, error) {
+	if trimmed := bytes.TrimSpace(data); len(trimmed) > 0 && trimmed[0] == '[' {
+		return parseEnvList(data)
+	}
+	var vars []envVar
+	lines := splitLines(data)
+	for i := 0; i < len(lines); i++ {
+		lineNum := i + 1
+		line := strings.TrimSpace(lines[i])
+		if line == "" || line[0] == '#' || line[0] == ';' {
+			continue
+		}
+		line = envExport.ReplaceAllString(line, "")
+		key, rest, ok := strings.Cut(line, "=")
+		if !strict {
+			key = strings.TrimSpace(key)
+		}
+		if !ok || !envKey.MatchString(key) {
+			return nil, fmt.Errorf("line %d: not a KEY=VALUE assignment: %s", lineNum, line)
+		}
+		value, next, err := parseEnvValue(lines, i, strings.TrimLeft(rest, " \t"))
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %s", lineNum, err)
+		}
+		vars = append(vars, envVar{key: key, value: value, line: lineNum})
+		i = next
+	}
+	return vars, nil
+}
+
+// parseEnvValue unquotes a value as the shell does: single quotes are kept
+// as they are, double quotes handle the \" \\ \$ and \` escapes and a
+// trailing backslash continues the value on the next line. Quoted values
+// can span several lines. It returns the index of the last line read.
+func parseEnvValue(lines []string, i int, rest string) (string, int, error) {
+	var b strings.Builder
+	var quote byte
+	// Length of the value without the trailing unquoted spaces
+	end := 0
+	for {
+		continued := false
+	scan:
+		for j := 0; j < len(rest); j++ {
+			c := rest[j]
+			switch {
+			case quote == '\'':
+				if c == '\'' {
+					quote = 0
+				} else {
+					b.WriteByte(c)
+				}
+			case quote == '"':
+				if c == '"' {
+					quote = 0
+				} else if c == '\\' && j+1 < len(rest) && strings.IndexByte("\"\\$`", rest[j+1]) >= 0 {
+					j++
+					b.WriteByte(rest[j])
+				} else {
+					b.WriteByte(c)
+				}
+			case c == '\'' || c == '"':
+				quote = c
+			case c == '\\' && j+1 < len(rest):
+				j++
+				b.WriteByte(rest[j])
+			case c == '\\':
+				continued = true
+			case c == '#' && j > 0 && (rest[j-1] == ' ' || rest[j-1] == '\t'):
+				// Comment at the end of the line
+				break scan
+			default:
+				b.WriteByte(c)
+				if c == ' ' || c == '\t' {
+					continue
+				}
+			}
+			end = b.Len()
+		}
+		switch {
+		case quote != 0:
+			b.WriteByte('\n')
+		case !continued:
+			return b.String()[:end], i, nil
+		}
+		if i++; i >= len(lines) {
+			if quote != 0 {
+				return "", i, fmt.Errorf("unterminated %c quote", quote)
+			}
+			return b.String()[:end], i, nil
+		}
+		rest = lines[i]
+	}
+}
+
+// parseEnvList reads a JSON list of KEY=VALUE strings, the line numbers are
+// the ones of the strings.
+func parseEnvList(data []byte) ([]envVar, error) {
+	var items []string
+	if err := json.Unmarshal(data, &items); err != nil {
+		return nil, err
+	}
+	lines := splitLines(data)
+	var vars []envVar
+	for n, item := range items {
+		key, value, ok := strings.Cut(item, "=")
+		if !ok || !envKey.MatchString(key) {
+			return nil, fmt.Errorf("item %d: not a KEY=VALUE string: %s", n, item)
+		}
+		line := 0
+		if len(lines) > 1 {
+			for i, l := range lines {
+				if strings.Contains(l, "\""+key+"=") {
+					line = i + 1
+					break
+				}
+			}
+		}
+		vars = append(vars, envVar{key: key, value: value, line: line})
+	}
+	return vars, nil
+}
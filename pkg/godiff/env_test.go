@@ -0,0 +1,124 @@
+/*
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * Copyright 2023 Red Hat, Inc.
+ *
+ */
+package godiff_test
+
+import (
+	"testing"
+
+	"github.com/openstack-k8s-operators/os-diff/pkg/godiff"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseEnv(t *testing.T) {
+	tests := []struct {
+		name     string
+		data     string
+		expected map[string]string
+	}{
+		{
+			name:     "Plain and exported",
+			data:     "# comment\nA=1\nexport B=two\n\n",
+			expected: map[string]string{"A": "1", "B": "two"},
+		},
+		{
+			name:     "Quotes",
+			data:     "A=\"x y\"\nB='$HOME \"q\"'\nC=\"a\\\"b\\\\c\"\nD=pre\"mid\"post\n",
+			expected: map[string]string{"A": "x y", "B": "$HOME \"q\"", "C": "a\"b\\c", "D": "premidpost"},
+		},
+		{
+			name:     "Comments and spaces",
+			data:     "A=value # comment\nB=a#b\nC=\"a # b\"\nD = spaced\nE=\n",
+			expected: map[string]string{"A": "value", "B": "a#b", "C": "a # b", "D": "spaced", "E": ""},
+		},
+		{
+			name:     "Multiple lines",
+			data:     "A=\"line1\nline2\"\nB=one \\\ntwo\nC=3\n",
+			expected: map[string]string{"A": "line1\nline2", "B": "one two", "C": "3"},
+		},
+		{
+			name:     "Last assignment wins",
+			data:     "A=1\nA=2\n",
+			expected: map[string]string{"A": "2"},
+		},
+		{
+			name:     "Podman env list",
+			data:     `["PATH=/usr/bin:/bin", "KOLLA_CONFIG_STRATEGY=COPY_ALWAYS", "EMPTY="]`,
+			expected: map[string]string{"PATH": "/usr/bin:/bin", "KOLLA_CONFIG_STRATEGY": "COPY_ALWAYS", "EMPTY": ""},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			env, err := godiff.ParseEnv([]byte(test.data))
+			assert.NoError(t, err)
+			assert.Equal(t, test.expected, env)
+		})
+	}
+
+	_, err := godiff.ParseEnv([]byte("A=\"unterminated\n"))
+	assert.Error(t, err)
+}
+
+func TestIsEnvFile(t *testing.T) {
+	tests := []struct {
+		name     string
+		data     string
+		expected bool
+	}{
+		{"Sysconfig", "# Options\nOPTIONS=\"-u\"\nexport LANG=C\n", true},
+		{"Env list", `["A=1", "B=2"]`, true},
+		{"Spaces around =", "key = value\n", false},
+		{"Ini", "[DEFAULT]\ndebug=true\n", false},
+		{"Command", "echo hello\n", false},
+		{"Json list", `["a", "b"]`, false},
+		{"Comments only", "# A=1\n", false},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			assert.Equal(t, test.expected, godiff.IsEnvFile([]byte(test.data)))
+		})
+	}
+}
+
+func TestCompareEnv(t *testing.T) {
+	origin := []byte(`# nova compute environment
+KOLLA_CONFIG_STRATEGY=COPY_ALWAYS
+TRIPLEO_CONFIG_HASH=abc
+export LANG="en_US.UTF-8"
+DB_PASSWORD=foo
+`)
+	dest := []byte(`KOLLA_CONFIG_STRATEGY='COPY_ALWAYS'
+LANG=C.UTF-8
+KOLLA_SERVICE_NAME=nova-compute
+DB_PASSWORD=bar
+`)
+	result, err := godiff.CompareEnv(origin, dest)
+	assert.NoError(t, err)
+	assert.Equal(t, "env", result.Format)
+	assert.Equal(t, []godiff.Entry{
+		{Kind: godiff.Removed, Path: "TRIPLEO_CONFIG_HASH", OldValue: "abc", OldLine: 3},
+		{Kind: godiff.Changed, Path: "LANG", OldValue: "en_US.UTF-8", NewValue: "C.UTF-8", OldLine: 4, NewLine: 2},
+		{Kind: godiff.Changed, Path: "DB_PASSWORD", OldValue: "foo", NewValue: "bar", OldLine: 5, NewLine: 4},
+		{Kind: godiff.Added, Path: "KOLLA_SERVICE_NAME", NewValue: "nova-compute", NewLine: 3},
+	}, result.Entries)
+
+	report := result.Report()
+	assert.Contains(t, report, "-TRIPLEO_CONFIG_HASH=abc\n")
+	assert.Contains(t, report, "-LANG=en_US.UTF-8\n+LANG=C.UTF-8\n")
+	for _, line := range report {
+		assert.NotContains(t, line, "foo")
+	}
+}
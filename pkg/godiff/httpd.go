@@ -0,0 +1,406 @@
+/*
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * Copyright 2023 Red Hat, Inc.
+ *
+ */
+package godiff
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Directives whose order matters to Apache, the values of the other
+// directives repeated in a block are compared as unordered sets.
+var httpdOrderedDirectives = map[string]bool{
+	"alias":                true,
+	"aliasmatch":           true,
+	"scriptalias":          true,
+	"scriptaliasmatch":     true,
+	"wsgiscriptalias":      true,
+	"wsgiscriptaliasmatch": true,
+	"redirect":             true,
+	"redirectmatch":        true,
+	"rewritecond":          true,
+	"rewriterule":          true,
+	"proxypass":            true,
+	"proxypassmatch":       true,
+	"proxypassreverse":     true,
+	"header":               true,
+	"requestheader":        true,
+	"setenvif":             true,
+	"setenvifnocase":       true,
+}
+
+// Directives found at the top of the httpd files, used to detect them.
+var httpdTopDirectives = map[string]bool{
+	"serverroot":        true,
+	"servername":        true,
+	"servertokens":      true,
+	"serversignature":   true,
+	"listen":            true,
+	"loadmodule":        true,
+	"include":           true,
+	"includeoptional":   true,
+	"user":              true,
+	"group":             true,
+	"documentroot":      true,
+	"traceenable":       true,
+	"timeout":           true,
+	"keepalive":         true,
+	"pidfile":           true,
+	"loglevel":          true,
+	"errorlog":          true,
+	"logformat":         true,
+	"wsgipythonhome":    true,
+	"wsgisocketprefix":  true,
+	"adddefaultcharset": true,
+	"enablesendfile":    true,
+	"hostnamelookups":   true,
+}
+
+// Sections found at the top of the httpd files, used to detect them.
+var httpdTopSections = map[string]bool{
+	"virtualhost":    true,
+	"directory":      true,
+	"directorymatch": true,
+	"location":       true,
+	"locationmatch":  true,
+	"files":          true,
+	"filesmatch":     true,
+	"ifmodule":       true,
+	"ifdefine":       true,
+	"ifversion":      true,
+	"proxy":          true,
+	"macro":          true,
+}
+
+type httpdDirective struct {
+	name string
+	args string
+	line int
+}
+
+// httpdBlock is a section like <VirtualHost *:5000>, the root block holds
+// the directives of the whole file.
+type httpdBlock struct {
+	name       string
+	args       string
+	line       int
+	endLine    int
+	directives []httpdDirective
+	blocks     []*httpdBlock
+}
+
+// CompareHttpd compares Apache httpd configuration files. Differences are
+// reported per directive at the path of their block, e.g.
+// VirtualHost[*:5000].Directory[/var/www/cgi-bin/keystone].Require. Blocks
+// are matched by type and arguments whatever their order, a block whose
+// arguments changed is matched with the only other block of its type.
+func CompareHttpd(origin []byte, dest []byte) (*Result, error) {
+	root1, err := parseHttpd(origin)
+	if err != nil {
+		return nil, fmt.Errorf("Error parsing httpd configuration: %s", err)
+	}
+	root2, err := parseHttpd(dest)
+	if err != nil {
+		return nil, fmt.Errorf("Error parsing httpd configuration: %s", err)
+	}
+	result := NewResult("", "", "httpd")
+	result.setContent(origin, dest)
+	result.compareHttpdBlocks("", root1, root2)
+	return result, nil
+}
+
+// IsHttpd returns true if the first directive of the content is a section
+// or a directive usually found at the top of the httpd files.
+func IsHttpd(data []byte) bool {
+	for _, line := range strings.Split(string(data), "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		if strings.HasPrefix(line, "<") && strings.HasSuffix(line, ">") {
+			name, _ := splitDirective(line[1 : len(line)-1])
+			return httpdTopSections[strings.ToLower(name)]
+		}
+		name, _ := splitDirective(line)
+		return httpdTopDirectives[strings.ToLower(name)]
+	}
+	return false
+}
+
+func parseHttpd(data []byte) (*httpdBlock, error) {
+	root := &httpdBlock{}
+	stack := []*httpdBlock{root}
+	lines := splitLines(data)
+	for i := 0; i < len(lines); i++ {
+		lineNum := i + 1
+		line := strings.TrimSpace(lines[i])
+		for strings.HasSuffix(line, "\\") && i+1 < len(lines) {
+			i++
+			line = strings.TrimSuffix(line, "\\") + " " + strings.TrimSpace(lines[i])
+		}
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		top := stack[len(stack)-1]
+		switch {
+		case strings.HasPrefix(line, "</"):
+			name := strings.TrimSpace(strings.TrimSuffix(line[2:], ">"))
+			if len(stack) == 1 || !strings.EqualFold(name, top.name) {
+				return nil, fmt.Errorf("line %d: unexpected </%s>", lineNum, name)
+			}
+			top.endLine = i + 1
+			stack = stack[:len(stack)-1]
+		case strings.HasPrefix(line, "<"):
+			if !strings.HasSuffix(line, ">") {
+				return nil, fmt.Errorf("line %d: unterminated section %s", lineNum, line)
+			}
+			name, args := splitDirective(line[1 : len(line)-1])
+			block := &httpdBlock{name: name, args: args, line: lineNum}
+			top.blocks = append(top.blocks, block)
+			stack = append(stack, block)
+		default:
+			name, args := splitDirective(line)
+			top.directives = append(top.directives, httpdDirective{name: name, args: args, line: lineNum})
+		}
+	}
+	if len(stack) > 1 {
+		top := stack[len(stack)-1]
+		return nil, fmt.Errorf("line %d: section <%s> not closed", top.line, top.name)
+	}
+	return root, nil
+}
+
+// splitDirective returns the name of a directive and its arguments, quotes
+// and repeated spaces removed as Apache does.
+func splitDirective(line string) (string, string) {
+	tokens := httpdTokens(line)
+	if len(tokens) == 0 {
+		return "", ""
+	}
+	args := tokens[1:]
+	for i, arg := range args {
+		if arg == "" || strings.ContainsAny(arg, " \t") {
+			args[i] = `"` + arg + `"`
+		}
+	}
+	return tokens[0], strings.Join(args, " ")
+}
+
+func httpdTokens(line string) []string {
+	var tokens []string
+	var token strings.Builder
+	inToken := false
+	var quote rune
+	for _, c := range line {
+		switch {
+		case quote != 0:
+			if c == quote {
+				quote = 0
+			} else {
+				token.WriteRune(c)
+			}
+		case c == '"' || c == '\'':
+			quote = c
+			inToken = true
+		case c == ' ' || c == '\t':
+			if inToken {
+				tokens = append(tokens, token.String())
+				token.Reset()
+				inToken = false
+			}
+		default:
+			token.WriteRune(c)
+			inToken = true
+		}
+	}
+	if inToken {
+		tokens = append(tokens, token.String())
+	}
+	return tokens
+}
+
+func (b *httpdBlock) key() string {
+	return strings.ToLower(b.name) + " " + b.args
+}
+
+func (b *httpdBlock) header() string {
+	if b.args == "" {
+		return "<" + b.name + ">"
+	}
+	return "<" + b.name + " " + b.args + ">"
+}
+
+func httpdBlockPath(path string, b *httpdBlock) string {
+	if b.args == "" {
+		return joinPath(path, b.name)
+	}
+	return joinPath(path, b.name+"["+b.args+"]")
+}
+
+func (r *Result) compareHttpdBlocks(path string, b1 *httpdBlock, b2 *httpdBlock) {
+	r.compareHttpdDirectives(path, b1.directives, b2.directives)
+
+	blocks1, blocks2 := mergeHttpdBlocks(b1.blocks), mergeHttpdBlocks(b2.blocks)
+	byKey := map[string]*httpdBlock{}
+	for _, b := range blocks2 {
+		byKey[b.key()] = b
+	}
+	matched := map[*httpdBlock]bool{}
+	var unmatched1 []*httpdBlock
+	for _, b := range blocks1 {
+		if other, ok := byKey[b.key()]; ok {
+			matched[other] = true
+			r.compareHttpdBlocks(httpdBlockPath(path, b), b, other)
+		} else {
+			unmatched1 = append(unmatched1, b)
+		}
+	}
+	var unmatched2 []*httpdBlock
+	for _, b := range blocks2 {
+		if !matched[b] {
+			unmatched2 = append(unmatched2, b)
+		}
+	}
+	for _, b := range unmatched1 {
+		// A block whose arguments changed, like the address of a VirtualHost,
+		// is matched with the only remaining block of its type
+		other := onlyHttpdBlock(unmatched2, b.name)
+		if other == nil || onlyHttpdBlock(unmatched1, b.name) == nil {
+			r.Add(Entry{Kind: Removed, Path: httpdBlockPath(path, b), OldValue: b.header(), OldLine: b.line})
+			r.setSpan(b.line, 0, b.endLine, 0)
+			continue
+		}
+		matched[other] = true
+		blockPath := httpdBlockPath(path, b)
+		r.Add(Entry{Kind: Changed, Path: blockPath, OldValue: b.header(), NewValue: other.header(), OldLine: b.line, NewLine: other.line})
+		r.compareHttpdBlocks(blockPath, b, other)
+	}
+	for _, b := range unmatched2 {
+		if !matched[b] {
+			r.Add(Entry{Kind: Added, Path: httpdBlockPath(path, b), NewValue: b.header(), NewLine: b.line})
+			r.setSpan(0, b.line, 0, b.endLine)
+		}
+	}
+}
+
+// onlyHttpdBlock returns the block of the type if it is the only one.
+func onlyHttpdBlock(blocks []*httpdBlock, name string) *httpdBlock {
+	var found *httpdBlock
+	for _, b := range blocks {
+		if strings.EqualFold(b.name, name) {
+			if found != nil {
+				return nil
+			}
+			found = b
+		}
+	}
+	return found
+}
+
+// mergeHttpdBlocks merges the blocks with the same type and arguments, as
+// Apache does.
+func mergeHttpdBlocks(blocks []*httpdBlock) []*httpdBlock {
+	var merged []*httpdBlock
+	byKey := map[string]*httpdBlock{}
+	for _, b := range blocks {
+		if first, ok := byKey[b.key()]; ok {
+			first.directives = append(first.directives, b.directives...)
+			first.blocks = append(first.blocks, b.blocks...)
+			continue
+		}
+		copied := *b
+		byKey[b.key()] = &copied
+		merged = append(merged, &copied)
+	}
+	return merged
+}
+
+func (r *Result) compareHttpdDirectives(path string, directives1 []httpdDirective, directives2 []httpdDirective) {
+	var names []string
+	values1 := map[string][]httpdDirective{}
+	values2 := map[string][]httpdDirective{}
+	for _, d := range directives1 {
+		name := strings.ToLower(d.name)
+		if _, ok := values1[name]; !ok {
+			names = append(names, name)
+		}
+		values1[name] = append(values1[name], d)
+	}
+	for _, d := range directives2 {
+		name := strings.ToLower(d.name)
+		if _, ok := values1[name]; !ok {
+			if _, ok := values2[name]; !ok {
+				names = append(names, name)
+			}
+		}
+		values2[name] = append(values2[name], d)
+	}
+	for _, name := range names {
+		d1, d2 := values1[name], values2[name]
+		displayName := name
+		if len(d1) > 0 {
+			displayName = d1[0].name
+		} else if len(d2) > 0 {
+			displayName = d2[0].name
+		}
+		keyPath := joinPath(path, displayName)
+		if httpdOrderedDirectives[name] || (len(d1) == 1 && len(d2) == 1) {
+			r.compareHttpdOrdered(keyPath, d1, d2)
+		} else {
+			r.compareHttpdUnordered(keyPath, d1, d2)
+		}
+	}
+}
+
+func (r *Result) compareHttpdOrdered(path string, d1 []httpdDirective, d2 []httpdDirective) {
+	for i := 0; i < len(d1) || i < len(d2); i++ {
+		valuePath := path
+		if len(d1) > 1 || len(d2) > 1 {
+			valuePath = fmt.Sprintf("%s[%d]", path, i)
+		}
+		switch {
+		case i >= len(d2):
+			r.Add(Entry{Kind: Removed, Path: valuePath, OldValue: d1[i].args, OldLine: d1[i].line})
+		case i >= len(d1):
+			r.Add(Entry{Kind: Added, Path: valuePath, NewValue: d2[i].args, NewLine: d2[i].line})
+		case d1[i].args != d2[i].args:
+			r.Add(Entry{Kind: Changed, Path: valuePath, OldValue: d1[i].args, NewValue: d2[i].args, OldLine: d1[i].line, NewLine: d2[i].line})
+		}
+	}
+}
+
+func (r *Result) compareHttpdUnordered(path string, d1 []httpdDirective, d2 []httpdDirective) {
+	matched := make([]bool, len(d2))
+	for _, d := range d1 {
+		found := false
+		for j, other := range d2 {
+			if !matched[j] && d.args == other.args {
+				matched[j] = true
+				found = true
+				break
+			}
+		}
+		if !found {
+			r.Add(Entry{Kind: Removed, Path: path, OldValue: d.args, OldLine: d.line})
+		}
+	}
+	for j, other := range d2 {
+		if !matched[j] {
+			r.Add(Entry{Kind: Added, Path: path, NewValue: other.args, NewLine: other.line})
+		}
+	}
+}
@@ -0,0 +1,90 @@
+/*
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * Copyright 2023 Red Hat, Inc.
+ *
+ */
+package godiff_test
+
+import (
+	"testing"
+
+	"github.com/openstack-k8s-operators/os-diff/pkg/godiff"
+	"github.com/stretchr/testify/assert"
+)
+
+const keystoneWsgiOrigin = `# Managed by Puppet
+<VirtualHost 172.17.0.10:5000>
+  ServerName standalone.internalapi.localdomain
+
+  <Directory "/var/www/cgi-bin/keystone">
+    Options -Indexes
+    Require all granted
+  </Directory>
+
+  ErrorLog "/var/log/httpd/keystone_wsgi_error.log"
+  SetEnvIf X-Forwarded-Proto https HTTPS=1
+  WSGIDaemonProcess keystone display-name=keystone group=keystone processes=4 threads=1 user=keystone
+  WSGIProcessGroup keystone
+  WSGIScriptAlias / "/var/www/cgi-bin/keystone/main"
+  Header set X-Frame-Options DENY
+  Header set X-Content-Type-Options nosniff
+</VirtualHost>
+`
+
+const keystoneWsgiDest = `<VirtualHost *:5000>
+  WSGIScriptAlias /    /var/www/cgi-bin/keystone/main
+  WSGIProcessGroup keystone
+  WSGIDaemonProcess keystone display-name=keystone group=keystone processes=2 threads=1 user=keystone
+  ServerName keystone-internal.openstack.svc
+
+  <Directory /var/www/cgi-bin/keystone>
+    Require all granted
+  </Directory>
+
+  ErrorLog /dev/stdout
+  Header set X-Content-Type-Options nosniff
+  Header set X-Frame-Options DENY
+  <IfModule mod_ssl.c>
+    SSLEngine on
+  </IfModule>
+</VirtualHost>
+`
+
+// Test case for function CompareHttpd
+func TestCompareHttpd(t *testing.T) {
+	assert.True(t, godiff.IsHttpd([]byte(keystoneWsgiOrigin)))
+	assert.True(t, godiff.IsHttpd([]byte("ServerRoot /etc/httpd\n")))
+	assert.False(t, godiff.IsHttpd([]byte("[DEFAULT]\n")))
+	assert.False(t, godiff.IsHttpd([]byte("<?xml version=\"1.0\"?>\n<config/>\n")))
+
+	result, err := godiff.CompareHttpd([]byte(keystoneWsgiOrigin), []byte(keystoneWsgiDest))
+	assert.NoError(t, err)
+	vhost := "VirtualHost[172.17.0.10:5000]"
+	assert.Equal(t, []godiff.Entry{
+		{Kind: godiff.Changed, Path: vhost, OldValue: "<VirtualHost 172.17.0.10:5000>", NewValue: "<VirtualHost *:5000>", OldLine: 2, NewLine: 1},
+		{Kind: godiff.Changed, Path: vhost + ".ServerName", OldValue: "standalone.internalapi.localdomain", NewValue: "keystone-internal.openstack.svc", OldLine: 3, NewLine: 5},
+		{Kind: godiff.Changed, Path: vhost + ".ErrorLog", OldValue: "/var/log/httpd/keystone_wsgi_error.log", NewValue: "/dev/stdout", OldLine: 10, NewLine: 11},
+		{Kind: godiff.Removed, Path: vhost + ".SetEnvIf", OldValue: "X-Forwarded-Proto https HTTPS=1", OldLine: 11},
+		{Kind: godiff.Changed, Path: vhost + ".WSGIDaemonProcess", OldValue: "keystone display-name=keystone group=keystone processes=4 threads=1 user=keystone", NewValue: "keystone display-name=keystone group=keystone processes=2 threads=1 user=keystone", OldLine: 12, NewLine: 4},
+		{Kind: godiff.Changed, Path: vhost + ".Header[0]", OldValue: "set X-Frame-Options DENY", NewValue: "set X-Content-Type-Options nosniff", OldLine: 15, NewLine: 12},
+		{Kind: godiff.Changed, Path: vhost + ".Header[1]", OldValue: "set X-Content-Type-Options nosniff", NewValue: "set X-Frame-Options DENY", OldLine: 16, NewLine: 13},
+		{Kind: godiff.Removed, Path: vhost + ".Directory[/var/www/cgi-bin/keystone].Options", OldValue: "-Indexes", OldLine: 6},
+		{Kind: godiff.Added, Path: vhost + ".IfModule[mod_ssl.c]", NewValue: "<IfModule mod_ssl.c>", NewLine: 14},
+	}, result.Entries)
+
+	_, err = godiff.CompareHttpd([]byte("<VirtualHost *:80>\n"), []byte(""))
+	assert.Error(t, err)
+	_, err = godiff.CompareHttpd([]byte("</Directory>\n"), []byte(""))
+	assert.Error(t, err)
+}
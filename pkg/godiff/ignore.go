@@ -0,0 +1,234 @@
+/*
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * Copyright 2023 Red Hat, Inc.
+ *
+ */
+package godiff
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// IniRule ignores INI options, an empty field matches everything.
+// Section and Key are glob patterns ('*' and '?'), Value is a regex
+// matched against the old or new value.
+type IniRule struct {
+	Section string `yaml:"section"`
+	Key     string `yaml:"key"`
+	Value   string `yaml:"value"`
+	valueRe *regexp.Regexp
+}
+
+// PathRule ignores YAML and JSON values by path, '*' matches a single path
+// element and '**' any number of them.
+type PathRule struct {
+	Path    string `yaml:"path"`
+	Value   string `yaml:"value"`
+	valueRe *regexp.Regexp
+}
+
+// IgnoreRules describes the known and expected differences which should not
+// be reported.
+type IgnoreRules struct {
+	// Files are glob patterns matched against the path relative to the
+	// compared directories, a matching directory is skipped entirely.
+	Files []string   `yaml:"files"`
+	Ini   []IniRule  `yaml:"ini"`
+	Paths []PathRule `yaml:"paths"`
+	// Values are regexes ignoring any difference whose value matches.
+	Values []string `yaml:"values"`
+	values []*regexp.Regexp
+}
+
+var ignoreRules *IgnoreRules
+
+func LoadIgnoreRules(rulesFile string) (*IgnoreRules, error) {
+	data, err := os.ReadFile(rulesFile)
+	if err != nil {
+		return nil, err
+	}
+	var rules IgnoreRules
+	if err := yaml.Unmarshal(data, &rules); err != nil {
+		return nil, fmt.Errorf("failed to load ignore rules %s: %w", rulesFile, err)
+	}
+	for i := range rules.Ini {
+		if rules.Ini[i].valueRe, err = compileRule(rules.Ini[i].Value); err != nil {
+			return nil, err
+		}
+	}
+	for i := range rules.Paths {
+		if rules.Paths[i].valueRe, err = compileRule(rules.Paths[i].Value); err != nil {
+			return nil, err
+		}
+	}
+	for _, value := range rules.Values {
+		re, err := compileRule(value)
+		if err != nil {
+			return nil, err
+		}
+		rules.values = append(rules.values, re)
+	}
+	return &rules, nil
+}
+
+func compileRule(expr string) (*regexp.Regexp, error) {
+	if expr == "" {
+		return nil, nil
+	}
+	re, err := regexp.Compile(expr)
+	if err != nil {
+		return nil, fmt.Errorf("invalid ignore rule value: %s: %w", expr, err)
+	}
+	return re, nil
+}
+
+// SetIgnoreRules sets the rules applied by every comparer, nil disables them.
+func SetIgnoreRules(rules *IgnoreRules) {
+	ignoreRules = rules
+}
+
+// MatchFile returns true if the relative path or one of its parent
+// directories matches a files rule.
+func (r *IgnoreRules) MatchFile(relPath string) bool {
+	if r == nil {
+		return false
+	}
+	parts := strings.Split(filepath.ToSlash(filepath.Clean(relPath)), "/")
+	for _, pattern := range r.Files {
+		pattern = strings.Trim(filepath.ToSlash(pattern), "/")
+		depth := len(strings.Split(pattern, "/"))
+		// The pattern can match anywhere in the path
+		for i := 0; i+depth <= len(parts); i++ {
+			if globMatch(pattern, strings.Join(parts[i:i+depth], "/")) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// MatchEntry returns true if the difference is covered by a rule.
+func (r *IgnoreRules) MatchEntry(format string, e Entry) bool {
+	if r == nil {
+		return false
+	}
+	for _, re := range r.values {
+		if valueMatch(re, e) {
+			return true
+		}
+	}
+	switch format {
+	case "ini", "mycnf":
+		for _, rule := range r.Ini {
+			if rule.Section != "" && !globMatch(rule.Section, e.Section) {
+				continue
+			}
+			if rule.Key != "" && (e.Path == "" || !globMatch(rule.Key, e.Path)) {
+				continue
+			}
+			if rule.valueRe != nil && !valueMatch(rule.valueRe, e) {
+				continue
+			}
+			return true
+		}
+	case "json", "yaml", "httpd", "policy", "env", "rabbitmq", "erlang":
+		for _, rule := range r.Paths {
+			if !pathMatch(rule.Path, e.Path) {
+				continue
+			}
+			if rule.valueRe != nil && !valueMatch(rule.valueRe, e) {
+				continue
+			}
+			return true
+		}
+	}
+	return false
+}
+
+// Apply moves the differences matching a rule from the result entries to
+// the ignored entries.
+func (r *IgnoreRules) Apply(result *Result) {
+	if r == nil || result == nil {
+		return
+	}
+	entries := []Entry{}
+	for _, e := range result.Entries {
+		if r.MatchEntry(result.Format, e) {
+			log.Info("Ignored difference: ", e.Section, " ", e.Path, " in: ", result.Origin)
+			result.Ignored = append(result.Ignored, e)
+		} else {
+			entries = append(entries, e)
+		}
+	}
+	result.Entries = entries
+}
+
+func valueMatch(re *regexp.Regexp, e Entry) bool {
+	return (e.OldValue != "" && re.MatchString(e.OldValue)) || (e.NewValue != "" && re.MatchString(e.NewValue))
+}
+
+func pathMatch(pattern string, path string) bool {
+	return matchSegments(splitPath(pattern), splitPath(path))
+}
+
+func matchSegments(pattern []string, path []string) bool {
+	if len(pattern) == 0 {
+		return len(path) == 0
+	}
+	if pattern[0] == "**" {
+		for i := 0; i <= len(path); i++ {
+			if matchSegments(pattern[1:], path[i:]) {
+				return true
+			}
+		}
+		return false
+	}
+	if len(path) == 0 || !globMatch(pattern[0], path[0]) {
+		return false
+	}
+	return matchSegments(pattern[1:], path[1:])
+}
+
+// globMatch matches s against a pattern where '*' matches any sequence of
+// characters and '?' any single character, no other character is special.
+func globMatch(pattern string, s string) bool {
+	for len(pattern) > 0 {
+		switch pattern[0] {
+		case '*':
+			for i := 0; i <= len(s); i++ {
+				if globMatch(pattern[1:], s[i:]) {
+					return true
+				}
+			}
+			return false
+		case '?':
+			if len(s) == 0 {
+				return false
+			}
+		default:
+			if len(s) == 0 || pattern[0] != s[0] {
+				return false
+			}
+		}
+		pattern = pattern[1:]
+		s = s[1:]
+	}
+	return len(s) == 0
+}
@@ -0,0 +1,137 @@
+/*
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * Copyright 2023 Red Hat, Inc.
+ *
+ */
+package godiff_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/openstack-k8s-operators/os-diff/pkg/godiff"
+	"github.com/stretchr/testify/assert"
+)
+
+const rulesContent = `
+files:
+  - etc/keystone/fernet-keys
+  - "*.pyc"
+ini:
+  - section: DEFAULT
+    key: transport_url
+  - section: "*"
+    key: memcache_*
+  - section: oslo_messaging_notifications
+paths:
+  - path: "**.uid"
+  - path: spec.replicas
+    value: "^[0-9]+$"
+values:
+  - "\\.localdomain"
+`
+
+func loadRules(t *testing.T) *godiff.IgnoreRules {
+	rulesFile := filepath.Join(t.TempDir(), "ignore.yaml")
+	assert.NoError(t, os.WriteFile(rulesFile, []byte(rulesContent), 0644))
+	rules, err := godiff.LoadIgnoreRules(rulesFile)
+	assert.NoError(t, err)
+	return rules
+}
+
+// Test case for function LoadIgnoreRules
+func TestLoadIgnoreRulesInvalidRegex(t *testing.T) {
+	rulesFile := filepath.Join(t.TempDir(), "ignore.yaml")
+	assert.NoError(t, os.WriteFile(rulesFile, []byte("values:\n  - \"(\"\n"), 0644))
+	_, err := godiff.LoadIgnoreRules(rulesFile)
+	assert.Error(t, err)
+}
+
+// Test case for function MatchFile
+func TestMatchFile(t *testing.T) {
+	rules := loadRules(t)
+	testCases := []struct {
+		path     string
+		expected bool
+	}{
+		{"keystone/etc/keystone/fernet-keys", true},
+		{"keystone/etc/keystone/fernet-keys/0", true},
+		{"keystone/etc/keystone/keystone.conf", false},
+		{"nova/lib/module.pyc", true},
+		{"etc/fernet-keys", false},
+	}
+	for _, tc := range testCases {
+		assert.Equal(t, tc.expected, rules.MatchFile(tc.path), tc.path)
+	}
+	var noRules *godiff.IgnoreRules
+	assert.False(t, noRules.MatchFile("etc/keystone/fernet-keys"))
+}
+
+// Test case for function MatchEntry
+func TestMatchEntry(t *testing.T) {
+	rules := loadRules(t)
+	testCases := []struct {
+		name     string
+		format   string
+		entry    godiff.Entry
+		expected bool
+	}{
+		{"ini key", "ini", godiff.Entry{Kind: godiff.Changed, Section: "DEFAULT", Path: "transport_url", OldValue: "a", NewValue: "b"}, true},
+		{"ini key other section", "ini", godiff.Entry{Kind: godiff.Changed, Section: "nova", Path: "transport_url", OldValue: "a", NewValue: "b"}, false},
+		{"ini key glob", "ini", godiff.Entry{Kind: godiff.Removed, Section: "cache", Path: "memcache_servers", OldValue: "a"}, true},
+		{"ini whole section", "ini", godiff.Entry{Kind: godiff.Removed, Section: "oslo_messaging_notifications"}, true},
+		{"ini section rule on key", "ini", godiff.Entry{Kind: godiff.Added, Section: "oslo_messaging_notifications", Path: "driver", NewValue: "noop"}, true},
+		{"ini key rule on section", "ini", godiff.Entry{Kind: godiff.Removed, Section: "DEFAULT"}, false},
+		{"path glob", "yaml", godiff.Entry{Kind: godiff.Changed, Path: "metadata.owner.uid", OldValue: "1", NewValue: "2"}, true},
+		{"path glob with item key", "json", godiff.Entry{Kind: godiff.Changed, Path: "config_files[dest=/etc/nova.conf].owner.uid", OldValue: "1", NewValue: "2"}, true},
+		{"path value", "json", godiff.Entry{Kind: godiff.Changed, Path: "spec.replicas", OldValue: "1", NewValue: "3"}, true},
+		{"path value mismatch", "json", godiff.Entry{Kind: godiff.Added, Path: "spec.replicas", NewValue: "many"}, false},
+		{"path rule on ini", "ini", godiff.Entry{Kind: godiff.Changed, Section: "DEFAULT", Path: "uid", OldValue: "1", NewValue: "2"}, false},
+		{"value", "raw", godiff.Entry{Kind: godiff.Added, NewValue: "controller-0.localdomain", NewLine: 2}, true},
+		{"no match", "raw", godiff.Entry{Kind: godiff.Added, NewValue: "controller-0", NewLine: 2}, false},
+	}
+	for _, tc := range testCases {
+		assert.Equal(t, tc.expected, rules.MatchEntry(tc.format, tc.entry), tc.name)
+	}
+}
+
+// Test case for function Apply
+func TestApplyIgnoreRules(t *testing.T) {
+	godiff.SetIgnoreRules(loadRules(t))
+	defer godiff.SetIgnoreRules(nil)
+
+	origin := []byte("[DEFAULT]\ndebug=True\ntransport_url=rabbit://a\n")
+	dest := []byte("[DEFAULT]\ndebug=False\ntransport_url=rabbit://b\n")
+	result, err := godiff.CompareIni(origin, dest, "a.conf", "b.conf", false, []string{})
+	assert.NoError(t, err)
+	// The comparers don't apply the rules
+	assert.Len(t, result.Entries, 2)
+	godiff.Classify(result, "a.conf")
+	assert.Equal(t, []godiff.Entry{
+		{Kind: godiff.Changed, Section: "DEFAULT", Path: "debug", OldValue: "True", NewValue: "False", OldLine: 2, NewLine: 2},
+	}, result.Entries)
+	assert.Equal(t, []godiff.Entry{
+		{Kind: godiff.Changed, Section: "DEFAULT", Path: "transport_url", OldValue: "rabbit://a", NewValue: "rabbit://b", OldLine: 3, NewLine: 3},
+	}, result.Ignored)
+
+	// CompareFiles applies them whatever the format
+	dir := t.TempDir()
+	assert.NoError(t, os.WriteFile(filepath.Join(dir, "a.json"), []byte(`{"metadata": {"uid": "1"}}`), 0644))
+	assert.NoError(t, os.WriteFile(filepath.Join(dir, "b.json"), []byte(`{"metadata": {"uid": "2"}}`), 0644))
+	result, err = godiff.CompareFiles(filepath.Join(dir, "a.json"), filepath.Join(dir, "b.json"), false, false, []string{})
+	assert.NoError(t, err)
+	assert.False(t, result.HasDifferences())
+	assert.Len(t, result.Ignored, 1)
+}
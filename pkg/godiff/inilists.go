@@ -0,0 +1,134 @@
+/*
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * Copyright 2023 Red Hat, Inc.
+ *
+ */
+package godiff
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// IniList is an INI option holding a list whose items are compared one by
+// one. Section and Key are glob patterns.
+type IniList struct {
+	Section string
+	Key     string
+	// Separator of the items, spaces when empty
+	Separator string
+	// Ordered lists report the inserted and removed items at their index,
+	// the others are compared as unordered sets.
+	Ordered bool
+}
+
+// iniLists covers the paste-deploy pipelines (api-paste.ini) and the
+// python logging configuration (logging.conf).
+var iniLists = []IniList{
+	{Section: "pipeline:*", Key: "pipeline", Ordered: true},
+	// pipeline_factory composites, like the nova and cinder APIs
+	{Section: "composite:*", Key: "keystone*", Ordered: true},
+	{Section: "composite:*", Key: "noauth*", Ordered: true},
+	{Section: "loggers", Key: "keys", Separator: ","},
+	{Section: "handlers", Key: "keys", Separator: ","},
+	{Section: "formatters", Key: "keys", Separator: ","},
+	{Section: "logger_*", Key: "handlers", Separator: ","},
+}
+
+// paste-deploy references: egg:<distribution>#<entry point>
+var pasteEggURI = regexp.MustCompile(`^egg:([^#]+)(#.*)?$`)
+
+// Separators of the distribution names, equivalent for pip (PEP 503)
+var distSeparators = regexp.MustCompile(`[-_.]+`)
+
+func findIniList(section string, key string) *IniList {
+	for i, list := range iniLists {
+		if globMatch(list.Section, section) && globMatch(list.Key, key) {
+			return &iniLists[i]
+		}
+	}
+	return nil
+}
+
+func (l *IniList) items(value string) []string {
+	if l.Separator == "" {
+		return strings.Fields(value)
+	}
+	var items []string
+	for _, item := range strings.Split(value, l.Separator) {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+// compareIniList adds an entry per item inserted or removed from a list
+// option, at its index for the ordered lists.
+func compareIniList(result *Result, list *IniList, section string, key string, value1 string, value2 string, line1 int, line2 int) {
+	items1, items2 := list.items(value1), list.items(value2)
+	if !list.Ordered {
+		matched := make([]bool, len(items2))
+		for _, item := range items1 {
+			found := false
+			for j, item2 := range items2 {
+				if !matched[j] && item == item2 {
+					matched[j] = true
+					found = true
+					break
+				}
+			}
+			if !found {
+				result.Add(Entry{Kind: Removed, Section: section, Path: key, OldValue: item, OldLine: line1})
+			}
+		}
+		for j, item2 := range items2 {
+			if !matched[j] {
+				result.Add(Entry{Kind: Added, Section: section, Path: key, NewValue: item2, NewLine: line2})
+			}
+		}
+		return
+	}
+	for _, edit := range diffLines(items1, items2) {
+		switch edit.op {
+		case opDelete:
+			path := fmt.Sprintf("%s[%d]", key, edit.oldLine-1)
+			result.Add(Entry{Kind: Removed, Section: section, Path: path, OldValue: items1[edit.oldLine-1], OldLine: line1})
+		case opInsert:
+			path := fmt.Sprintf("%s[%d]", key, edit.newLine-1)
+			result.Add(Entry{Kind: Added, Section: section, Path: path, NewValue: items2[edit.newLine-1], NewLine: line2})
+		}
+	}
+}
+
+// pasteUseEqual compares the "use" references of the paste-deploy sections,
+// distribution names are not case sensitive and '-', '_' and '.' are the
+// same.
+func pasteUseEqual(section string, key string, v1 string, v2 string) bool {
+	if key != "use" || !strings.Contains(section, ":") {
+		return false
+	}
+	return normalizePasteURI(v1) == normalizePasteURI(v2)
+}
+
+func normalizePasteURI(uri string) string {
+	uri = strings.TrimSpace(uri)
+	m := pasteEggURI.FindStringSubmatch(uri)
+	if m == nil {
+		return uri
+	}
+	dist := strings.ToLower(distSeparators.ReplaceAllString(m[1], "-"))
+	return "egg:" + dist + m[2]
+}
@@ -0,0 +1,86 @@
+/*
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * Copyright 2023 Red Hat, Inc.
+ *
+ */
+package godiff_test
+
+import (
+	"testing"
+
+	"github.com/openstack-k8s-operators/os-diff/pkg/godiff"
+	"github.com/stretchr/testify/assert"
+)
+
+// Test case for the paste-deploy pipelines
+func TestCompareIniPastePipeline(t *testing.T) {
+	origin := []byte(`[pipeline:public_api]
+pipeline = cors sizelimit http_proxy_to_wsgi osprofiler url_normalize request_id admin_token_auth build_auth_context token_auth json_body ec2_extension public_service
+
+[filter:cors]
+use = egg:oslo.middleware#cors
+
+[app:public_service]
+use = egg:Keystone#public_service
+`)
+	dest := []byte(`[pipeline:public_api]
+pipeline = cors  sizelimit http_proxy_to_wsgi url_normalize request_id healthcheck build_auth_context token_auth json_body ec2_extension public_service
+
+[filter:cors]
+use = egg:oslo-middleware#cors
+
+[app:public_service]
+use = egg:keystone#public_service
+`)
+	result, err := godiff.CompareIni(origin, dest, "api-paste.ini", "api-paste.ini", false, []string{})
+	assert.NoError(t, err)
+	assert.Equal(t, []godiff.Entry{
+		{Kind: godiff.Removed, Section: "pipeline:public_api", Path: "pipeline[3]", OldValue: "osprofiler", OldLine: 2},
+		{Kind: godiff.Removed, Section: "pipeline:public_api", Path: "pipeline[6]", OldValue: "admin_token_auth", OldLine: 2},
+		{Kind: godiff.Added, Section: "pipeline:public_api", Path: "pipeline[5]", NewValue: "healthcheck", NewLine: 2},
+	}, result.Entries)
+}
+
+// Test case for the python logging configuration
+func TestCompareIniLoggingLists(t *testing.T) {
+	origin := []byte(`[loggers]
+keys=root,keystone
+
+[handlers]
+keys=production,file,devel
+
+[logger_keystone]
+level=INFO
+handlers=file, production
+`)
+	dest := []byte(`[loggers]
+keys=keystone, root
+
+[handlers]
+keys=devel,production,stdout
+
+[logger_keystone]
+level=INFO
+handlers=stdout
+`)
+	result, err := godiff.CompareIni(origin, dest, "logging.conf", "logging.conf", false, []string{})
+	assert.NoError(t, err)
+	assert.Equal(t, []godiff.Entry{
+		{Kind: godiff.Removed, Section: "handlers", Path: "keys", OldValue: "file", OldLine: 5},
+		{Kind: godiff.Added, Section: "handlers", Path: "keys", NewValue: "stdout", NewLine: 5},
+		{Kind: godiff.Removed, Section: "logger_keystone", Path: "handlers", OldValue: "file", OldLine: 9},
+		{Kind: godiff.Removed, Section: "logger_keystone", Path: "handlers", OldValue: "production", OldLine: 9},
+		{Kind: godiff.Added, Section: "logger_keystone", Path: "handlers", NewValue: "stdout", NewLine: 9},
+	}, result.Entries)
+}
@@ -0,0 +1,269 @@
+/*
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * Copyright 2023 Red Hat, Inc.
+ *
+ */
+package godiff
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+)
+
+var unorderedArrays = false
+
+// Fields identifying the objects of an array, like the dest of the kolla
+// config_files or the path of the permissions.
+var arrayKeys []string
+
+// SetJSONArrays selects how JSON arrays are compared: by position (default),
+// as unordered multisets, and for arrays of objects, by the first of the
+// keys set with a unique value in every object.
+func SetJSONArrays(unordered bool, keys []string) {
+	unorderedArrays = unordered
+	arrayKeys = nil
+	for _, key := range keys {
+		if key = strings.TrimSpace(key); key != "" {
+			arrayKeys = append(arrayKeys, key)
+		}
+	}
+}
+
+// CompareJSON compares decoded JSON values recursively and returns the
+// differences at their full path, e.g. config_files[0].dest. A value whose
+// type changed is reported as changed.
+func CompareJSON(orgData, destData interface{}, path string) ([]Entry, error) {
+	var diff []Entry
+	orgMap, orgIsMap := orgData.(map[string]interface{})
+	destMap, destIsMap := destData.(map[string]interface{})
+	orgArray, orgIsArray := orgData.([]interface{})
+	destArray, destIsArray := destData.([]interface{})
+	switch {
+	case orgIsMap && destIsMap:
+		for _, key := range sortedKeys(orgMap) {
+			if value2, ok := destMap[key]; ok {
+				entries, _ := CompareJSON(orgMap[key], value2, joinPath(path, key))
+				diff = append(diff, entries...)
+			} else {
+				diff = append(diff, Entry{Kind: Removed, Path: joinPath(path, key), OldValue: jsonString(orgMap[key])})
+			}
+		}
+		for _, key := range sortedKeys(destMap) {
+			if _, ok := orgMap[key]; !ok {
+				diff = append(diff, Entry{Kind: Added, Path: joinPath(path, key), NewValue: jsonString(destMap[key])})
+			}
+		}
+	case orgIsArray && destIsArray:
+		diff = append(diff, compareJSONArrays(orgArray, destArray, path)...)
+	case !reflect.DeepEqual(orgData, destData):
+		oldValue, newValue := jsonString(orgData), jsonString(destData)
+		if reflect.TypeOf(orgData) != reflect.TypeOf(destData) {
+			// Make the type change visible, like 1 and "1"
+			oldValue = jsonType(orgData) + " " + oldValue
+			newValue = jsonType(destData) + " " + newValue
+		}
+		diff = append(diff, Entry{Kind: Changed, Path: path, OldValue: oldValue, NewValue: newValue})
+	}
+	return diff, nil
+}
+
+func compareJSONArrays(orgArray []interface{}, destArray []interface{}, path string) []Entry {
+	var diff []Entry
+	if key := arrayKey(orgArray, destArray); key != "" {
+		destItems := map[string]interface{}{}
+		for _, item := range destArray {
+			destItems[objectKey(item, key)] = item
+		}
+		orgItems := map[string]bool{}
+		for _, item := range orgArray {
+			id := objectKey(item, key)
+			orgItems[id] = true
+			itemPath := fmt.Sprintf("%s[%s=%s]", path, key, id)
+			if item2, ok := destItems[id]; ok {
+				entries, _ := CompareJSON(item, item2, itemPath)
+				diff = append(diff, entries...)
+			} else {
+				diff = append(diff, Entry{Kind: Removed, Path: itemPath, OldValue: jsonString(item)})
+			}
+		}
+		for _, item := range destArray {
+			if id := objectKey(item, key); !orgItems[id] {
+				diff = append(diff, Entry{Kind: Added, Path: fmt.Sprintf("%s[%s=%s]", path, key, id), NewValue: jsonString(item)})
+			}
+		}
+		return diff
+	}
+	if unorderedArrays {
+		// Equal items match whatever their position, the others are reported
+		// at their index
+		matched := make([]bool, len(destArray))
+		orgMatched := make([]bool, len(orgArray))
+		for i, item := range orgArray {
+			for j, item2 := range destArray {
+				if !matched[j] && reflect.DeepEqual(item, item2) {
+					matched[j] = true
+					orgMatched[i] = true
+					break
+				}
+			}
+		}
+		for i, item := range orgArray {
+			if orgMatched[i] {
+				continue
+			}
+			// An item whose type changed, like 2 and "2", is reported as
+			// changed to show both types
+			found := false
+			for j, item2 := range destArray {
+				if !matched[j] && jsonString(item) == jsonString(item2) {
+					entries, _ := CompareJSON(item, item2, fmt.Sprintf("%s[%d]", path, i))
+					diff = append(diff, entries...)
+					matched[j] = true
+					found = true
+					break
+				}
+			}
+			if !found {
+				diff = append(diff, Entry{Kind: Removed, Path: fmt.Sprintf("%s[%d]", path, i), OldValue: jsonString(item)})
+			}
+		}
+		for j, item2 := range destArray {
+			if !matched[j] {
+				diff = append(diff, Entry{Kind: Added, Path: fmt.Sprintf("%s[%d]", path, j), NewValue: jsonString(item2)})
+			}
+		}
+		return diff
+	}
+	for i := 0; i < len(orgArray) || i < len(destArray); i++ {
+		itemPath := fmt.Sprintf("%s[%d]", path, i)
+		switch {
+		case i >= len(destArray):
+			diff = append(diff, Entry{Kind: Removed, Path: itemPath, OldValue: jsonString(orgArray[i])})
+		case i >= len(orgArray):
+			diff = append(diff, Entry{Kind: Added, Path: itemPath, NewValue: jsonString(destArray[i])})
+		default:
+			entries, _ := CompareJSON(orgArray[i], destArray[i], itemPath)
+			diff = append(diff, entries...)
+		}
+	}
+	return diff
+}
+
+// arrayKey returns the first of the array keys set with a unique value in
+// all the objects of both arrays, or an empty string.
+func arrayKey(orgArray []interface{}, destArray []interface{}) string {
+	for _, key := range arrayKeys {
+		if uniqueObjectKeys(orgArray, key) && uniqueObjectKeys(destArray, key) {
+			return key
+		}
+	}
+	return ""
+}
+
+func uniqueObjectKeys(array []interface{}, key string) bool {
+	seen := map[string]bool{}
+	for _, item := range array {
+		id := objectKey(item, key)
+		if id == "" || seen[id] {
+			return false
+		}
+		seen[id] = true
+	}
+	return true
+}
+
+// objectKey returns the scalar value of the key of a JSON object, or an
+// empty string.
+func objectKey(item interface{}, key string) string {
+	object, ok := item.(map[string]interface{})
+	if !ok {
+		return ""
+	}
+	switch value := object[key].(type) {
+	case string:
+		return value
+	case float64, bool:
+		return fmt.Sprintf("%v", value)
+	}
+	return ""
+}
+
+func sortedKeys(m map[string]interface{}) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// jsonString returns a scalar as is and objects or arrays as compact JSON.
+func jsonString(value interface{}) string {
+	switch value.(type) {
+	case map[string]interface{}, []interface{}:
+		if out, err := json.Marshal(value); err == nil {
+			return string(out)
+		}
+	}
+	return fmt.Sprintf("%v", value)
+}
+
+func jsonType(value interface{}) string {
+	switch value.(type) {
+	case nil:
+		return "null"
+	case map[string]interface{}:
+		return "object"
+	case []interface{}:
+		return "array"
+	case string:
+		return "string"
+	case bool:
+		return "boolean"
+	case float64, int, int64:
+		return "number"
+	}
+	return fmt.Sprintf("%T", value)
+}
+
+func joinPath(path string, key string) string {
+	if path == "" {
+		return key
+	}
+	return path + "." + key
+}
+
+// splitPath splits a YAML or JSON path in elements, the dots of the item
+// keys like config_files[dest=/etc/nova/nova.conf] are kept.
+func splitPath(path string) []string {
+	var parts []string
+	depth, start := 0, 0
+	for i, c := range path {
+		switch c {
+		case '[':
+			depth++
+		case ']':
+			depth--
+		case '.':
+			if depth == 0 {
+				parts = append(parts, path[start:i])
+				start = i + 1
+			}
+		}
+	}
+	return append(parts, path[start:])
+}
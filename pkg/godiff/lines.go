@@ -0,0 +1,239 @@
+/*
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * Copyright 2023 Red Hat, Inc.
+ *
+ */
+package godiff
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Number of unchanged lines around the changes in the hunks
+const hunkContext = 3
+
+type editOp int
+
+const (
+	opEqual editOp = iota
+	opDelete
+	opInsert
+)
+
+// lineEdit is a step of the edit script turning the old lines into the new
+// ones, line numbers start at 1 and are 0 when not relevant.
+type lineEdit struct {
+	op      editOp
+	oldLine int
+	newLine int
+}
+
+// hunk is a unified diff hunk, lines are prefixed with ' ', '-' or '+'.
+type hunk struct {
+	oldStart int
+	oldCount int
+	newStart int
+	newCount int
+	lines    []string
+}
+
+func (h hunk) header() string {
+	return fmt.Sprintf("@@ -%s +%s @@", hunkRange(h.oldStart, h.oldCount), hunkRange(h.newStart, h.newCount))
+}
+
+func hunkRange(start int, count int) string {
+	if count == 1 {
+		return fmt.Sprintf("%d", start)
+	}
+	return fmt.Sprintf("%d,%d", start, count)
+}
+
+// Marker written by diff after a last line without line break.
+const noNewline = "\\ No newline at end of file"
+
+// noFinalNewline returns true if the content doesn't end with a line break.
+func noFinalNewline(data []byte) bool {
+	return len(data) > 0 && data[len(data)-1] != '\n'
+}
+
+// splitLines splits a file content in lines, without the empty line after
+// the last line break.
+func splitLines(data []byte) []string {
+	if len(data) == 0 {
+		return []string{}
+	}
+	return strings.Split(strings.TrimSuffix(string(data), "\n"), "\n")
+}
+
+// diffLines returns the shortest edit script between a and b, computed with
+// the Myers diff algorithm.
+func diffLines(a []string, b []string) []lineEdit {
+	n, m := len(a), len(b)
+	max := n + m
+	offset := max + 1
+	v := make([]int, 2*max+3)
+	// trace[d] holds the furthest x reached on the diagonals -d..d before
+	// the step d
+	var trace [][]int
+	for d := 0; d <= max; d++ {
+		trace = append(trace, append([]int{}, v[offset-d:offset+d+1]...))
+		for k := -d; k <= d; k += 2 {
+			var x int
+			if k == -d || (k != d && v[offset+k-1] < v[offset+k+1]) {
+				x = v[offset+k+1]
+			} else {
+				x = v[offset+k-1] + 1
+			}
+			y := x - k
+			for x < n && y < m && a[x] == b[y] {
+				x++
+				y++
+			}
+			v[offset+k] = x
+			if x >= n && y >= m {
+				return backtrackEdits(trace, n, m)
+			}
+		}
+	}
+	return nil
+}
+
+func backtrackEdits(trace [][]int, n int, m int) []lineEdit {
+	var edits []lineEdit
+	x, y := n, m
+	for d := len(trace) - 1; d >= 0; d-- {
+		v := trace[d]
+		// v[i] is the diagonal i-d
+		at := func(k int) int { return v[k+d] }
+		k := x - y
+		var prevK int
+		if k == -d || (k != d && at(k-1) < at(k+1)) {
+			prevK = k + 1
+		} else {
+			prevK = k - 1
+		}
+		prevX := 0
+		if d > 0 {
+			prevX = at(prevK)
+		}
+		prevY := prevX - prevK
+		for x > prevX && y > prevY && x > 0 && y > 0 {
+			edits = append(edits, lineEdit{op: opEqual, oldLine: x, newLine: y})
+			x--
+			y--
+		}
+		if d > 0 {
+			if x == prevX {
+				edits = append(edits, lineEdit{op: opInsert, newLine: y})
+			} else {
+				edits = append(edits, lineEdit{op: opDelete, oldLine: x})
+			}
+		}
+		x, y = prevX, prevY
+	}
+	for i, j := 0, len(edits)-1; i < j; i, j = i+1, j-1 {
+		edits[i], edits[j] = edits[j], edits[i]
+	}
+	return edits
+}
+
+// buildHunks groups the changes of the edit script in hunks with context
+// lines. Only the changes for which anchor returns true start a hunk, other
+// changes are only shown when they are close to an anchored one. The new
+// line numbers are computed from the shown changes only, so the hunks can
+// be applied with patch. aNoEOL and bNoEOL are set when the last line of a
+// or b has no line break, the line is then followed by the noNewline marker.
+func buildHunks(edits []lineEdit, a []string, b []string, aNoEOL bool, bNoEOL bool, context int, anchor func(lineEdit) bool) []hunk {
+	// Ranges of edits to show, merged when they overlap
+	var ranges [][2]int
+	for i, e := range edits {
+		if e.op == opEqual || !anchor(e) {
+			continue
+		}
+		start, end := i-context, i+context
+		if start < 0 {
+			start = 0
+		}
+		if end > len(edits)-1 {
+			end = len(edits) - 1
+		}
+		if len(ranges) > 0 && start <= ranges[len(ranges)-1][1]+1 {
+			ranges[len(ranges)-1][1] = end
+		} else {
+			ranges = append(ranges, [2]int{start, end})
+		}
+	}
+	// Old lines consumed before each edit
+	oldPos := make([]int, len(edits))
+	pos := 0
+	for i, e := range edits {
+		oldPos[i] = pos
+		if e.op != opInsert {
+			pos++
+		}
+	}
+	var hunks []hunk
+	delta := 0
+	for _, r := range ranges {
+		h := hunk{oldStart: oldPos[r[0]]}
+		for _, e := range edits[r[0] : r[1]+1] {
+			oldNoEOL := aNoEOL && e.oldLine == len(a)
+			newNoEOL := bNoEOL && e.newLine == len(b)
+			switch {
+			case e.op == opEqual && oldNoEOL == newNoEOL:
+				h.lines = append(h.lines, " "+a[e.oldLine-1])
+				if oldNoEOL {
+					h.lines = append(h.lines, noNewline)
+				}
+				h.oldCount++
+				h.newCount++
+			case e.op == opEqual:
+				// Same line, only one side has the line break
+				h.lines = append(h.lines, "-"+a[e.oldLine-1])
+				if oldNoEOL {
+					h.lines = append(h.lines, noNewline)
+				}
+				h.lines = append(h.lines, "+"+b[e.newLine-1])
+				if newNoEOL {
+					h.lines = append(h.lines, noNewline)
+				}
+				h.oldCount++
+				h.newCount++
+			case e.op == opDelete:
+				h.lines = append(h.lines, "-"+a[e.oldLine-1])
+				if oldNoEOL {
+					h.lines = append(h.lines, noNewline)
+				}
+				h.oldCount++
+			case e.op == opInsert:
+				h.lines = append(h.lines, "+"+b[e.newLine-1])
+				if newNoEOL {
+					h.lines = append(h.lines, noNewline)
+				}
+				h.newCount++
+			}
+		}
+		h.newStart = h.oldStart + delta
+		if h.oldCount > 0 {
+			h.oldStart++
+		}
+		if h.newCount > 0 {
+			h.newStart++
+		}
+		delta += h.newCount - h.oldCount
+		hunks = append(hunks, h)
+	}
+	return hunks
+}
@@ -0,0 +1,150 @@
+/*
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * Copyright 2023 Red Hat, Inc.
+ *
+ */
+package godiff
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/go-ini/ini"
+	"github.com/openstack-k8s-operators/os-diff/pkg/common"
+)
+
+// ExpandConfigPaths turns a comma separated list of files and directories
+// into the ordered list of files oslo.config would read: the files in the
+// given order, and for a directory (--config-dir, like glance.conf.d) its
+// *.conf files sorted by name.
+func ExpandConfigPaths(paths string) ([]string, error) {
+	var files []string
+	for _, path := range strings.Split(paths, ",") {
+		path = strings.TrimSpace(path)
+		if path == "" {
+			continue
+		}
+		info, err := os.Stat(path)
+		if err != nil {
+			return nil, common.UsageError("%w", err)
+		}
+		if !info.IsDir() {
+			files = append(files, path)
+			continue
+		}
+		dirFiles, err := filepath.Glob(filepath.Join(path, "*.conf"))
+		if err != nil {
+			return nil, err
+		}
+		sort.Strings(dirFiles)
+		files = append(files, dirFiles...)
+	}
+	if len(files) == 0 {
+		return nil, common.UsageError("no configuration file found in: %s", paths)
+	}
+	return files, nil
+}
+
+// MergeIniFiles merges INI files with the oslo.config precedence, the types
+// of the options are taken from the oslo-config-generator output of the
+// service: the values of a MultiStrOpt are added to the ones of the previous
+// files, the last value of any other option wins, even within a file. The
+// options of unknown type get the values of the last file setting them.
+func MergeIniFiles(files []string, types OptionDefaults) ([]byte, error) {
+	merged := ini.Empty(iniLoadOptions)
+	for _, file := range files {
+		data, err := os.ReadFile(file)
+		if err != nil {
+			return nil, common.CollectionError("failed to read %s: %w", file, err)
+		}
+		cfg, err := ini.LoadSources(iniLoadOptions, data)
+		if err != nil {
+			return nil, common.UsageError("Error while loading file %s: %s", file, err)
+		}
+		for _, sec := range cfg.Sections() {
+			mergedSec, err := merged.NewSection(sec.Name())
+			if err != nil {
+				return nil, err
+			}
+			for _, key := range sec.Keys() {
+				values := iniKeyValues(key)
+				opt, known := types[sec.Name()][optionName(key.Name())]
+				if known && !opt.Multi {
+					values = values[len(values)-1:]
+				}
+				mergedKey, err := mergedSec.GetKey(key.Name())
+				if err != nil || !opt.Multi {
+					mergedSec.DeleteKey(key.Name())
+					if mergedKey, err = mergedSec.NewKey(key.Name(), values[0]); err != nil {
+						return nil, err
+					}
+					values = values[1:]
+				}
+				for _, value := range values {
+					if err := mergedKey.AddShadow(value); err != nil {
+						return nil, err
+					}
+				}
+			}
+		}
+	}
+	var buf bytes.Buffer
+	if _, err := merged.WriteTo(&buf); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// CompareMergedFiles compares the effective configuration of both sides,
+// each side is a comma separated list of INI files and/or directories merged
+// with MergeIniFiles. Line numbers are not reported as they don't match a
+// single file.
+func CompareMergedFiles(origin string, dest string, verbose bool, iniFilters []string) (*Result, error) {
+	orgFiles, err := ExpandConfigPaths(origin)
+	if err != nil {
+		return nil, err
+	}
+	destFiles, err := ExpandConfigPaths(dest)
+	if err != nil {
+		return nil, err
+	}
+	// Both sides are merged with the types of the service of the origin,
+	// as for the classification of the differences
+	types := defaultsFor(DefaultsService(orgFiles[0]))
+	orgContent, err := MergeIniFiles(orgFiles, types)
+	if err != nil {
+		return nil, err
+	}
+	destContent, err := MergeIniFiles(destFiles, types)
+	if err != nil {
+		return nil, err
+	}
+	result, err := CompareIni(orgContent, destContent, origin, dest, verbose, iniFilters)
+	if err != nil {
+		return nil, err
+	}
+	log.Info("Merged ", strings.Join(orgFiles, ", "), " and ", strings.Join(destFiles, ", "))
+	Classify(result, orgFiles[0])
+	for _, entries := range [][]Entry{result.Entries, result.Ignored, result.Expected, result.Defaults} {
+		for i := range entries {
+			entries[i].OldLine = 0
+			entries[i].NewLine = 0
+		}
+	}
+	PublishResult(result, "ini")
+	return result, nil
+}
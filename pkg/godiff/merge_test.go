@@ -0,0 +1,133 @@
+/*
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * Copyright 2023 Red Hat, Inc.
+ *
+ */
+package godiff_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/openstack-k8s-operators/os-diff/pkg/common"
+	"github.com/openstack-k8s-operators/os-diff/pkg/godiff"
+	"github.com/stretchr/testify/assert"
+)
+
+func writeConfDir(t *testing.T) string {
+	dir := t.TempDir()
+	confDir := filepath.Join(dir, "glance.conf.d")
+	assert.NoError(t, os.Mkdir(confDir, 0755))
+	files := map[string]string{
+		"00-config.conf": "[DEFAULT]\ndebug=True\nworkers=4\n[glance_store]\nstores=file\nstores=http\n",
+		"01-config.conf": "[DEFAULT]\nworkers=8\n[glance_store]\nstores=rbd\nstores=swift\n",
+		"README":         "not a configuration file\n",
+	}
+	for name, content := range files {
+		assert.NoError(t, os.WriteFile(filepath.Join(confDir, name), []byte(content), 0644))
+	}
+	assert.NoError(t, os.WriteFile(filepath.Join(dir, "glance-api.conf"), []byte("[DEFAULT]\ndebug=True\nworkers=8\n[glance_store]\nstores=file\nstores=http\nstores=rbd\nstores=swift\n"), 0644))
+	// stores is typed as a MultiStrOpt to merge its values
+	defaultsDir := filepath.Join(dir, "defaults")
+	assert.NoError(t, os.Mkdir(defaultsDir, 0755))
+	assert.NoError(t, os.WriteFile(filepath.Join(defaultsDir, "glance.yaml"), []byte("options:\n  glance_store:\n    opts:\n    - name: stores\n      type: multi valued\n      default: null\n"), 0644))
+	godiff.SetDefaultsDir(defaultsDir)
+	t.Cleanup(func() { godiff.SetDefaultsDir("") })
+	return dir
+}
+
+// Test case for function ExpandConfigPaths
+func TestExpandConfigPaths(t *testing.T) {
+	dir := writeConfDir(t)
+	files, err := godiff.ExpandConfigPaths(filepath.Join(dir, "glance-api.conf") + "," + filepath.Join(dir, "glance.conf.d"))
+	assert.NoError(t, err)
+	assert.Equal(t, []string{
+		filepath.Join(dir, "glance-api.conf"),
+		filepath.Join(dir, "glance.conf.d", "00-config.conf"),
+		filepath.Join(dir, "glance.conf.d", "01-config.conf"),
+	}, files)
+
+	_, err = godiff.ExpandConfigPaths(filepath.Join(dir, "missing.conf"))
+	assert.Error(t, err)
+	_, err = godiff.ExpandConfigPaths(t.TempDir())
+	assert.Error(t, err)
+}
+
+// Test case for function CompareMergedFiles
+func TestCompareMergedFiles(t *testing.T) {
+	dir := writeConfDir(t)
+	result, err := godiff.CompareMergedFiles(filepath.Join(dir, "glance-api.conf"), filepath.Join(dir, "glance.conf.d"), false, []string{})
+	assert.NoError(t, err)
+	assert.False(t, result.HasDifferences())
+
+	// Only the first file of the directory
+	result, err = godiff.CompareMergedFiles(filepath.Join(dir, "glance-api.conf"), filepath.Join(dir, "glance.conf.d", "00-config.conf"), false, []string{})
+	assert.NoError(t, err)
+	assert.Equal(t, []godiff.Entry{
+		{Kind: godiff.Changed, Section: "DEFAULT", Path: "workers", OldValue: "8", NewValue: "4"},
+		{Kind: godiff.Removed, Section: "glance_store", Path: "stores", OldValue: "rbd"},
+		{Kind: godiff.Removed, Section: "glance_store", Path: "stores", OldValue: "swift"},
+	}, result.Entries)
+}
+
+// Test case for function MergeIniFiles
+func TestMergeIniFiles(t *testing.T) {
+	single := godiff.OptionDefaults{"store": {"k": {Value: "1", Type: godiff.TypeInt}}}
+	multi := godiff.OptionDefaults{"store": {"k": {Type: godiff.TypeString, Multi: true}}}
+	testCases := []struct {
+		name     string
+		types    godiff.OptionDefaults
+		first    string
+		second   string
+		expected string
+	}{
+		{"unknown single values", nil, "k=1\n", "k=3\n", "k = 3\n"},
+		{"unknown repeated key overridden", nil, "k=1\nk=2\n", "k=3\n", "k = 3\n"},
+		{"unknown repeated key of the last file", nil, "k=1\n", "k=3\nk=4\n", "k = 3\nk = 4\n"},
+		{"unknown key of the first file only", nil, "k=1\nk=2\n", "j=3\n", "k = 1\nk = 2\nj = 3\n"},
+		{"single values", single, "k=1\n", "k=3\n", "k = 3\n"},
+		{"single repeated in a file", single, "k=1\n", "k=3\nk=4\n", "k = 4\n"},
+		{"single of the first file only", single, "k=1\nk=2\n", "j=3\n", "k = 2\nj = 3\n"},
+		{"multi once in each file", multi, "k=1\n", "k=3\n", "k = 1\nk = 3\n"},
+		{"multi repeated", multi, "k=1\nk=2\n", "k=3\nk=4\n", "k = 1\nk = 2\nk = 3\nk = 4\n"},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			dir := t.TempDir()
+			first := filepath.Join(dir, "first.conf")
+			second := filepath.Join(dir, "second.conf")
+			assert.NoError(t, os.WriteFile(first, []byte("[store]\n"+tc.first), 0644))
+			assert.NoError(t, os.WriteFile(second, []byte("[store]\n"+tc.second), 0644))
+			merged, err := godiff.MergeIniFiles([]string{first, second}, tc.types)
+			assert.NoError(t, err)
+			assert.Equal(t, "[store]\n"+tc.expected, string(merged))
+		})
+	}
+}
+
+func TestCompareMergedFilesExitCodes(t *testing.T) {
+	dir := writeConfDir(t)
+	conf := filepath.Join(dir, "glance-api.conf")
+
+	// A wrong path is a usage error
+	_, err := godiff.CompareMergedFiles(conf, filepath.Join(dir, "missing.conf"), false, nil)
+	assert.Equal(t, common.ExitUsage, common.ExitCode(err))
+
+	// A file which can't be read isn't
+	assert.NoError(t, os.Mkdir(filepath.Join(dir, "glance.conf.d", "02-config.conf"), 0755))
+	_, err = godiff.CompareMergedFiles(conf, filepath.Join(dir, "glance.conf.d"), false, nil)
+	assert.ErrorContains(t, err, "failed to read")
+	assert.Equal(t, common.ExitCollection, common.ExitCode(err))
+}
@@ -0,0 +1,247 @@
+/*
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * Copyright 2023 Red Hat, Inc.
+ *
+ */
+package godiff
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// mycnfOption is an option of a my.cnf group, or an include directive.
+type mycnfOption struct {
+	// Name as written in the file
+	name string
+	// Name used to match the options: '-' is the same as '_' and the
+	// loose- prefix is dropped, as mysqld does
+	key   string
+	value string
+	line  int
+}
+
+// mycnfGroup is a [group] of a my.cnf file, the include directives before
+// the first group are in the group with an empty name.
+type mycnfGroup struct {
+	name    string
+	line    int
+	options []mycnfOption
+}
+
+// Groups read by the MySQL and MariaDB programs, used to detect my.cnf files.
+var mycnfGroups = map[string]bool{
+	"client": true, "client-server": true, "client-mariadb": true,
+	"mysql": true, "mysqld": true, "mysqld_safe": true, "mysqldump": true,
+	"mariadb": true, "mariadbd": true, "mariadb-client": true,
+	"server": true, "galera": true, "sst": true, "embedded": true,
+}
+
+// Sizes like 128M, compared as their number of bytes
+var mycnfSize = regexp.MustCompile(`^(\d+)([KMGTkmgt])$`)
+
+// CompareMyCnf compares MySQL/MariaDB option files. Options are matched in
+// their group whatever the '-' or '_' in their name, options without value
+// (skip-name-resolve) and the !include/!includedir directives are compared
+// too. Values are compared unquoted, with their size suffixes expanded.
+func CompareMyCnf(origin []byte, dest []byte) (*Result, error) {
+	groups1, err := parseMyCnf(origin)
+	if err != nil {
+		return nil, fmt.Errorf("Error parsing my.cnf: %s", err)
+	}
+	groups2, err := parseMyCnf(dest)
+	if err != nil {
+		return nil, fmt.Errorf("Error parsing my.cnf: %s", err)
+	}
+	result := NewResult("", "", "mycnf")
+	result.setContent(origin, dest)
+	for _, g1 := range groups1 {
+		g2 := findMyCnfGroup(groups2, g1.name)
+		if g2 == nil {
+			if g1.name != "" {
+				result.Add(Entry{Kind: Removed, Section: g1.name, OldLine: g1.line})
+			}
+			for _, o := range g1.options {
+				result.Add(Entry{Kind: Removed, Section: g1.name, Path: o.name, OldValue: o.value, OldLine: o.line})
+			}
+			continue
+		}
+		compareMyCnfOptions(result, g1, g2)
+	}
+	for _, g2 := range groups2 {
+		if findMyCnfGroup(groups1, g2.name) != nil {
+			continue
+		}
+		if g2.name != "" {
+			result.Add(Entry{Kind: Added, Section: g2.name, NewLine: g2.line})
+		}
+		for _, o := range g2.options {
+			result.Add(Entry{Kind: Added, Section: g2.name, Path: o.name, NewValue: o.value, NewLine: o.line})
+		}
+	}
+	return result, nil
+}
+
+// IsMyCnf returns true if the content starts with an include directive or
+// a group read by the MySQL or MariaDB programs.
+func IsMyCnf(data []byte) bool {
+	for _, line := range splitLines(data) {
+		line = strings.TrimSpace(line)
+		if line == "" || line[0] == '#' || line[0] == ';' {
+			continue
+		}
+		if strings.HasPrefix(line, "!include") {
+			return true
+		}
+		if strings.HasPrefix(line, "[") && strings.HasSuffix(line, "]") {
+			return mycnfGroups[strings.ToLower(strings.TrimSpace(line[1:len(line)-1]))]
+		}
+		return false
+	}
+	return false
+}
+
+func findMyCnfGroup(groups []*mycnfGroup, name string) *mycnfGroup {
+	for _, g := range groups {
+		if g.name == name {
+			return g
+		}
+	}
+	return nil
+}
+
+func compareMyCnfOptions(result *Result, g1 *mycnfGroup, g2 *mycnfGroup) {
+	opts1, opts2 := mycnfByKey(g1.options), mycnfByKey(g2.options)
+	for _, o := range g1.options {
+		o2, ok := opts2[o.key]
+		switch {
+		case opts1[o.key].line != o.line:
+			// Overridden by a later occurrence
+		case !ok:
+			result.Add(Entry{Kind: Removed, Section: g1.name, Path: o.name, OldValue: o.value, OldLine: o.line})
+		case !mycnfValuesEqual(g1.name, o.key, o.value, o2.value):
+			result.Add(Entry{Kind: Changed, Section: g1.name, Path: o.name, OldValue: o.value, NewValue: o2.value, OldLine: o.line, NewLine: o2.line})
+		}
+	}
+	for _, o := range g2.options {
+		if _, ok := opts1[o.key]; !ok && opts2[o.key].line == o.line {
+			result.Add(Entry{Kind: Added, Section: g2.name, Path: o.name, NewValue: o.value, NewLine: o.line})
+		}
+	}
+}
+
+func mycnfByKey(options []mycnfOption) map[string]mycnfOption {
+	byKey := map[string]mycnfOption{}
+	for _, o := range options {
+		byKey[o.key] = o
+	}
+	return byKey
+}
+
+func mycnfValuesEqual(group string, key string, v1 string, v2 string) bool {
+	if ValuesEqual(group, key, v1, v2) || mycnfValue(v1) == mycnfValue(v2) {
+		return true
+	}
+	if key == "wsrep_provider_options" {
+		// Galera provider options: "gcache.size=1G; gcs.fc_limit=128"
+		return wsrepOptions(v1) == wsrepOptions(v2)
+	}
+	return false
+}
+
+func mycnfValue(value string) string {
+	if m := mycnfSize.FindStringSubmatch(value); m != nil {
+		n, err := strconv.ParseInt(m[1], 10, 64)
+		if err == nil {
+			shift := strings.Index("KMGT", strings.ToUpper(m[2]))*10 + 10
+			return strconv.FormatInt(n<<shift, 10)
+		}
+	}
+	return value
+}
+
+func wsrepOptions(value string) string {
+	var options []string
+	for _, option := range strings.Split(value, ";") {
+		k, v, _ := strings.Cut(option, "=")
+		if k = strings.TrimSpace(k); k != "" {
+			options = append(options, k+"="+mycnfValue(strings.TrimSpace(v)))
+		}
+	}
+	sort.Strings(options)
+	return strings.Join(options, ";")
+}
+
+func parseMyCnf(data []byte) ([]*mycnfGroup, error) {
+	root := &mycnfGroup{}
+	groups := []*mycnfGroup{root}
+	current := root
+	for i, line := range splitLines(data) {
+		lineNum := i + 1
+		line = strings.TrimSpace(line)
+		if line == "" || line[0] == '#' || line[0] == ';' {
+			continue
+		}
+		if strings.HasPrefix(line, "!") {
+			name, path, _ := strings.Cut(line, " ")
+			if name != "!include" && name != "!includedir" {
+				return nil, fmt.Errorf("line %d: unknown directive %s", lineNum, name)
+			}
+			path = strings.TrimSpace(path)
+			// Several files can be included, the path is part of the key
+			current.options = append(current.options, mycnfOption{name: name, key: name + " " + path, value: path, line: lineNum})
+			continue
+		}
+		if strings.HasPrefix(line, "[") {
+			if !strings.HasSuffix(line, "]") {
+				return nil, fmt.Errorf("line %d: invalid group %s", lineNum, line)
+			}
+			name := strings.TrimSpace(line[1 : len(line)-1])
+			if current = findMyCnfGroup(groups, name); current == nil {
+				current = &mycnfGroup{name: name, line: lineNum}
+				groups = append(groups, current)
+			}
+			continue
+		}
+		if current == root {
+			return nil, fmt.Errorf("line %d: option outside of a group: %s", lineNum, line)
+		}
+		name, value, _ := strings.Cut(line, "=")
+		name = strings.TrimSpace(name)
+		key := strings.ReplaceAll(name, "-", "_")
+		key = strings.TrimPrefix(key, "loose_")
+		current.options = append(current.options, mycnfOption{name: name, key: key, value: mycnfUnquote(value), line: lineNum})
+	}
+	if len(root.options) == 0 {
+		groups = groups[1:]
+	}
+	return groups, nil
+}
+
+// mycnfUnquote removes the comment at the end of a value and its quotes.
+func mycnfUnquote(value string) string {
+	value = strings.TrimSpace(value)
+	if len(value) > 0 && (value[0] == '"' || value[0] == '\'') {
+		if end := strings.IndexByte(value[1:], value[0]); end >= 0 {
+			return value[1 : end+1]
+		}
+	}
+	if i := strings.Index(value, "#"); i >= 0 {
+		value = strings.TrimSpace(value[:i])
+	}
+	return value
+}
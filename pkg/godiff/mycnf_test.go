@@ -0,0 +1,98 @@
+/*
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * Copyright 2023 Red Hat, Inc.
+ *
+ */
+package godiff_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/openstack-k8s-operators/os-diff/pkg/godiff"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCompareMyCnf(t *testing.T) {
+	origin := []byte(`!includedir /etc/my.cnf.d
+
+[mysqld]
+bind-address = 172.17.0.10
+skip-name-resolve
+max_connections = 4096
+innodb_buffer_pool_size = 1G
+wsrep_provider_options = "gcache.size=1G; gcs.fc_limit=128"
+log-error = /var/log/mysql/mysqld.log # error log
+
+[mysqld_safe]
+pid-file = /var/run/mysql/mysqld.pid
+`)
+	dest := []byte(`!includedir /etc/my.cnf.d
+!include /etc/mysql/secrets.cnf
+
+[mysqld]
+bind_address = *
+skip_name_resolve
+max-connections = 4096
+innodb-buffer-pool-size = 1024M
+wsrep_provider_options = "gcs.fc_limit=128;gcache.size=1024M"
+log_error = '/var/log/mysql/mysqld.log'
+loose-wsrep-sync-wait = 1
+
+[galera]
+wsrep_on = ON
+`)
+	result, err := godiff.CompareMyCnf(origin, dest)
+	assert.NoError(t, err)
+	assert.Equal(t, "mycnf", result.Format)
+	assert.Equal(t, []godiff.Entry{
+		{Kind: godiff.Added, Section: "", Path: "!include", NewValue: "/etc/mysql/secrets.cnf", NewLine: 2},
+		{Kind: godiff.Changed, Section: "mysqld", Path: "bind-address", OldValue: "172.17.0.10", NewValue: "*", OldLine: 4, NewLine: 5},
+		{Kind: godiff.Added, Section: "mysqld", Path: "loose-wsrep-sync-wait", NewValue: "1", NewLine: 11},
+		{Kind: godiff.Removed, Section: "mysqld_safe", OldLine: 11},
+		{Kind: godiff.Removed, Section: "mysqld_safe", Path: "pid-file", OldValue: "/var/run/mysql/mysqld.pid", OldLine: 12},
+		{Kind: godiff.Added, Section: "galera", NewLine: 13},
+		{Kind: godiff.Added, Section: "galera", Path: "wsrep_on", NewValue: "ON", NewLine: 14},
+	}, result.Entries)
+
+	assert.Equal(t, []string{
+		"+!include /etc/mysql/secrets.cnf\n",
+		"[mysqld]\n-bind-address=172.17.0.10\n+bind-address=*\n",
+		"+loose-wsrep-sync-wait=1\n",
+		"-[mysqld_safe]\n",
+		"-pid-file=/var/run/mysql/mysqld.pid\n",
+		"+[galera]\n",
+		"+wsrep_on=ON\n",
+	}, result.Report())
+}
+
+func TestIsMyCnf(t *testing.T) {
+	assert.True(t, godiff.IsMyCnf([]byte("# MariaDB\n[mysqld]\nskip-name-resolve\n")))
+	assert.True(t, godiff.IsMyCnf([]byte("!includedir /etc/my.cnf.d\n")))
+	assert.False(t, godiff.IsMyCnf([]byte("[DEFAULT]\ndebug=true\n")))
+	assert.False(t, godiff.IsMyCnf([]byte("")))
+
+	_, err := godiff.CompareMyCnf([]byte("skip-name-resolve\n"), []byte("[mysqld]\n"))
+	assert.Error(t, err)
+}
+
+func TestMyCnfSecretsRedaction(t *testing.T) {
+	result, err := godiff.CompareMyCnf([]byte("[mysqld]\nwsrep_sst_auth = root:secret1\n"), []byte("[mysqld]\nwsrep-sst-auth = root:secret2\n"))
+	assert.NoError(t, err)
+	report := strings.Join(result.Report(), "")
+	assert.Contains(t, report, "<redacted:")
+	assert.NotContains(t, report, "secret1")
+	assert.NotContains(t, report, "secret2")
+	assert.NotContains(t, result.Redacted().Entries[0].NewValue, "secret2")
+}
@@ -0,0 +1,262 @@
+/*
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * Copyright 2023 Red Hat, Inc.
+ *
+ */
+package godiff
+
+import (
+	"fmt"
+	"net/url"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Value types, following the oslo.config option types.
+type ValueType string
+
+const (
+	TypeAuto   ValueType = "auto"
+	TypeString ValueType = "string"
+	TypeBool   ValueType = "bool"
+	TypeInt    ValueType = "int"
+	TypeList   ValueType = "list"
+	TypeDict   ValueType = "dict"
+	TypeURL    ValueType = "url"
+
+	// Comma separated items compared in order, the lists guessed in auto
+	// mode.
+	typeItems ValueType = "items"
+)
+
+// NormalizeRule sets the type of the INI options matching Section and Key
+// (glob patterns).
+type NormalizeRule struct {
+	Section string    `yaml:"section"`
+	Key     string    `yaml:"key"`
+	Type    ValueType `yaml:"type"`
+}
+
+var normalizeAll = false
+var normalizeRules []NormalizeRule
+
+// SetNormalize enables the normalization of every INI value with the type
+// guessed from the values (all) and/or of the options matching the rules.
+func SetNormalize(all bool, rules []NormalizeRule) {
+	normalizeAll = all
+	normalizeRules = rules
+}
+
+func LoadNormalizeRules(rulesFile string) ([]NormalizeRule, error) {
+	data, err := os.ReadFile(rulesFile)
+	if err != nil {
+		return nil, err
+	}
+	var rules []NormalizeRule
+	if err := yaml.Unmarshal(data, &rules); err != nil {
+		return nil, fmt.Errorf("failed to load normalize rules %s: %w", rulesFile, err)
+	}
+	for _, rule := range rules {
+		switch rule.Type {
+		case TypeAuto, TypeString, TypeBool, TypeInt, TypeList, TypeDict, TypeURL:
+		default:
+			return nil, fmt.Errorf("unknown type %q for %s/%s in %s", rule.Type, rule.Section, rule.Key, rulesFile)
+		}
+	}
+	return rules, nil
+}
+
+// valueType returns the type used to compare an option, an empty type means
+// the values are compared as they are. In auto mode the type of the option in
+// the oslo-config-generator output of the service wins over the guessed type,
+// except for the strings (transport_url, hosts...) which are still guessed.
+func valueType(types OptionDefaults, section string, key string) ValueType {
+	for _, rule := range normalizeRules {
+		if (rule.Section == "" || globMatch(rule.Section, section)) && (rule.Key == "" || globMatch(rule.Key, key)) {
+			return rule.Type
+		}
+	}
+	if normalizeAll {
+		if opt, ok := types[section][optionName(key)]; ok && opt.Type != TypeString {
+			return opt.Type
+		}
+		return TypeAuto
+	}
+	return ""
+}
+
+// ValuesEqual compares two INI values once normalized for their type.
+func ValuesEqual(section string, key string, v1 string, v2 string) bool {
+	return OptionDefaults(nil).ValuesEqual(section, key, v1, v2)
+}
+
+// ValuesEqual compares two INI values once normalized for their type, the
+// type of the options comes from the rules, then from the defaults.
+func (d OptionDefaults) ValuesEqual(section string, key string, v1 string, v2 string) bool {
+	if v1 == v2 || pasteUseEqual(section, key, v1, v2) {
+		return true
+	}
+	t := valueType(d, section, key)
+	if t == "" {
+		return false
+	}
+	if t == TypeAuto {
+		t = guessType(v1, v2)
+	}
+	return NormalizeValue(t, v1) == NormalizeValue(t, v2)
+}
+
+// guessType returns the first type both values can be parsed as. The order
+// of the items of a list matters for some options (enabled_filters,
+// api_servers...), a guessed list is compared in order.
+func guessType(v1 string, v2 string) ValueType {
+	v1 = unquote(v1)
+	v2 = unquote(v2)
+	if _, ok := parseBool(v1); ok {
+		if _, ok := parseBool(v2); ok {
+			return TypeBool
+		}
+	}
+	if _, err := strconv.ParseInt(v1, 10, 64); err == nil {
+		if _, err := strconv.ParseInt(v2, 10, 64); err == nil {
+			return TypeInt
+		}
+	}
+	if strings.Contains(v1, "://") && strings.Contains(v2, "://") {
+		return TypeURL
+	}
+	if strings.Contains(v1, ",") || strings.Contains(v2, ",") {
+		if isDict(v1) && isDict(v2) {
+			return TypeDict
+		}
+		return typeItems
+	}
+	return TypeString
+}
+
+// NormalizeValue returns the canonical form of a value for its type, values
+// which can't be parsed are only unquoted.
+func NormalizeValue(t ValueType, value string) string {
+	value = unquote(value)
+	switch t {
+	case TypeBool:
+		if b, ok := parseBool(value); ok {
+			return strconv.FormatBool(b)
+		}
+	case TypeInt:
+		if i, err := strconv.ParseInt(value, 10, 64); err == nil {
+			return strconv.FormatInt(i, 10)
+		}
+	case TypeList:
+		items := splitItems(value)
+		sort.Strings(items)
+		return strings.Join(items, ",")
+	case typeItems:
+		return strings.Join(splitItems(value), ",")
+	case TypeDict:
+		var items []string
+		for _, item := range splitItems(value) {
+			k, v, _ := strings.Cut(item, ":")
+			items = append(items, strings.TrimSpace(k)+":"+strings.TrimSpace(v))
+		}
+		sort.Strings(items)
+		return strings.Join(items, ",")
+	case TypeURL:
+		// transport_url can hold several hosts, only the URL parts are
+		// normalized, the order is kept. The hosts after the first one
+		// have no scheme: user:password@host:port.
+		var items []string
+		for i, item := range splitItems(value) {
+			if i == 0 || strings.Contains(item, "://") {
+				items = append(items, normalizeURL(item))
+			} else {
+				items = append(items, normalizeURLHost(item))
+			}
+		}
+		return strings.Join(items, ",")
+	}
+	return value
+}
+
+func unquote(value string) string {
+	value = strings.TrimSpace(value)
+	if len(value) >= 2 && (value[0] == '"' || value[0] == '\'') && value[len(value)-1] == value[0] {
+		return value[1 : len(value)-1]
+	}
+	return value
+}
+
+// parseBool accepts the same values as oslo.config BoolOpt.
+func parseBool(value string) (bool, bool) {
+	switch strings.ToLower(value) {
+	case "true", "1", "yes", "on":
+		return true, true
+	case "false", "0", "no", "off":
+		return false, true
+	}
+	return false, false
+}
+
+func isDict(value string) bool {
+	for _, item := range splitItems(value) {
+		if !strings.Contains(item, ":") {
+			return false
+		}
+	}
+	return true
+}
+
+func splitItems(value string) []string {
+	items := []string{}
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+func normalizeURL(value string) string {
+	u, err := url.Parse(value)
+	if err != nil || u.Scheme == "" {
+		return strings.TrimRight(value, "/")
+	}
+	u.Scheme = strings.ToLower(u.Scheme)
+	u.Host = strings.ToLower(u.Host)
+	u.Path = strings.TrimRight(u.Path, "/")
+	u.RawPath = ""
+	return u.String()
+}
+
+// normalizeURLHost lowercases the host of a user:password@host:port item of
+// a URL with several hosts, the last one holding the path and query of the
+// URL. The credentials are kept as they are.
+func normalizeURLHost(item string) string {
+	creds, host := "", item
+	if i := strings.LastIndex(item, "@"); i >= 0 {
+		creds, host = item[:i+1], item[i+1:]
+	}
+	path, query := "", ""
+	if i := strings.IndexAny(host, "/?"); i >= 0 {
+		host, path = host[:i], host[i:]
+	}
+	if i := strings.Index(path, "?"); i >= 0 {
+		path, query = path[:i], path[i:]
+	}
+	return creds + strings.ToLower(host) + strings.TrimRight(path, "/") + query
+}
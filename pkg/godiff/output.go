@@ -0,0 +1,160 @@
+/*
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * Copyright 2023 Red Hat, Inc.
+ *
+ */
+package godiff
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/openstack-k8s-operators/os-diff/pkg/common"
+)
+
+const (
+	TextOutput    = "text"
+	JSONOutput    = "json"
+	UnifiedOutput = "unified"
+)
+
+var outputFormat = TextOutput
+
+// FileReport is a compared file pair as written in the JSON report,
+// Type is the content type detected for the origin file (json, ini, yaml or
+// raw), the format of the result is the name of the comparer selected.
+type FileReport struct {
+	Type string `json:"type"`
+	*Result
+}
+
+// DiffReport is the JSON document written with --output-format json
+type DiffReport struct {
+	CatalogVersion string       `json:"catalog_version"`
+	Files          []FileReport `json:"files"`
+	MissingPaths   []string     `json:"missing_paths,omitempty"`
+	// Sources of the missing paths per the os-diff pull manifests: where the
+	// path comes from, or why the other side failed to collect it
+	MissingSources map[string]*common.ManifestFile `json:"missing_sources,omitempty"`
+	TypeMismatches []string                        `json:"type_mismatches,omitempty"`
+}
+
+var diffReport DiffReport
+
+// SetOutputFormat selects between the colored console output, the JSON
+// report and the unified diff. With the JSON and unified outputs the logs
+// only go to results.log so the console output can be parsed.
+func SetOutputFormat(format string) error {
+	switch format {
+	case TextOutput:
+	case JSONOutput, UnifiedOutput:
+		if logFile != nil {
+			log.SetOutput(logFile)
+		} else {
+			log.SetOutput(os.Stderr)
+		}
+	default:
+		return fmt.Errorf("unknown output format: %s, should be one of: %s, %s, %s", format, TextOutput, JSONOutput, UnifiedOutput)
+	}
+	outputFormat = format
+	return nil
+}
+
+func IsJSONOutput() bool {
+	return outputFormat == JSONOutput
+}
+
+func IsUnifiedOutput() bool {
+	return outputFormat == UnifiedOutput
+}
+
+// RecordResult keeps a result for the JSON report, it does nothing with the
+// text output.
+func RecordResult(result *Result, fileType string) {
+	if !IsJSONOutput() || result == nil {
+		return
+	}
+	diffReport.Files = append(diffReport.Files, FileReport{Type: fileType, Result: result})
+}
+
+// PublishResult prints the result on the console or records it for the
+// JSON report depending on the output format.
+func PublishResult(result *Result, fileType string) {
+	if IsJSONOutput() {
+		RecordResult(result, fileType)
+		return
+	}
+	printResult(result)
+}
+
+// printResult prints the result on the console, colored with the text
+// output or as a plain unified diff.
+func printResult(result *Result) {
+	switch outputFormat {
+	case JSONOutput:
+	case UnifiedOutput:
+		fmt.Print(strings.Join(result.UnifiedDiff(), ""))
+	default:
+		if result.HasDifferences() {
+			PrintReport(result.Report())
+		}
+		printClassified(result)
+	}
+}
+
+// printClassified prints the differences which are not part of the report:
+// options set to their default value and expected differences.
+func printClassified(result *Result) {
+	for _, line := range result.DefaultsReport() {
+		fmt.Print(line)
+	}
+	for _, line := range result.ExpectedReport() {
+		fmt.Print(line)
+	}
+}
+
+func recordMissingPath(path string, source *common.ManifestFile) {
+	if IsJSONOutput() {
+		diffReport.MissingPaths = append(diffReport.MissingPaths, path)
+		if source != nil {
+			if diffReport.MissingSources == nil {
+				diffReport.MissingSources = map[string]*common.ManifestFile{}
+			}
+			diffReport.MissingSources[path] = source
+		}
+	}
+}
+
+func recordTypeMismatch(path string) {
+	if IsJSONOutput() {
+		diffReport.TypeMismatches = append(diffReport.TypeMismatches, path)
+	}
+}
+
+// WriteJSONReport writes every recorded result as a single JSON document,
+// secrets are redacted as in the text report.
+func WriteJSONReport(w io.Writer) error {
+	report := diffReport
+	report.CatalogVersion = CatalogVersion
+	report.Files = []FileReport{}
+	for _, file := range diffReport.Files {
+		report.Files = append(report.Files, FileReport{Type: file.Type, Result: file.Result.Redacted()})
+	}
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(report)
+}
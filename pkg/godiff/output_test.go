@@ -0,0 +1,146 @@
+/*
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * Copyright 2023 Red Hat, Inc.
+ *
+ */
+package godiff_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/openstack-k8s-operators/os-diff/pkg/godiff"
+	"github.com/stretchr/testify/assert"
+)
+
+// Test case for function WriteJSONReport
+func TestWriteJSONReport(t *testing.T) {
+	assert.Error(t, godiff.SetOutputFormat("xml"))
+	assert.NoError(t, godiff.SetOutputFormat(godiff.JSONOutput))
+	defer godiff.SetOutputFormat(godiff.TextOutput)
+
+	result, err := godiff.CompareIni([]byte("[DEFAULT]\ndebug=True\n"), []byte("[DEFAULT]\ndebug=False\n"), "a.conf", "b.conf", false, []string{})
+	assert.NoError(t, err)
+	godiff.PublishResult(result, "ini")
+
+	var buf bytes.Buffer
+	assert.NoError(t, godiff.WriteJSONReport(&buf))
+
+	var report godiff.DiffReport
+	assert.NoError(t, json.Unmarshal(buf.Bytes(), &report))
+	assert.Len(t, report.Files, 1)
+	assert.Equal(t, "ini", report.Files[0].Type)
+	assert.Equal(t, "a.conf", report.Files[0].Origin)
+	assert.Equal(t, []godiff.Entry{
+		{Kind: godiff.Changed, Section: "DEFAULT", Path: "debug", OldValue: "True", NewValue: "False", OldLine: 2, NewLine: 2},
+	}, report.Files[0].Entries)
+}
+
+// The type of a file is the detected content type, the format is the comparer
+func TestWriteJSONReportTypeAndFormat(t *testing.T) {
+	assert.NoError(t, godiff.SetOutputFormat(godiff.JSONOutput))
+	defer godiff.SetOutputFormat(godiff.TextOutput)
+
+	dir := t.TempDir()
+	origin := filepath.Join(dir, "origin", "policy.yaml")
+	dest := filepath.Join(dir, "dest", "policy.yaml")
+	assert.NoError(t, os.MkdirAll(filepath.Dir(origin), 0755))
+	assert.NoError(t, os.MkdirAll(filepath.Dir(dest), 0755))
+	assert.NoError(t, os.WriteFile(origin, []byte("\"os_compute_api:servers:index\": \"rule:admin_api\"\n"), 0644))
+	assert.NoError(t, os.WriteFile(dest, []byte("\"os_compute_api:servers:index\": \"rule:admin_or_owner\"\n"), 0644))
+	_, err := godiff.CompareFiles(origin, dest, false, false, []string{})
+	assert.NoError(t, err)
+
+	var buf bytes.Buffer
+	assert.NoError(t, godiff.WriteJSONReport(&buf))
+	var report godiff.DiffReport
+	assert.NoError(t, json.Unmarshal(buf.Bytes(), &report))
+	file := report.Files[len(report.Files)-1]
+	assert.Equal(t, "yaml", file.Type)
+	assert.Equal(t, "policy", file.Format)
+}
+
+// Test case for function UnifiedDiff
+func TestUnifiedDiff(t *testing.T) {
+	origin := "[DEFAULT]\n# comment\ndebug=True\nverbose=True\n\n[database]\nconnection=mysql://db1\n"
+	dest := "[DEFAULT]\n# other comment\ndebug=False\nverbose=True\n\n[database]\nconnection=mysql://db1\n"
+	result, err := godiff.CompareIni([]byte(origin), []byte(dest), "a.conf", "b.conf", false, []string{})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{
+		"--- a.conf\n",
+		"+++ b.conf\n",
+		"@@ -1,6 +1,6 @@\n",
+		" [DEFAULT]\n",
+		"-# comment\n",
+		"-debug=True\n",
+		"+# other comment\n",
+		"+debug=False\n",
+		" verbose=True\n",
+		" \n",
+		" [database]\n",
+	}, result.UnifiedDiff())
+
+	same, err := godiff.CompareIni([]byte(origin), []byte(origin), "a.conf", "b.conf", false, []string{})
+	assert.NoError(t, err)
+	assert.Empty(t, same.UnifiedDiff())
+
+	// Without the compared contents, the entries are listed in a single hunk
+	mapping := godiff.NewResult("nova.conf", "nova.yaml", "mapping")
+	mapping.Entries = []godiff.Entry{
+		{Kind: godiff.Changed, Path: "debug", OldValue: "True", NewValue: "False"},
+		{Kind: godiff.Added, Path: "workers", NewValue: "4"},
+	}
+	assert.Equal(t, []string{
+		"--- nova.conf\n",
+		"+++ nova.yaml\n",
+		"@@ -1 +1,2 @@\n",
+		"-debug: True\n",
+		"+debug: False\n",
+		"+workers: 4\n",
+	}, mapping.UnifiedDiff())
+}
+
+// The last lines without line break are followed by the diff marker
+func TestUnifiedDiffNoNewline(t *testing.T) {
+	result, err := godiff.CompareRawData([]byte("a\nb"), []byte("a\nc"), "a.txt", "b.txt")
+	assert.NoError(t, err)
+	assert.Equal(t, []string{
+		"--- a.txt\n",
+		"+++ b.txt\n",
+		"@@ -1,2 +1,2 @@\n",
+		" a\n",
+		"-b\n",
+		"\\ No newline at end of file\n",
+		"+c\n",
+		"\\ No newline at end of file\n",
+	}, result.UnifiedDiff())
+
+	// Only the origin has no line break after its last line
+	result, err = godiff.CompareRawData([]byte("a\nb\nc"), []byte("a\nx\nc\n"), "a.txt", "b.txt")
+	assert.NoError(t, err)
+	assert.Equal(t, []string{
+		"--- a.txt\n",
+		"+++ b.txt\n",
+		"@@ -1,3 +1,3 @@\n",
+		" a\n",
+		"-b\n",
+		"+x\n",
+		"-c\n",
+		"\\ No newline at end of file\n",
+		"+c\n",
+	}, result.UnifiedDiff())
+}
@@ -0,0 +1,342 @@
+/*
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * Copyright 2023 Red Hat, Inc.
+ *
+ */
+package godiff
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// policyRule is a rule of an oslo.policy file with its line number.
+type policyRule struct {
+	name string
+	expr string
+	line int
+}
+
+// policyNode is a parsed oslo.policy check expression.
+type policyNode struct {
+	// "or", "and", "not" or "check"
+	op       string
+	check    string
+	children []*policyNode
+}
+
+// Maximum depth of the rule:<name> references expanded when comparing rules.
+const policyMaxDepth = 10
+
+// CompareOsloPolicy compares oslo.policy files in the YAML or JSON format.
+// Rules are compared by name and their check expressions are normalized:
+// spaces, case of the operators, order of the "and"/"or" operands and the
+// empty rule ("" or "@"). A rule:<name> reference and the expression of the
+// referenced rule are also considered equal.
+func CompareOsloPolicy(origin []byte, dest []byte) (*Result, error) {
+	rules1, err := loadPolicyRules(origin)
+	if err != nil {
+		return nil, fmt.Errorf("Error loading policy rules: %s", err)
+	}
+	rules2, err := loadPolicyRules(dest)
+	if err != nil {
+		return nil, fmt.Errorf("Error loading policy rules: %s", err)
+	}
+	result := NewResult("", "", "policy")
+	result.setContent(origin, dest)
+	byName1 := map[string]policyRule{}
+	for _, rule := range rules1 {
+		byName1[rule.name] = rule
+	}
+	byName2 := map[string]policyRule{}
+	for _, rule := range rules2 {
+		byName2[rule.name] = rule
+	}
+	for _, rule := range rules1 {
+		rule2, ok := byName2[rule.name]
+		switch {
+		case !ok:
+			result.Add(Entry{Kind: Removed, Path: rule.name, OldValue: rule.expr, OldLine: rule.line})
+		case !policyRulesEqual(rule.expr, byName1, rule2.expr, byName2):
+			result.Add(Entry{Kind: Changed, Path: rule.name, OldValue: rule.expr, NewValue: rule2.expr, OldLine: rule.line, NewLine: rule2.line})
+		}
+	}
+	for _, rule := range rules2 {
+		if _, ok := byName1[rule.name]; !ok {
+			result.Add(Entry{Kind: Added, Path: rule.name, NewValue: rule.expr, NewLine: rule.line})
+		}
+	}
+	return result, nil
+}
+
+// IsOsloPolicy returns true for a YAML or JSON mapping of rule names to
+// valid check expressions with at least one role or rule check.
+func IsOsloPolicy(data []byte) bool {
+	rules, err := loadPolicyRules(data)
+	if err != nil || len(rules) == 0 {
+		return false
+	}
+	hasCheck := false
+	for _, rule := range rules {
+		node, err := parsePolicy(rule.expr)
+		if err != nil {
+			return false
+		}
+		hasCheck = hasCheck || node.hasRoleOrRule()
+	}
+	return hasCheck
+}
+
+func (n *policyNode) hasRoleOrRule() bool {
+	if n.op == "check" {
+		return strings.HasPrefix(n.check, "role:") || strings.HasPrefix(n.check, "rule:")
+	}
+	for _, child := range n.children {
+		if child.hasRoleOrRule() {
+			return true
+		}
+	}
+	return false
+}
+
+// NormalizePolicyRule returns the canonical form of a check expression.
+func NormalizePolicyRule(expr string) (string, error) {
+	node, err := parsePolicy(expr)
+	if err != nil {
+		return "", err
+	}
+	return node.String(), nil
+}
+
+// loadPolicyRules reads the rules of a policy file, JSON being valid YAML.
+func loadPolicyRules(data []byte) ([]policyRule, error) {
+	var doc yaml.Node
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return nil, err
+	}
+	if len(doc.Content) == 0 || doc.Content[0].Kind != yaml.MappingNode {
+		return nil, fmt.Errorf("not a mapping of policy rules")
+	}
+	var rules []policyRule
+	root := doc.Content[0]
+	for i := 0; i+1 < len(root.Content); i += 2 {
+		key, value := root.Content[i], root.Content[i+1]
+		if value.Kind != yaml.ScalarNode {
+			return nil, fmt.Errorf("line %d: rule %s is not a string", value.Line, key.Value)
+		}
+		rules = append(rules, policyRule{name: key.Value, expr: value.Value, line: key.Line})
+	}
+	return rules, nil
+}
+
+func policyRulesEqual(expr1 string, rules1 map[string]policyRule, expr2 string, rules2 map[string]policyRule) bool {
+	node1, err1 := parsePolicy(expr1)
+	node2, err2 := parsePolicy(expr2)
+	if err1 != nil || err2 != nil {
+		return strings.Join(strings.Fields(expr1), " ") == strings.Join(strings.Fields(expr2), " ")
+	}
+	if node1.String() == node2.String() {
+		return true
+	}
+	return expandPolicy(node1, rules1, 0).String() == expandPolicy(node2, rules2, 0).String()
+}
+
+// expandPolicy replaces the rule:<name> checks by the expression of the
+// rules defined in the file.
+func expandPolicy(node *policyNode, rules map[string]policyRule, depth int) *policyNode {
+	if depth > policyMaxDepth {
+		return node
+	}
+	if node.op == "check" {
+		if !strings.HasPrefix(node.check, "rule:") {
+			return node
+		}
+		rule, ok := rules[strings.TrimPrefix(node.check, "rule:")]
+		if !ok {
+			return node
+		}
+		expanded, err := parsePolicy(rule.expr)
+		if err != nil {
+			return node
+		}
+		return expandPolicy(expanded, rules, depth+1)
+	}
+	expanded := &policyNode{op: node.op}
+	for _, child := range node.children {
+		expanded.children = append(expanded.children, expandPolicy(child, rules, depth))
+	}
+	return expanded
+}
+
+// String renders the canonical form of the expression: operands of "and"
+// and "or" sorted and deduplicated, parentheses only where needed.
+func (n *policyNode) String() string {
+	switch n.op {
+	case "check":
+		return n.check
+	case "not":
+		return "not " + n.children[0].operand()
+	}
+	seen := map[string]bool{}
+	var operands []string
+	for _, child := range n.flatten(n.op) {
+		operand := child.operand()
+		if !seen[operand] {
+			seen[operand] = true
+			operands = append(operands, operand)
+		}
+	}
+	sort.Strings(operands)
+	if len(operands) == 1 {
+		return operands[0]
+	}
+	return strings.Join(operands, " "+n.op+" ")
+}
+
+func (n *policyNode) operand() string {
+	s := n.String()
+	if (n.op == "and" || n.op == "or") && strings.Contains(s, " "+n.op+" ") {
+		return "(" + s + ")"
+	}
+	return s
+}
+
+// flatten returns the operands of nested operations of the same kind,
+// a and (b and c) is a and b and c.
+func (n *policyNode) flatten(op string) []*policyNode {
+	if n.op != op {
+		return []*policyNode{n}
+	}
+	var operands []*policyNode
+	for _, child := range n.children {
+		operands = append(operands, child.flatten(op)...)
+	}
+	return operands
+}
+
+// policyTokens splits an expression as oslo.policy does: on spaces, with the
+// parentheses at the start or end of the words.
+func policyTokens(expr string) []string {
+	var tokens []string
+	for _, word := range strings.Fields(expr) {
+		clean := strings.TrimLeft(word, "(")
+		for i := 0; i < len(word)-len(clean); i++ {
+			tokens = append(tokens, "(")
+		}
+		trimmed := strings.TrimRight(clean, ")")
+		if trimmed != "" {
+			lowered := strings.ToLower(trimmed)
+			if lowered == "and" || lowered == "or" || lowered == "not" {
+				trimmed = lowered
+			}
+			tokens = append(tokens, trimmed)
+		}
+		for i := 0; i < len(clean)-len(trimmed); i++ {
+			tokens = append(tokens, ")")
+		}
+	}
+	return tokens
+}
+
+type policyParser struct {
+	tokens []string
+	pos    int
+}
+
+// parsePolicy parses a check expression, the empty expression always
+// passes like "@".
+func parsePolicy(expr string) (*policyNode, error) {
+	p := &policyParser{tokens: policyTokens(expr)}
+	if len(p.tokens) == 0 {
+		return &policyNode{op: "check", check: "@"}, nil
+	}
+	node, err := p.parseOr()
+	if err != nil {
+		return nil, err
+	}
+	if p.pos < len(p.tokens) {
+		return nil, fmt.Errorf("unexpected %q in %q", p.tokens[p.pos], expr)
+	}
+	return node, nil
+}
+
+func (p *policyParser) peek() string {
+	if p.pos < len(p.tokens) {
+		return p.tokens[p.pos]
+	}
+	return ""
+}
+
+func (p *policyParser) parseOr() (*policyNode, error) {
+	return p.parseBinary("or", p.parseAnd)
+}
+
+func (p *policyParser) parseAnd() (*policyNode, error) {
+	return p.parseBinary("and", p.parseNot)
+}
+
+func (p *policyParser) parseBinary(op string, operand func() (*policyNode, error)) (*policyNode, error) {
+	node, err := operand()
+	if err != nil {
+		return nil, err
+	}
+	if p.peek() != op {
+		return node, nil
+	}
+	node = &policyNode{op: op, children: []*policyNode{node}}
+	for p.peek() == op {
+		p.pos++
+		child, err := operand()
+		if err != nil {
+			return nil, err
+		}
+		node.children = append(node.children, child)
+	}
+	return node, nil
+}
+
+func (p *policyParser) parseNot() (*policyNode, error) {
+	switch token := p.peek(); token {
+	case "not":
+		p.pos++
+		child, err := p.parseNot()
+		if err != nil {
+			return nil, err
+		}
+		return &policyNode{op: "not", children: []*policyNode{child}}, nil
+	case "(":
+		p.pos++
+		node, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		if p.peek() != ")" {
+			return nil, fmt.Errorf("missing closing parenthesis")
+		}
+		p.pos++
+		return node, nil
+	case "", ")", "and", "or":
+		return nil, fmt.Errorf("missing check before %q", token)
+	default:
+		p.pos++
+		if kind, match, ok := strings.Cut(token, ":"); ok && strings.EqualFold(kind, "role") {
+			// Roles are matched without case
+			token = "role:" + strings.ToLower(match)
+		}
+		return &policyNode{op: "check", check: token}, nil
+	}
+}
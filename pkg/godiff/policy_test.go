@@ -0,0 +1,85 @@
+/*
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * Copyright 2023 Red Hat, Inc.
+ *
+ */
+package godiff_test
+
+import (
+	"testing"
+
+	"github.com/openstack-k8s-operators/os-diff/pkg/godiff"
+	"github.com/stretchr/testify/assert"
+)
+
+// Test case for function NormalizePolicyRule
+func TestNormalizePolicyRule(t *testing.T) {
+	tests := []struct {
+		expr     string
+		expected string
+	}{
+		{"", "@"},
+		{"role:admin", "role:admin"},
+		{"role:Admin  OR   rule:owner", "role:admin or rule:owner"},
+		{"rule:owner or role:admin or role:admin", "role:admin or rule:owner"},
+		{"(role:reader and system_scope:all) or role:admin", "(role:reader and system_scope:all) or role:admin"},
+		{"role:admin or (system_scope:all and role:reader)", "(role:reader and system_scope:all) or role:admin"},
+		{"role:a and (role:b and role:c)", "role:a and role:b and role:c"},
+		{"not (role:a or role:b)", "not (role:a or role:b)"},
+		{"project_id:%(project_id)s and (user_id:%(user_id)s)", "project_id:%(project_id)s and user_id:%(user_id)s"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.expr, func(t *testing.T) {
+			normalized, err := godiff.NormalizePolicyRule(tt.expr)
+			assert.NoError(t, err)
+			assert.Equal(t, tt.expected, normalized)
+		})
+	}
+	for _, expr := range []string{"role:a or", "(role:a", "role:a)", "and role:a"} {
+		_, err := godiff.NormalizePolicyRule(expr)
+		assert.Error(t, err, expr)
+	}
+}
+
+// Test case for function CompareOsloPolicy
+func TestCompareOsloPolicy(t *testing.T) {
+	origin := []byte(`"admin_api": "role:admin"
+"os_compute_api:servers:create": "rule:admin_api or project_id:%(project_id)s"
+"os_compute_api:servers:delete": "rule:admin_api"
+"os_compute_api:os-hypervisors:list": "role:admin"
+"os_compute_api:servers:show": ""
+`)
+	dest := []byte(`{
+    "os_compute_api:servers:create": "project_id:%(project_id)s  or  rule:admin_api",
+    "os_compute_api:servers:delete": "role:admin",
+    "os_compute_api:os-hypervisors:list": "role:admin and system_scope:all",
+    "os_compute_api:servers:show": "@",
+    "os_compute_api:servers:resize": "rule:admin_api"
+}`)
+	assert.True(t, godiff.IsOsloPolicy(origin))
+	assert.True(t, godiff.IsOsloPolicy(dest))
+	assert.False(t, godiff.IsOsloPolicy([]byte("replicas: 1\n")))
+	assert.False(t, godiff.IsOsloPolicy([]byte(`{"url": "http://controller:5000"}`)))
+
+	result, err := godiff.CompareOsloPolicy(origin, dest)
+	assert.NoError(t, err)
+	assert.Equal(t, []godiff.Entry{
+		{Kind: godiff.Removed, Path: "admin_api", OldValue: "role:admin", OldLine: 1},
+		{Kind: godiff.Changed, Path: "os_compute_api:os-hypervisors:list", OldValue: "role:admin", NewValue: "role:admin and system_scope:all", OldLine: 4, NewLine: 4},
+		{Kind: godiff.Added, Path: "os_compute_api:servers:resize", NewValue: "rule:admin_api", NewLine: 6},
+	}, result.Entries)
+
+	assert.Equal(t, "policy", godiff.DetectFormat("nova/policy.yaml", origin))
+	assert.Equal(t, "json", godiff.DetectFormat("config.json", []byte(`{"command": "nova-api"}`)))
+}
@@ -30,6 +30,7 @@ import (
 )
 
 var log = logrus.New()
+var logFile *os.File
 
 type GoDiffDataStruct struct {
 	Origin          string
@@ -40,6 +41,11 @@ type GoDiffDataStruct struct {
 	wrongTypeInOrg  []string
 	wrongTypeInDest []string
 	unmatchFile     []string
+	// Manifests of the os-diff pull collections holding the directories
+	originManifest *common.Manifest
+	destManifest   *common.Manifest
+	// Where the reported paths come from, per the manifests
+	provenance map[string]*common.ManifestFile
 }
 
 func init() {
@@ -52,6 +58,7 @@ func init() {
 			DisableLevelTruncation: true,
 			PadLevelText:           true,
 		}
+		logFile = file
 		multi := io.MultiWriter(os.Stdout, file)
 		log.SetOutput(multi)
 		// log.Out = file
@@ -146,14 +153,12 @@ func checkFile(path1 string, path2 string) (bool, error) {
 		n2, err2 := file2.Read(buf2)
 		if err1 != nil || err2 != nil || n1 != n2 {
 			return false, nil
-			break
 		}
 		if n1 == 0 {
 			break
 		}
 		if string(buf1[:n1]) != string(buf2[:n2]) {
 			return false, nil
-			break
 		}
 	}
 	return true, nil
@@ -167,29 +172,42 @@ func (p *GoDiffDataStruct) Process(dir1 string, dir2 string) error {
 		report and log results.
 	*/
 	// Walk through DIR 1
-	filepath.Walk(dir1, func(path string, info os.FileInfo, err error) error {
+	return filepath.Walk(dir1, func(path string, info os.FileInfo, err error) error {
 		if err != nil {
 			return err
 		}
 		// Get the corresponding file in the second directory
 		relPath, _ := filepath.Rel(dir1, path)
 		path2 := filepath.Join(dir2, relPath)
+		if !info.IsDir() && info.Name() == common.ManifestFileName {
+			return nil
+		}
+		if relPath != "." && ignoreRules.MatchFile(relPath) {
+			log.Info("Skipping ignored path: ", path)
+			if info.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
 		file1, err := os.Stat(path)
 		if err != nil {
-			fmt.Errorf("Error in: %s, %s", path, err)
+			log.Error("Error in: ", path, " ", err)
 			return nil
 		}
 		file2, err := os.Stat(path2)
 		if err != nil {
 			if !common.StringInSlice(path, p.missingPath) {
+				source := p.missingSource(dir1, path, dir2, path2)
 				if file1.IsDir() {
 					log.Info("Directory is missing: ", path, "\n")
 					p.missingPath = append(p.missingPath, path)
+					recordMissingPath(path, source)
 					// Skip this dir if the current path is missing, no need to walk through all subdir
 					return filepath.SkipDir
 				} else {
 					log.Warn("File is missing: ", path, "\n")
 					p.missingPath = append(p.missingPath, path)
+					recordMissingPath(path, source)
 				}
 			}
 		} else {
@@ -197,12 +215,14 @@ func (p *GoDiffDataStruct) Process(dir1 string, dir2 string) error {
 				if !common.StringInSlice(path, p.wrongTypeInOrg) {
 					log.Warn("File: ", path, "and: ", path2, " have different type (directory vs file)")
 					p.wrongTypeInOrg = append(p.wrongTypeInOrg, path)
+					recordTypeMismatch(path)
 				}
 			}
 			if !file1.IsDir() && file2.IsDir() {
 				if !common.StringInSlice(path, p.wrongTypeInDest) {
 					log.Warn("File: ", path, "and: ", path2, " have different type (directory vs file)")
 					p.wrongTypeInDest = append(p.wrongTypeInDest, path2)
+					recordTypeMismatch(path2)
 				}
 			}
 			if !file1.IsDir() && !file2.IsDir() {
@@ -210,23 +230,31 @@ func (p *GoDiffDataStruct) Process(dir1 string, dir2 string) error {
 				if err != nil {
 					return err
 				}
-				if !check {
+				if check {
+					recordEqualFiles(path, path2, p.source(dir1, path), p.source(dir2, path2))
+				} else {
 					// Compare the two files
 					if !common.StringInSlice(path, p.unmatchFile) {
-						p.unmatchFile = append(p.unmatchFile, path)
 						// Set empty iniFilers
 						inifilters := []string{}
-						report, err := CompareFiles(path, path2, false, true, inifilters)
+						result, err := CompareFiles(path, path2, false, true, inifilters)
 						if err != nil {
 							return err
 						}
+						result.OriginSource = p.source(dir1, path)
+						result.DestinationSource = p.source(dir2, path2)
+						if IsUnifiedOutput() {
+							printResult(result)
+						}
 
-						if report != nil {
+						if result.HasDifferences() {
 							if !common.StringInSlice(path, p.unmatchFile) {
 								p.unmatchFile = append(p.unmatchFile, path)
+								p.setProvenance(path, result.OriginSource)
 							}
 							if !common.StringInSlice(path2, p.unmatchFile) {
 								p.unmatchFile = append(p.unmatchFile, path2)
+								p.setProvenance(path2, result.DestinationSource)
 							}
 						}
 					}
@@ -237,28 +265,116 @@ func (p *GoDiffDataStruct) Process(dir1 string, dir2 string) error {
 		}
 		return nil
 	})
-	return nil
 }
 
 func (p *GoDiffDataStruct) ProcessDirectories(reverse bool) error {
 	// Compare origin vs destination
 	log.Info("Start processing: ", p.Origin, " as source and: ", p.Destination, " as destination.")
-	p.Process(p.Origin, p.Destination)
+	p.originManifest = common.FindManifest(p.Origin)
+	p.destManifest = common.FindManifest(p.Destination)
+	if err := p.Process(p.Origin, p.Destination); err != nil {
+		return err
+	}
 	if reverse {
-		p.Process(p.Destination, p.Origin)
+		if err := p.Process(p.Destination, p.Origin); err != nil {
+			return err
+		}
+	}
+	if IsJSONOutput() {
+		return nil
+	}
+	// Keep stdout a valid patch with the unified output
+	out := os.Stdout
+	if IsUnifiedOutput() {
+		out = os.Stderr
 	}
-	fmt.Printf("\n**** Report ****\n")
+	fmt.Fprintf(out, "\n**** Report ****\n")
 	if len(p.missingPath) > 0 {
-		fmt.Printf("\n**** Missing files or directories ****\n")
-		fmt.Println(strings.Join(p.missingPath, "\n"))
+		fmt.Fprintf(out, "\n**** Missing files or directories ****\n")
+		p.printPaths(out, p.missingPath)
 	}
 	if len(p.unmatchFile) > 0 {
-		fmt.Printf("\n**** Files with differences ****\n")
-		fmt.Println(strings.Join(p.unmatchFile, "\n"))
+		fmt.Fprintf(out, "\n**** Files with differences ****\n")
+		p.printPaths(out, p.unmatchFile)
 	}
 	if len(p.wrongTypeInOrg) > 0 {
-		fmt.Printf("\n**** Different file type in origin ****\n")
-		fmt.Println(strings.Join(p.wrongTypeInOrg, "\n"))
+		fmt.Fprintf(out, "\n**** Different file type in origin ****\n")
+		fmt.Fprintln(out, strings.Join(p.wrongTypeInOrg, "\n"))
 	}
 	return nil
 }
+
+func recordEqualFiles(path1 string, path2 string, source1 *common.ManifestFile, source2 *common.ManifestFile) {
+	if !IsJSONOutput() {
+		return
+	}
+	content, err := os.ReadFile(path1)
+	if err != nil {
+		log.Error("Failed to read file: ", path1, " error: ", err)
+		return
+	}
+	result := NewResult(path1, path2, DetectFormat(path1, content))
+	result.OriginSource = source1
+	result.DestinationSource = source2
+	RecordResult(result, common.DetectType(content))
+}
+
+// manifest returns the manifest of the compared directory dir, nil
+// without manifest.
+func (p *GoDiffDataStruct) manifest(dir string) *common.Manifest {
+	if dir == p.Origin {
+		return p.originManifest
+	}
+	return p.destManifest
+}
+
+// source returns the manifest entry of a path of the compared directory dir.
+func (p *GoDiffDataStruct) source(dir string, path string) *common.ManifestFile {
+	if manifest := p.manifest(dir); manifest != nil {
+		return manifest.Lookup(path)
+	}
+	return nil
+}
+
+// missingSource returns why path2 is missing when its collection failed,
+// else where path comes from.
+func (p *GoDiffDataStruct) missingSource(dir1 string, path string, dir2 string, path2 string) *common.ManifestFile {
+	var source *common.ManifestFile
+	if manifest := p.manifest(dir2); manifest != nil {
+		source = manifest.Failure(path2)
+	}
+	if source == nil {
+		source = p.source(dir1, path)
+	}
+	p.setProvenance(path, source)
+	return source
+}
+
+func (p *GoDiffDataStruct) setProvenance(path string, source *common.ManifestFile) {
+	if source == nil {
+		return
+	}
+	if p.provenance == nil {
+		p.provenance = map[string]*common.ManifestFile{}
+	}
+	p.provenance[path] = source
+}
+
+// printPaths prints the reported paths with where they have been collected
+// from.
+func (p *GoDiffDataStruct) printPaths(out io.Writer, paths []string) {
+	for _, path := range paths {
+		fmt.Fprintln(out, path)
+		if source, ok := p.provenance[path]; ok {
+			if source.Error != "" {
+				fmt.Fprintf(out, "    failed to collect: %s\n", source.Describe())
+			} else {
+				fmt.Fprintf(out, "    collected from: %s\n", source.Describe())
+			}
+		}
+	}
+}
+
+func (p *GoDiffDataStruct) HasDifferences() bool {
+	return len(p.missingPath) > 0 || len(p.unmatchFile) > 0 || len(p.wrongTypeInOrg) > 0 || len(p.wrongTypeInDest) > 0
+}
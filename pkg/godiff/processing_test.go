@@ -17,9 +17,13 @@
 package godiff_test
 
 import (
+	"bytes"
+	"encoding/json"
 	"os"
+	"path/filepath"
 	"testing"
 
+	"github.com/openstack-k8s-operators/os-diff/pkg/common"
 	"github.com/openstack-k8s-operators/os-diff/pkg/godiff"
 	"github.com/stretchr/testify/assert"
 )
@@ -56,3 +60,42 @@ func TestFilesEqual(t *testing.T) {
 	assert.NoError(t, err)
 	assert.False(t, result)
 }
+
+func TestProcessDirectoriesProvenance(t *testing.T) {
+	tripleo := t.TempDir()
+	ocp := t.TempDir()
+	assert.NoError(t, os.MkdirAll(filepath.Join(tripleo, "nova", "etc", "nova"), 0755))
+	assert.NoError(t, os.MkdirAll(filepath.Join(ocp, "nova", "etc", "nova"), 0755))
+	assert.NoError(t, os.WriteFile(filepath.Join(tripleo, "nova", "etc", "nova", "nova.conf"), []byte("[DEFAULT]\ndebug=True\n"), 0644))
+	assert.NoError(t, os.WriteFile(filepath.Join(ocp, "nova", "etc", "nova", "nova.conf"), []byte("[DEFAULT]\ndebug=False\n"), 0644))
+	assert.NoError(t, os.WriteFile(filepath.Join(tripleo, "nova", "etc", "nova", "api-paste.ini"), []byte("[composite:osapi]\n"), 0644))
+	assert.NoError(t, common.WriteManifest(tripleo, &common.Manifest{Version: 1, Cloud: "tripleo", Files: []common.ManifestFile{
+		{Path: "nova/etc/nova/nova.conf", Service: "nova", Host: "standalone", PodmanId: "0123456789ab", Image: "nova-api:17.1"},
+	}}))
+	assert.NoError(t, common.WriteManifest(ocp, &common.Manifest{Version: 1, Cloud: "ocp", Files: []common.ManifestFile{
+		{Path: "nova/etc/nova/nova.conf", Service: "nova", Pod: "nova-api-0", Container: "nova-api"},
+		{Path: "nova/etc/nova/api-paste.ini", Service: "nova", Pod: "nova-api-0", Error: "failed to copy"},
+	}}))
+
+	assert.NoError(t, godiff.SetOutputFormat(godiff.JSONOutput))
+	defer godiff.SetOutputFormat(godiff.TextOutput)
+	goDiff := &godiff.GoDiffDataStruct{Origin: filepath.Join(tripleo, "nova"), Destination: filepath.Join(ocp, "nova")}
+	assert.NoError(t, goDiff.ProcessDirectories(false))
+	assert.True(t, goDiff.HasDifferences())
+
+	var buf bytes.Buffer
+	assert.NoError(t, godiff.WriteJSONReport(&buf))
+	var report godiff.DiffReport
+	assert.NoError(t, json.Unmarshal(buf.Bytes(), &report))
+	file := report.Files[len(report.Files)-1]
+	if assert.NotNil(t, file.OriginSource) && assert.NotNil(t, file.DestinationSource) {
+		assert.Equal(t, "0123456789ab", file.OriginSource.PodmanId)
+		assert.Equal(t, "nova-api-0", file.DestinationSource.Pod)
+	}
+	// The other side failed to collect the missing file
+	missing := filepath.Join(tripleo, "nova", "etc", "nova", "api-paste.ini")
+	assert.Contains(t, report.MissingPaths, missing)
+	if assert.NotNil(t, report.MissingSources[missing]) {
+		assert.Equal(t, "failed to copy", report.MissingSources[missing].Error)
+	}
+}
@@ -0,0 +1,365 @@
+/*
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * Copyright 2023 Red Hat, Inc.
+ *
+ */
+package godiff
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// rabbitmqSetting is a key = value line of a rabbitmq.conf file.
+type rabbitmqSetting struct {
+	key   string
+	value string
+	line  int
+}
+
+// rabbitmq.conf keys: listeners.tcp.default, cluster_formation.peer_discovery_backend...
+var rabbitmqKey = regexp.MustCompile(`^[A-Za-z0-9_$.-]+$`)
+
+// Top level keys found in the rabbitmq.conf files, used to detect them.
+var rabbitmqTopKeys = map[string]bool{
+	"listeners": true, "loopback_users": true, "cluster_formation": true,
+	"cluster_name": true, "cluster_partition_handling": true, "ssl_options": true,
+	"management": true, "default_user": true, "default_pass": true,
+	"default_vhost": true, "vm_memory_high_watermark": true, "disk_free_limit": true,
+	"log": true, "heartbeat": true, "collect_statistics_interval": true,
+	"auth_mechanisms": true, "queue_master_locator": true, "prometheus": true,
+	"num_acceptors": true, "channel_max": true, "total_memory_available_override_value": true,
+}
+
+// CompareRabbitmqConf compares rabbitmq.conf files (sysctl format) key by
+// key, the last value of a key wins.
+func CompareRabbitmqConf(origin []byte, dest []byte) (*Result, error) {
+	settings1, err := parseRabbitmqConf(origin)
+	if err != nil {
+		return nil, fmt.Errorf("Error parsing rabbitmq.conf: %s", err)
+	}
+	settings2, err := parseRabbitmqConf(dest)
+	if err != nil {
+		return nil, fmt.Errorf("Error parsing rabbitmq.conf: %s", err)
+	}
+	result := NewResult("", "", "rabbitmq")
+	result.setContent(origin, dest)
+	byKey1, byKey2 := rabbitmqByKey(settings1), rabbitmqByKey(settings2)
+	for _, s := range settings1 {
+		s2, ok := byKey2[s.key]
+		switch {
+		case byKey1[s.key].line != s.line:
+			// Overridden by a later line
+		case !ok:
+			result.Add(Entry{Kind: Removed, Path: s.key, OldValue: s.value, OldLine: s.line})
+		case s.value != s2.value:
+			result.Add(Entry{Kind: Changed, Path: s.key, OldValue: s.value, NewValue: s2.value, OldLine: s.line, NewLine: s2.line})
+		}
+	}
+	for _, s := range settings2 {
+		if _, ok := byKey1[s.key]; !ok && byKey2[s.key].line == s.line {
+			result.Add(Entry{Kind: Added, Path: s.key, NewValue: s.value, NewLine: s.line})
+		}
+	}
+	return result, nil
+}
+
+// IsRabbitmqConf returns true if every line of the content is a comment or
+// a key = value setting, with at least one well known RabbitMQ key.
+func IsRabbitmqConf(data []byte) bool {
+	settings, err := parseRabbitmqConf(data)
+	if err != nil {
+		return false
+	}
+	for _, s := range settings {
+		top, _, _ := strings.Cut(s.key, ".")
+		if rabbitmqTopKeys[top] {
+			return true
+		}
+	}
+	return false
+}
+
+func rabbitmqByKey(settings []rabbitmqSetting) map[string]rabbitmqSetting {
+	byKey := map[string]rabbitmqSetting{}
+	for _, s := range settings {
+		byKey[s.key] = s
+	}
+	return byKey
+}
+
+func parseRabbitmqConf(data []byte) ([]rabbitmqSetting, error) {
+	var settings []rabbitmqSetting
+	for i, line := range splitLines(data) {
+		line = strings.TrimSpace(line)
+		if line == "" || line[0] == '#' {
+			continue
+		}
+		key, value, ok := strings.Cut(line, "=")
+		key = strings.TrimSpace(key)
+		if !ok || !rabbitmqKey.MatchString(key) {
+			return nil, fmt.Errorf("line %d: not a key = value setting: %s", i+1, line)
+		}
+		settings = append(settings, rabbitmqSetting{key: key, value: strings.TrimSpace(value), line: i + 1})
+	}
+	return settings, nil
+}
+
+// CompareErlangConfig compares Erlang term configuration files like the
+// legacy rabbitmq.config or advanced.config. The property lists ({Key,
+// Value} tuples) are compared as JSON objects, so the differences are
+// reported at their path, e.g. rabbit.tcp_listeners.
+func CompareErlangConfig(origin []byte, dest []byte) (*Result, error) {
+	term1, err := parseErlangConfig(origin)
+	if err != nil {
+		return nil, fmt.Errorf("Error parsing Erlang configuration: %s", err)
+	}
+	term2, err := parseErlangConfig(dest)
+	if err != nil {
+		return nil, fmt.Errorf("Error parsing Erlang configuration: %s", err)
+	}
+	entries, err := CompareJSON(term1, term2, "")
+	if err != nil {
+		return nil, err
+	}
+	result := NewResult("", "", "erlang")
+	result.setContent(origin, dest)
+	result.Entries = append(result.Entries, entries...)
+	return result, nil
+}
+
+// IsErlangConfig returns true for a single Erlang list term ended by a dot.
+func IsErlangConfig(data []byte) bool {
+	term, err := parseErlangConfig(data)
+	if err != nil {
+		return false
+	}
+	switch term.(type) {
+	case map[string]interface{}, []interface{}:
+		return true
+	}
+	return false
+}
+
+// erlangParser reads the Erlang terms: lists, tuples, atoms, strings,
+// binaries and numbers, the % comments are skipped.
+type erlangParser struct {
+	data []byte
+	pos  int
+}
+
+func parseErlangConfig(data []byte) (interface{}, error) {
+	p := &erlangParser{data: data}
+	term, err := p.term()
+	if err != nil {
+		return nil, err
+	}
+	if p.skipSpaces(); p.peek() != '.' {
+		return nil, p.errorf("missing the final dot")
+	}
+	p.pos++
+	if p.skipSpaces(); p.pos < len(p.data) {
+		return nil, p.errorf("unexpected content after the final dot")
+	}
+	return term, nil
+}
+
+func (p *erlangParser) errorf(format string, args ...interface{}) error {
+	line := strings.Count(string(p.data[:p.pos]), "\n") + 1
+	return fmt.Errorf("line %d: %s", line, fmt.Sprintf(format, args...))
+}
+
+func (p *erlangParser) peek() byte {
+	if p.pos < len(p.data) {
+		return p.data[p.pos]
+	}
+	return 0
+}
+
+func (p *erlangParser) skipSpaces() {
+	for p.pos < len(p.data) {
+		switch c := p.data[p.pos]; {
+		case c == '%':
+			for p.pos < len(p.data) && p.data[p.pos] != '\n' {
+				p.pos++
+			}
+		case c == ' ' || c == '\t' || c == '\r' || c == '\n':
+			p.pos++
+		default:
+			return
+		}
+	}
+}
+
+func (p *erlangParser) term() (interface{}, error) {
+	p.skipSpaces()
+	switch c := p.peek(); {
+	case c == '[':
+		p.pos++
+		items, err := p.sequence(']')
+		if err != nil {
+			return nil, err
+		}
+		return erlangList(items), nil
+	case c == '{':
+		p.pos++
+		return p.sequence('}')
+	case c == '<' && strings.HasPrefix(string(p.data[p.pos:]), "<<"):
+		p.pos += 2
+		p.skipSpaces()
+		value := ""
+		if p.peek() == '"' {
+			s, err := p.quoted('"')
+			if err != nil {
+				return nil, err
+			}
+			value = s
+		}
+		if p.skipSpaces(); !strings.HasPrefix(string(p.data[p.pos:]), ">>") {
+			return nil, p.errorf("unterminated binary")
+		}
+		p.pos += 2
+		return value, nil
+	case c == '"' || c == '\'':
+		return p.quoted(c)
+	case c == '-' || (c >= '0' && c <= '9'):
+		return p.number()
+	case c >= 'a' && c <= 'z':
+		start := p.pos
+		for p.pos < len(p.data) && erlangAtomChar(p.data[p.pos]) {
+			p.pos++
+		}
+		switch atom := string(p.data[start:p.pos]); atom {
+		case "true":
+			return true, nil
+		case "false":
+			return false, nil
+		default:
+			return atom, nil
+		}
+	case c == 0:
+		return nil, p.errorf("unexpected end of the content")
+	}
+	return nil, p.errorf("unexpected %q", p.peek())
+}
+
+// sequence reads the comma separated terms of a list or a tuple.
+func (p *erlangParser) sequence(end byte) ([]interface{}, error) {
+	items := []interface{}{}
+	if p.skipSpaces(); p.peek() == end {
+		p.pos++
+		return items, nil
+	}
+	for {
+		item, err := p.term()
+		if err != nil {
+			return nil, err
+		}
+		items = append(items, item)
+		p.skipSpaces()
+		switch p.peek() {
+		case ',':
+			p.pos++
+		case end:
+			p.pos++
+			return items, nil
+		default:
+			return nil, p.errorf("expected ',' or %q", end)
+		}
+	}
+}
+
+func (p *erlangParser) quoted(quote byte) (string, error) {
+	var b strings.Builder
+	for p.pos++; p.pos < len(p.data); p.pos++ {
+		c := p.data[p.pos]
+		switch {
+		case c == quote:
+			p.pos++
+			return b.String(), nil
+		case c == '\\' && p.pos+1 < len(p.data):
+			p.pos++
+			switch e := p.data[p.pos]; e {
+			case 'n':
+				b.WriteByte('\n')
+			case 't':
+				b.WriteByte('\t')
+			default:
+				b.WriteByte(e)
+			}
+		default:
+			b.WriteByte(c)
+		}
+	}
+	return "", p.errorf("unterminated %c quote", quote)
+}
+
+func (p *erlangParser) number() (interface{}, error) {
+	start := p.pos
+	if p.peek() == '-' {
+		p.pos++
+	}
+	for p.pos < len(p.data) && strings.IndexByte("0123456789.eE#abcdefABCDEF_", p.data[p.pos]) >= 0 {
+		if p.data[p.pos] == '.' && (p.pos+1 >= len(p.data) || p.data[p.pos+1] < '0' || p.data[p.pos+1] > '9') {
+			// Final dot of the term
+			break
+		}
+		p.pos++
+	}
+	text := strings.ReplaceAll(string(p.data[start:p.pos]), "_", "")
+	if base, digits, ok := strings.Cut(text, "#"); ok {
+		b, err := strconv.Atoi(strings.TrimPrefix(base, "-"))
+		if err == nil {
+			if n, err := strconv.ParseInt(digits, b, 64); err == nil {
+				if strings.HasPrefix(base, "-") {
+					n = -n
+				}
+				return float64(n), nil
+			}
+		}
+	} else if n, err := strconv.ParseFloat(text, 64); err == nil {
+		return n, nil
+	}
+	return nil, p.errorf("invalid number %s", text)
+}
+
+func erlangAtomChar(c byte) bool {
+	return c == '_' || c == '@' || (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z') || (c >= '0' && c <= '9')
+}
+
+// erlangList returns a property list, a list of {atom, Value} tuples, as a
+// map. Other lists are kept as they are.
+func erlangList(items []interface{}) interface{} {
+	if len(items) == 0 {
+		return items
+	}
+	proplist := map[string]interface{}{}
+	for _, item := range items {
+		tuple, ok := item.([]interface{})
+		if !ok || len(tuple) != 2 {
+			return items
+		}
+		key, ok := tuple[0].(string)
+		if !ok {
+			return items
+		}
+		if _, dup := proplist[key]; dup {
+			return items
+		}
+		proplist[key] = tuple[1]
+	}
+	return proplist
+}
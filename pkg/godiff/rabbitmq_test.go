@@ -0,0 +1,101 @@
+/*
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * Copyright 2023 Red Hat, Inc.
+ *
+ */
+package godiff_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/openstack-k8s-operators/os-diff/pkg/godiff"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCompareRabbitmqConf(t *testing.T) {
+	origin := []byte(`# RabbitMQ configuration
+listeners.tcp.default = 5672
+loopback_users.guest = false
+cluster_formation.peer_discovery_backend = classic_config
+cluster_formation.classic_config.nodes.1 = rabbit@controller-0
+`)
+	dest := []byte(`listeners.ssl.default = 5671
+loopback_users.guest = false
+cluster_formation.peer_discovery_backend = rabbit_peer_discovery_k8s
+`)
+	result, err := godiff.CompareRabbitmqConf(origin, dest)
+	assert.NoError(t, err)
+	assert.Equal(t, []godiff.Entry{
+		{Kind: godiff.Removed, Path: "listeners.tcp.default", OldValue: "5672", OldLine: 2},
+		{Kind: godiff.Changed, Path: "cluster_formation.peer_discovery_backend", OldValue: "classic_config", NewValue: "rabbit_peer_discovery_k8s", OldLine: 4, NewLine: 3},
+		{Kind: godiff.Removed, Path: "cluster_formation.classic_config.nodes.1", OldValue: "rabbit@controller-0", OldLine: 5},
+		{Kind: godiff.Added, Path: "listeners.ssl.default", NewValue: "5671", NewLine: 1},
+	}, result.Entries)
+	assert.Contains(t, result.Report(), "-listeners.tcp.default = 5672\n")
+
+	assert.True(t, godiff.IsRabbitmqConf(origin))
+	assert.False(t, godiff.IsRabbitmqConf([]byte("a = 1\n")))
+	assert.False(t, godiff.IsRabbitmqConf([]byte("[DEFAULT]\nlog = 1\n")))
+}
+
+func TestCompareErlangConfig(t *testing.T) {
+	origin := []byte(`% Legacy RabbitMQ configuration
+[
+  {rabbit, [
+    {tcp_listeners, [{"172.17.0.10", 5672}]},
+    {loopback_users, []},
+    {cluster_partition_handling, ignore},
+    {default_user, <<"guest">>}
+  ]},
+  {rabbitmq_management, [{listener, [{port, 15672}, {ssl, false}]}]}
+].
+`)
+	dest := []byte(`[{rabbit, [{tcp_listeners, [{"172.17.0.10", 5672}]},
+            {loopback_users, []},
+            {cluster_partition_handling, pause_minority},
+            {default_user, <<"guest">>},
+            {collect_statistics_interval, 30000}]},
+ {rabbitmq_management, [{listener, [{ssl, false}, {port, 15671}]}]}].
+`)
+	result, err := godiff.CompareErlangConfig(origin, dest)
+	assert.NoError(t, err)
+	assert.Equal(t, "erlang", result.Format)
+	assert.Equal(t, []godiff.Entry{
+		{Kind: godiff.Changed, Path: "rabbit.cluster_partition_handling", OldValue: "ignore", NewValue: "pause_minority"},
+		{Kind: godiff.Added, Path: "rabbit.collect_statistics_interval", NewValue: "30000"},
+		{Kind: godiff.Changed, Path: "rabbitmq_management.listener.port", OldValue: "15672", NewValue: "15671"},
+	}, result.Entries)
+
+	assert.True(t, godiff.IsErlangConfig(origin))
+	assert.False(t, godiff.IsErlangConfig([]byte(`[{"a": 1}]`)))
+	assert.False(t, godiff.IsErlangConfig([]byte("[{rabbit, []}]\n")))
+}
+
+func TestRabbitmqSecretsRedaction(t *testing.T) {
+	result, err := godiff.CompareRabbitmqConf([]byte("default_user = guest\ndefault_pass = secret1\n"), []byte("default_user = guest\ndefault_pass = secret2\n"))
+	assert.NoError(t, err)
+	report := strings.Join(result.Report(), "")
+	assert.Contains(t, report, "default_pass = <redacted:")
+	assert.NotContains(t, report, "secret1")
+	assert.NotContains(t, report, "secret2")
+
+	result, err = godiff.CompareErlangConfig([]byte("[{rabbit, [{default_pass, <<\"secret1\">>}]}].\n"), []byte("[{rabbit, [{default_pass, <<\"secret2\">>}]}].\n"))
+	assert.NoError(t, err)
+	report = strings.Join(result.Report(), "")
+	assert.Contains(t, report, "<redacted:")
+	assert.NotContains(t, report, "secret1")
+	assert.NotContains(t, report, "secret2")
+	assert.NotContains(t, result.Redacted().Entries[0].OldValue, "secret1")
+}
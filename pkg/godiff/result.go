@@ -0,0 +1,368 @@
+/*
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * Copyright 2023 Red Hat, Inc.
+ *
+ */
+package godiff
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/openstack-k8s-operators/os-diff/pkg/common"
+)
+
+// Kind of difference found between origin and destination.
+type EntryKind string
+
+const (
+	// Added: only present in the destination
+	Added EntryKind = "added"
+	// Removed: only present in the origin
+	Removed EntryKind = "removed"
+	// Changed: present in both with a different value
+	Changed EntryKind = "changed"
+)
+
+// Entry describes a single difference.
+// Section is only set for INI files, Path holds the INI key name or the
+// JSON/YAML path of the value. Line numbers are 0 when unknown.
+// Reason is only set for the expected and default value differences.
+type Entry struct {
+	Kind     EntryKind `json:"kind"`
+	Section  string    `json:"section,omitempty"`
+	Path     string    `json:"path,omitempty"`
+	OldValue string    `json:"old_value,omitempty"`
+	NewValue string    `json:"new_value,omitempty"`
+	OldLine  int       `json:"old_line,omitempty"`
+	NewLine  int       `json:"new_line,omitempty"`
+	Reason   string    `json:"reason,omitempty"`
+}
+
+// Result holds every difference found between two files, the differences
+// matching the ignore rules are kept apart in Ignored and the ones listed in
+// the catalog of expected differences in Expected, and the options set to
+// their default value on one side only in Defaults.
+type Result struct {
+	Origin      string  `json:"origin"`
+	Destination string  `json:"destination"`
+	Format      string  `json:"format"`
+	Entries     []Entry `json:"entries"`
+	Ignored     []Entry `json:"ignored,omitempty"`
+	Expected    []Entry `json:"expected,omitempty"`
+	Defaults    []Entry `json:"defaults,omitempty"`
+	// Where the files have been collected from, per the os-diff pull
+	// manifests
+	OriginSource      *common.ManifestFile `json:"origin_source,omitempty"`
+	DestinationSource *common.ManifestFile `json:"destination_source,omitempty"`
+	// Compared contents, used to render the hunks
+	oldText []string
+	newText []string
+	// The last line of the contents has no line break
+	oldNoEOL bool
+	newNoEOL bool
+	// Last line of the values spanning several lines, by first line
+	oldSpans map[int]int
+	newSpans map[int]int
+}
+
+func NewResult(origin string, dest string, format string) *Result {
+	return &Result{
+		Origin:      origin,
+		Destination: dest,
+		Format:      format,
+		Entries:     []Entry{},
+	}
+}
+
+func (r *Result) Add(entry Entry) {
+	r.Entries = append(r.Entries, entry)
+}
+
+func (r *Result) setContent(oldData []byte, newData []byte) {
+	r.oldText = splitLines(oldData)
+	r.newText = splitLines(newData)
+	r.oldNoEOL = noFinalNewline(oldData)
+	r.newNoEOL = noFinalNewline(newData)
+}
+
+// setSpan records the last lines of a value starting at the old and new
+// lines, 0 when the value is not on that side.
+func (r *Result) setSpan(oldLine int, newLine int, oldEnd int, newEnd int) {
+	if r.oldSpans == nil {
+		r.oldSpans = map[int]int{}
+		r.newSpans = map[int]int{}
+	}
+	if oldLine > 0 && oldEnd > oldLine {
+		r.oldSpans[oldLine] = oldEnd
+	}
+	if newLine > 0 && newEnd > newLine {
+		r.newSpans[newLine] = newEnd
+	}
+}
+
+// anchorEdit returns true if the change of the edit script is reported by
+// one of the entries.
+func (r *Result) anchorEdit() func(lineEdit) bool {
+	oldLines := map[int]bool{}
+	newLines := map[int]bool{}
+	for _, e := range r.Entries {
+		for line := e.OldLine; line > 0 && (line == e.OldLine || line <= r.oldSpans[e.OldLine]); line++ {
+			oldLines[line] = true
+		}
+		for line := e.NewLine; line > 0 && (line == e.NewLine || line <= r.newSpans[e.NewLine]); line++ {
+			newLines[line] = true
+		}
+	}
+	return func(edit lineEdit) bool {
+		if edit.op == opDelete {
+			return oldLines[edit.oldLine]
+		}
+		return newLines[edit.newLine]
+	}
+}
+
+func (r *Result) HasDifferences() bool {
+	return r != nil && len(r.Entries) > 0
+}
+
+// Report renders the result as the "+"/"-" text used for the console
+// output and the .diff files. Lines starting with "-" come from the origin,
+// lines starting with "+" from the destination. Secrets are redacted unless
+// SetShowSecrets has been called.
+func (r *Result) Report() []string {
+	var report []string
+	if !r.HasDifferences() {
+		return report
+	}
+	r = r.Redacted()
+	if r.Origin != "" || r.Destination != "" {
+		report = append(report, fmt.Sprintf("Source file path: %s, difference with: %s\n", r.Origin, r.Destination))
+	}
+	switch r.Format {
+	case "ini", "mycnf":
+		report = append(report, r.iniReport()...)
+	case "raw":
+		report = append(report, r.rawReport()...)
+	case "env", "rabbitmq":
+		report = append(report, r.keyValueReport()...)
+	default:
+		report = append(report, r.treeReport()...)
+	}
+	return report
+}
+
+// ExpectedReport lists the differences found in the catalog of expected
+// differences, they are not part of Report.
+func (r *Result) ExpectedReport() []string {
+	var report []string
+	if r == nil {
+		return report
+	}
+	for _, e := range r.Expected {
+		report = append(report, fmt.Sprintf("Expected difference: [%s] %s (%s)\n", e.Section, e.Path, e.Reason))
+	}
+	return report
+}
+
+// DefaultsReport lists the options only set on one side, to their default
+// value, they are not part of Report.
+func (r *Result) DefaultsReport() []string {
+	var report []string
+	if r == nil {
+		return report
+	}
+	for _, e := range r.Redacted().Defaults {
+		if e.Kind == Removed {
+			report = append(report, fmt.Sprintf("Default value: [%s] %s=%s only set in origin\n", e.Section, e.Path, e.OldValue))
+		} else {
+			report = append(report, fmt.Sprintf("Default value: [%s] %s=%s only set in destination\n", e.Section, e.Path, e.NewValue))
+		}
+	}
+	return report
+}
+
+func (r *Result) iniReport() []string {
+	var report []string
+	var msg string
+	currentSection := ""
+	headerDone := false
+	for _, e := range r.Entries {
+		if e.Section != currentSection || !headerDone {
+			currentSection = e.Section
+			headerDone = true
+			if e.Path == "" {
+				// The whole section is missing on one side
+				report = append(report, fmt.Sprintf("%s[%s]\n", sign(e.Kind), e.Section))
+				continue
+			}
+			msg = fmt.Sprintf("[%s]\n", e.Section)
+			if e.Section == "" {
+				// my.cnf include directives before the first group
+				msg = ""
+			}
+		} else {
+			msg = ""
+		}
+		switch e.Kind {
+		case Removed:
+			msg += "-" + r.entryLine(e, e.OldValue) + "\n"
+		case Added:
+			msg += "+" + r.entryLine(e, e.NewValue) + "\n"
+		case Changed:
+			msg += "-" + r.entryLine(e, e.OldValue) + "\n+" + r.entryLine(e, e.NewValue) + "\n"
+		}
+		report = append(report, msg)
+	}
+	return report
+}
+
+// UnifiedDiff renders the result as a unified diff which can be applied
+// with patch. When the compared contents are known, the hunks only hold the
+// changed lines reported by the entries, with context lines. Otherwise a
+// single hunk lists the old and new values.
+func (r *Result) UnifiedDiff() []string {
+	var diff []string
+	if !r.HasDifferences() {
+		return diff
+	}
+	r = r.Redacted()
+	diff = append(diff, fmt.Sprintf("--- %s\n", r.Origin), fmt.Sprintf("+++ %s\n", r.Destination))
+	if r.oldText != nil {
+		return append(diff, r.hunks()...)
+	}
+	var oldLines, newLines []string
+	for _, e := range r.Entries {
+		if e.Kind != Added {
+			oldLines = append(oldLines, "-"+r.entryLine(e, e.OldValue))
+		}
+		if e.Kind != Removed {
+			newLines = append(newLines, "+"+r.entryLine(e, e.NewValue))
+		}
+	}
+	h := hunk{oldStart: 1, oldCount: len(oldLines), newStart: 1, newCount: len(newLines), lines: append(oldLines, newLines...)}
+	if h.oldCount == 0 {
+		h.oldStart = 0
+	}
+	if h.newCount == 0 {
+		h.newStart = 0
+	}
+	diff = append(diff, h.header()+"\n")
+	for _, line := range h.lines {
+		diff = append(diff, line+"\n")
+	}
+	return diff
+}
+
+func (r *Result) entryLine(e Entry, value string) string {
+	switch {
+	case (r.Format == "ini" || r.Format == "mycnf") && e.Path == "":
+		return "[" + e.Section + "]"
+	case r.Format == "mycnf" && strings.HasPrefix(e.Path, "!"):
+		// !include and !includedir directives
+		return e.Path + " " + value
+	case r.Format == "mycnf" && value == "":
+		// Options without value like skip-name-resolve
+		return e.Path
+	case r.Format == "ini" || r.Format == "mycnf" || r.Format == "env":
+		return e.Path + "=" + value
+	case r.Format == "rabbitmq":
+		return e.Path + " = " + value
+	case r.Format == "raw":
+		return value
+	}
+	return e.Path + ": " + value
+}
+
+// hunks renders the line diff of the compared contents. Only the changes
+// reported by the entries start a hunk, unless an entry has no line number.
+func (r *Result) hunks() []string {
+	var lines []string
+	anchor := r.anchorEdit()
+	for _, e := range r.Entries {
+		if e.OldLine == 0 && e.NewLine == 0 {
+			anchor = func(lineEdit) bool { return true }
+			break
+		}
+	}
+	edits := diffLines(r.oldText, r.newText)
+	for _, h := range buildHunks(edits, r.oldText, r.newText, r.oldNoEOL, r.newNoEOL, hunkContext, anchor) {
+		lines = append(lines, h.header()+"\n")
+		for _, line := range h.lines {
+			lines = append(lines, line+"\n")
+		}
+	}
+	return lines
+}
+
+// rawReport renders the differences as unified hunks with context lines,
+// or as the changed lines when the compared contents are not known.
+func (r *Result) rawReport() []string {
+	var report []string
+	if r.oldText != nil {
+		return r.hunks()
+	}
+	lastLine := -1
+	for _, e := range r.Entries {
+		line := e.OldLine
+		value := e.OldValue
+		if e.Kind == Added {
+			line = e.NewLine
+			value = e.NewValue
+		}
+		if line != lastLine {
+			report = append(report, fmt.Sprintf("@ line: %d\n", line))
+			lastLine = line
+		}
+		report = append(report, fmt.Sprintf("%s%s\n", sign(e.Kind), value))
+	}
+	return report
+}
+
+func (r *Result) treeReport() []string {
+	var report []string
+	for _, e := range r.Entries {
+		switch e.Kind {
+		case Removed:
+			report = append(report, fmt.Sprintf("-%s: %s\n", e.Path, e.OldValue))
+		case Added:
+			report = append(report, fmt.Sprintf("+%s: %s\n", e.Path, e.NewValue))
+		case Changed:
+			report = append(report, fmt.Sprintf("-%s: %s\n+%s: %s\n", e.Path, e.OldValue, e.Path, e.NewValue))
+		}
+	}
+	return report
+}
+
+func (r *Result) keyValueReport() []string {
+	var report []string
+	for _, e := range r.Entries {
+		switch e.Kind {
+		case Removed:
+			report = append(report, "-"+r.entryLine(e, e.OldValue)+"\n")
+		case Added:
+			report = append(report, "+"+r.entryLine(e, e.NewValue)+"\n")
+		case Changed:
+			report = append(report, "-"+r.entryLine(e, e.OldValue)+"\n+"+r.entryLine(e, e.NewValue)+"\n")
+		}
+	}
+	return report
+}
+
+func sign(kind EntryKind) string {
+	if kind == Added {
+		return "+"
+	}
+	return "-"
+}
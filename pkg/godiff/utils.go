@@ -20,311 +20,296 @@ import (
 	"fmt"
 	"io/ioutil"
 	"os/exec"
-	"reflect"
 	"strings"
 
 	"github.com/go-ini/ini"
 	"github.com/openstack-k8s-operators/os-diff/pkg/common"
-	"gopkg.in/yaml.v3"
 )
 
-func CompareYAML(origin []byte, dest []byte) ([]string, error) {
+// Repeated keys (oslo.config MultiStrOpt) are kept as shadows, with their
+// duplicated values. '#' is part of the values, like oslo.config and
+// paste-deploy (egg:keystone#public_service) do.
+var iniLoadOptions = ini.LoadOptions{AllowShadows: true, AllowDuplicateShadowValues: true, IgnoreInlineComment: true}
 
-	var report []string
-	var map1, map2 map[interface{}]interface{}
-	err := yaml.Unmarshal(origin, &map1)
-	if err != nil {
-		return nil, fmt.Errorf("Error unmarshalling %s, error: %s", origin, err)
-	}
-	err = yaml.Unmarshal(dest, &map2)
-	if err != nil {
-		return nil, fmt.Errorf("Error unmarshalling %s, error: %s", dest, err)
-	}
-	// Compare the maps and print the differences
-	msg := string("")
-	if !reflect.DeepEqual(map1, map2) {
-		//printMapDiff(map1, map2)
-		for key, val1 := range map1 {
-			val2, isEqual := map2[key]
-			if !isEqual {
-				msg = fmt.Sprintf("+%v: %v\n", key, val1)
-				report = append(report, msg)
-			} else if !reflect.DeepEqual(val1, val2) {
-				// data1, _ := yaml.Marshal(&val1)
-				// data2, _ := yaml.Marshal(&val2)
-				// msg = fmt.Sprintf("+%v: %s\n-%v:%s\n", key, data1, key, data2)
-				data, _ := yaml.Marshal(&map1)
-				ioutil.WriteFile("/tmp/test.yaml", data, 0644)
-				msg = fmt.Sprintf("+%v: %s\n-%v:%s\n", key, val1, key, val2)
-				report = append(report, msg)
-			}
-		}
-		// Loop through the keys in map2 and check for any missing keys
-		for key := range map2 {
-			_, isEqual := map1[key]
-			if !isEqual {
-				msg = fmt.Sprintf("-%v: %v\n", key, map2[key])
-				report = append(report, msg)
-			}
-		}
-	}
-	return report, nil
-}
-
-func CompareJSON(orgData, destData interface{}, path string) ([]string, error) {
-	if reflect.TypeOf(orgData) != reflect.TypeOf(destData) {
-		//fmt.Println("Type mismatch at %s: %T != %T\n", path, orgData, destData)
-		return nil, fmt.Errorf("Type mismatch at %s: %T != %T\n", path, orgData, destData)
-	}
+var multiValuesOrdered = false
 
-	var diff []string
-	msg := string("")
-	switch orgData := orgData.(type) {
-	case map[string]interface{}:
-		destData := destData.(map[string]interface{})
-		for key, value := range orgData {
-			if value2, ok := destData[key]; ok {
-				results, _ := CompareJSON(value, value2, path+"."+key)
-				diff = append(diff, results...)
-			} else {
-				//fmt.Println("Key %s not found in second JSON\n", path+"."+key)
-				msg = fmt.Sprintf("-%s", key)
-				diff = append(diff, msg)
-			}
-		}
-		for key := range destData {
-			if _, ok := orgData[key]; !ok {
-				//fmt.Println("Key %s not found in first JSON\n", path+"."+key)
-				msg = fmt.Sprintf("+%s", key)
-				diff = append(diff, msg)
-			}
-		}
-	case []interface{}:
-		destData := destData.([]interface{})
-		if len(orgData) != len(destData) {
-			//fmt.Println("Array length mismatch at %s: %d != %d\n", path, len(orgData), len(destData))
-			return diff, fmt.Errorf("Array length mismatch at %s: %d != %d\n", path, len(orgData), len(destData))
-		}
-		for i := range orgData {
-			results, _ := CompareJSON(orgData[i], destData[i], fmt.Sprintf("%s[%d]", path, i))
-			diff = append(diff, results...)
-			return diff, nil
-		}
-	default:
-		if !reflect.DeepEqual(orgData, destData) {
-			msg = fmt.Sprintf("-%s +%s", orgData, destData)
-			diff = append(diff, msg)
-			//fmt.Println("Value mismatch at %s: %v != %v\n", path, orgData, destData)
-			return diff, nil
-		}
-	}
-	return diff, nil
+// SetMultiValuesOrdered selects how repeated INI keys are compared: as
+// ordered lists of values or as unordered multisets (default).
+func SetMultiValuesOrdered(ordered bool) {
+	multiValuesOrdered = ordered
 }
 
-func CompareIni(rawdata1 []byte, rawdata2 []byte, origin string, dest string, verbose bool, iniFilters []string) ([]string, error) {
+func CompareIni(rawdata1 []byte, rawdata2 []byte, origin string, dest string, verbose bool, iniFilters []string) (*Result, error) {
 	if !verbose {
 		log.SetOutput(ioutil.Discard)
 	}
-	var report []string
+	result := NewResult(origin, dest, "ini")
+	result.setContent(rawdata1, rawdata2)
 	// Load the INI files
-	cfg1, err := ini.Load(rawdata1)
+	cfg1, err := ini.LoadSources(iniLoadOptions, rawdata1)
 	if err != nil {
 		return nil, fmt.Errorf("Error while loading file %s: %s", origin, err)
 	}
-	cfg2, err := ini.Load(rawdata2)
+	cfg2, err := ini.LoadSources(iniLoadOptions, rawdata2)
 	if err != nil {
 		log.Error("Error while loading file: ", dest, err)
 		return nil, fmt.Errorf("Erro while loading file %s: %s", dest, err)
 	}
+	lines1 := iniLineIndex(rawdata1)
+	lines2 := iniLineIndex(rawdata2)
+	// Types of the options for the normalization
+	types := defaultsFor(DefaultsService(origin))
 
-	diffFound := false
-	sectionFound := false
-	msg := string("")
 	// Compare the sections and keys in each file
 	for _, sec1 := range cfg1.Sections() {
-		if (len(iniFilters) > 0 && common.StringInSlice(strings.ToLower(sec1.Name()), common.ToLowerSlice(iniFilters))) || len(iniFilters) == 0 {
-			sectionFound = false
-			sec2, err := cfg2.GetSection(sec1.Name())
-			if err != nil {
-				msg := fmt.Sprintf("-[%s]\n", sec1.Name())
-				if !common.StringInSlice(msg, report) {
-					diffFound = true
-					log.Warn("Difference detected. Section: ", sec1.Name(), " not found in:", dest)
-					report = append(report, msg)
-				}
+		if !iniSectionSelected(sec1.Name(), iniFilters) {
+			continue
+		}
+		sec2, err := cfg2.GetSection(sec1.Name())
+		if err != nil {
+			log.Warn("Difference detected. Section: ", sec1.Name(), " not found in:", dest)
+			result.Add(Entry{Kind: Removed, Section: sec1.Name(), OldLine: lines1.line(sec1.Name(), "")})
+		}
+		for _, key1 := range sec1.Keys() {
+			if sec2 == nil {
+				log.Warn("Difference detected. Section: ", sec1.Name(), " Key ", key1.Name(), " not found in:", dest)
+				iniAddKey(result, Removed, sec1.Name(), key1, lines1)
+				continue
 			}
-			for _, key1 := range sec1.Keys() {
-				if sec2 == nil {
-					if !sectionFound {
-						sectionFound = true
-						msg = fmt.Sprintf("[%s]\n-%s=%s\n", sec1.Name(), key1.Name(), key1.Value())
-					} else {
-						msg = fmt.Sprintf("-%s=%s\n", key1.Name(), key1.Value())
-					}
-					if !common.StringInSlice(msg, report) {
-						diffFound = true
-						log.Warn("Difference detected. Section: ", sec1.Name(), " Key ", key1.Name(), " not found in:", dest)
-						report = append(report, msg)
-					}
-				} else {
-					key2, err := sec2.GetKey(key1.Name())
-					if err != nil {
-						// key2 not found
-						if !sectionFound {
-							sectionFound = true
-							msg = fmt.Sprintf("[%s]\n-%s=%s\n", sec1.Name(), key1.Name(), key1.Value())
-						} else {
-							msg = fmt.Sprintf("-%s=%s\n", key1.Name(), key1.Value())
-						}
-						if !common.StringInSlice(msg, report) {
-							diffFound = true
-							log.Warn("Difference detected. Section: ", sec1.Name(), " Key ", key1.Name(), " not found in:", dest)
-							report = append(report, msg)
-						}
-					} else if key1.Value() != key2.Value() {
-						if !sectionFound {
-							sectionFound = true
-							msg = fmt.Sprintf(
-								"[%s]\n-%s=%s\n+%s=%s\n",
-								sec1.Name(),
-								key1.Name(),
-								key1.Value(),
-								key2.Name(),
-								key2.Value(),
-							)
-						} else {
-							msg = fmt.Sprintf("-%s=%s\n+%s=%s\n", key1.Name(), key1.Value(), key2.Name(), key2.Value())
-						}
-						if !common.StringInSlice(msg, report) {
-							diffFound = true
-							log.Warn("Difference detected: Values are not equal: ",
-								key1.Value(), " and ", key2.Value(),
-								"Section: ", sec1.Name(), " Key ", key1.Name(), dest)
-							report = append(report, msg)
-						}
-					}
-				}
+			key2, err := sec2.GetKey(key1.Name())
+			if err != nil {
+				// key2 not found
+				log.Warn("Difference detected. Section: ", sec1.Name(), " Key ", key1.Name(), " not found in:", dest)
+				iniAddKey(result, Removed, sec1.Name(), key1, lines1)
+			} else if len(iniKeyValues(key1)) > 1 || len(iniKeyValues(key2)) > 1 {
+				compareMultiValues(result, types, sec1.Name(), key1.Name(), iniKeyValues(key1), iniKeyValues(key2), lines1, lines2)
+			} else if list := findIniList(sec1.Name(), key1.Name()); list != nil {
+				compareIniList(result, list, sec1.Name(), key1.Name(), key1.Value(), key2.Value(),
+					lines1.line(sec1.Name(), key1.Name()), lines2.line(sec2.Name(), key2.Name()))
+			} else if !types.ValuesEqual(sec1.Name(), key1.Name(), key1.Value(), key2.Value()) {
+				log.Warn("Difference detected: Values are not equal: ",
+					RedactValue(key1.Name(), key1.Value()), " and ", RedactValue(key2.Name(), key2.Value()),
+					"Section: ", sec1.Name(), " Key ", key1.Name(), dest)
+				result.Add(Entry{
+					Kind:     Changed,
+					Section:  sec1.Name(),
+					Path:     key1.Name(),
+					OldValue: key1.Value(),
+					NewValue: key2.Value(),
+					OldLine:  lines1.line(sec1.Name(), key1.Name()),
+					NewLine:  lines2.line(sec2.Name(), key2.Name()),
+				})
 			}
-			// Look for missing keys in Origin:
-			if sec2 != nil {
-				for _, key2 := range sec2.Keys() {
-					_, err := sec1.GetKey(key2.Name())
-					if err != nil {
-						if !sectionFound {
-							sectionFound = true
-							msg = fmt.Sprintf("[%s]\n+%s=%s\n", sec2.Name(), key2.Name(), key2.Value())
-						} else {
-							msg = fmt.Sprintf("+%s=%s\n", key2.Name(), key2.Value())
-						}
-						if !common.StringInSlice(msg, report) {
-							diffFound = true
-							log.Warn("Difference detected -- Section: ", sec2.Name(), " Key ", key2.Name(), " not found in:", dest)
-							report = append(report, msg)
-						}
-					}
+		}
+		// Look for missing keys in Origin:
+		if sec2 != nil {
+			for _, key2 := range sec2.Keys() {
+				if _, err := sec1.GetKey(key2.Name()); err != nil {
+					log.Warn("Difference detected -- Section: ", sec2.Name(), " Key ", key2.Name(), " not found in:", origin)
+					iniAddKey(result, Added, sec2.Name(), key2, lines2)
 				}
 			}
 		}
 	}
 	for _, sec2 := range cfg2.Sections() {
-		if (len(iniFilters) > 0 && common.StringInSlice(strings.ToLower(sec2.Name()), common.ToLowerSlice(iniFilters))) || len(iniFilters) == 0 {
-			sectionFound = false
-			_, err := cfg1.GetSection(sec2.Name())
-			if err != nil {
-				msg := fmt.Sprintf("-[%s]\n", sec2.Name())
-				if !common.StringInSlice(msg, report) {
-					diffFound = true
-					log.Warn("Difference detected. Section: ", sec2.Name(), " not found in:", dest)
-					report = append(report, msg)
-				}
-				for _, key2 := range sec2.Keys() {
-					msg = fmt.Sprintf("-%s=%s\n", key2.Name(), key2.Value())
-					if !common.StringInSlice(msg, report) {
-						log.Warn("Difference detected -- Section: ", sec2.Name(), " Key ", key2.Name(), " not found in:", dest)
-						report = append(report, msg)
-					}
-				}
-			}
+		if !iniSectionSelected(sec2.Name(), iniFilters) {
+			continue
+		}
+		if _, err := cfg1.GetSection(sec2.Name()); err == nil {
+			continue
+		}
+		log.Warn("Difference detected. Section: ", sec2.Name(), " not found in:", origin)
+		result.Add(Entry{Kind: Added, Section: sec2.Name(), NewLine: lines2.line(sec2.Name(), "")})
+		for _, key2 := range sec2.Keys() {
+			log.Warn("Difference detected -- Section: ", sec2.Name(), " Key ", key2.Name(), " not found in:", origin)
+			iniAddKey(result, Added, sec2.Name(), key2, lines2)
 		}
 	}
-	if diffFound {
+	if result.HasDifferences() {
 		log.Warn("File: ", origin, " has difference with: ", dest)
-		msg := fmt.Sprintf("Source file path: %s, difference with: %s\n", origin, dest)
-		report = append([]string{msg}, report...)
 	}
-	return report, nil
+	return result, nil
 }
 
-func CompareRawData(rawdata1 []byte, rawdata2 []byte, origin string, dest string) ([]string, error) {
-	var report []string
-	log.Info("Start basic line by line comparison")
-	// Split both files into lines
-	file1 := strings.Split(string(rawdata1), "\n")
-	file2 := strings.Split(string(rawdata2), "\n")
+// iniKeyValues returns every value of a key, a repeated key has several
+// values.
+func iniKeyValues(key *ini.Key) []string {
+	values := key.ValueWithShadows()
+	if len(values) == 0 {
+		return []string{key.Value()}
+	}
+	return values
+}
 
-	found := false
-	diffFound := false
-	msg := string("")
-	for i, line1 := range file1 {
-		found = false
-		// Skip comments
-		if !strings.HasPrefix(line1, "#") && len(line1) > 0 {
-			for _, line2 := range file2 {
-				if !strings.HasPrefix(line2, "#") && len(line2) > 0 {
-					if line1 == line2 {
-						found = true
-						break
-					}
-				}
+// iniAddKey adds an entry per value of a key only present on one side.
+func iniAddKey(result *Result, kind EntryKind, section string, key *ini.Key, lines iniLines) {
+	values := iniKeyValues(key)
+	for i, value := range values {
+		e := Entry{Kind: kind, Section: section, Path: key.Name()}
+		line := lines.line(section, key.Name())
+		if len(values) > 1 {
+			line = lines.valueLine(section, key.Name(), i)
+		}
+		if kind == Removed {
+			e.OldValue = value
+			e.OldLine = line
+		} else {
+			e.NewValue = value
+			e.NewLine = line
+		}
+		result.Add(e)
+	}
+}
+
+// compareMultiValues compares the values of a repeated key, every value
+// missing on one side is reported.
+func compareMultiValues(result *Result, types OptionDefaults, section string, key string, values1 []string, values2 []string, lines1 iniLines, lines2 iniLines) {
+	if multiValuesOrdered {
+		for i := 0; i < len(values1) || i < len(values2); i++ {
+			switch {
+			case i >= len(values2):
+				result.Add(Entry{Kind: Removed, Section: section, Path: key, OldValue: values1[i], OldLine: lines1.valueLine(section, key, i)})
+			case i >= len(values1):
+				result.Add(Entry{Kind: Added, Section: section, Path: key, NewValue: values2[i], NewLine: lines2.valueLine(section, key, i)})
+			case !types.ValuesEqual(section, key, values1[i], values2[i]):
+				result.Add(Entry{
+					Kind:     Changed,
+					Section:  section,
+					Path:     key,
+					OldValue: values1[i],
+					NewValue: values2[i],
+					OldLine:  lines1.valueLine(section, key, i),
+					NewLine:  lines2.valueLine(section, key, i),
+				})
 			}
-			if !found {
-				log.Warn("Line: ", line1, " not found in: ", dest, " line: ", i+1)
-				msg = fmt.Sprintf("@ line: %d\n", i+1)
-				if !common.StringInSlice(msg, report) {
-					report = append(report, msg)
-				}
-				msg = fmt.Sprintf("+%s\n", line1)
-				report = append(report, msg)
-				diffFound = true
+		}
+		return
+	}
+	// Multisets: each value of one side matches at most one value of the
+	// other side.
+	matched := make([]bool, len(values2))
+	for i, v1 := range values1 {
+		found := false
+		for j, v2 := range values2 {
+			if !matched[j] && types.ValuesEqual(section, key, v1, v2) {
+				matched[j] = true
+				found = true
+				break
 			}
 		}
+		if !found {
+			log.Warn("Difference detected. Section: ", section, " Key ", key, " value ", RedactValue(key, v1), " not found in destination")
+			result.Add(Entry{Kind: Removed, Section: section, Path: key, OldValue: v1, OldLine: lines1.valueLine(section, key, i)})
+		}
 	}
-	//var lineNb []string
-	var index int
-	for i, line2 := range file2 {
-		found = false
-		// Skip comments
-		if !strings.HasPrefix(line2, "#") && len(line2) > 0 {
-			for _, line1 := range file1 {
-				if !strings.HasPrefix(line1, "#") && len(line1) > 0 {
-					if line1 == line2 {
-						found = true
-						break
-					}
-				}
+	for j, v2 := range values2 {
+		if !matched[j] {
+			log.Warn("Difference detected. Section: ", section, " Key ", key, " value ", RedactValue(key, v2), " not found in origin")
+			result.Add(Entry{Kind: Added, Section: section, Path: key, NewValue: v2, NewLine: lines2.valueLine(section, key, j)})
+		}
+	}
+}
+
+func iniSectionSelected(section string, iniFilters []string) bool {
+	if len(iniFilters) == 0 {
+		return true
+	}
+	return common.StringInSlice(strings.ToLower(section), common.ToLowerSlice(iniFilters))
+}
+
+func iniLineKey(section string, key string) string {
+	return section + "\x00" + key
+}
+
+// iniLines maps every section and section/key pair to the line numbers where
+// it is defined, go-ini does not keep track of it. The key lines are split
+// between every occurrence and the occurrences with a value, go-ini drops
+// the empty values of the repeated keys.
+type iniLines struct {
+	all    map[string][]int
+	values map[string][]int
+}
+
+// line returns the first line defining a section or a key.
+func (l iniLines) line(section string, key string) int {
+	if lines := l.all[iniLineKey(section, key)]; len(lines) > 0 {
+		return lines[0]
+	}
+	return 0
+}
+
+// valueLine returns the line of the n-th value of a repeated key.
+func (l iniLines) valueLine(section string, key string, n int) int {
+	if lines := l.values[iniLineKey(section, key)]; n < len(lines) {
+		return lines[n]
+	}
+	return l.line(section, key)
+}
+
+func iniLineIndex(data []byte) iniLines {
+	index := iniLines{all: make(map[string][]int), values: make(map[string][]int)}
+	section := ini.DefaultSection
+	for i, line := range strings.Split(string(data), "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") || strings.HasPrefix(line, ";") {
+			continue
+		}
+		if strings.HasPrefix(line, "[") && strings.HasSuffix(line, "]") {
+			section = strings.TrimSpace(line[1 : len(line)-1])
+			index.all[iniLineKey(section, "")] = append(index.all[iniLineKey(section, "")], i+1)
+			continue
+		}
+		sep := strings.IndexAny(line, "=:")
+		if sep == -1 {
+			continue
+		}
+		key := iniLineKey(section, strings.TrimSpace(line[:sep]))
+		index.all[key] = append(index.all[key], i+1)
+		if strings.TrimSpace(line[sep+1:]) != "" {
+			index.values[key] = append(index.values[key], i+1)
+		}
+	}
+	return index
+}
+
+var compareComments = false
+
+// SetCompareComments selects whether the comments and empty lines are
+// reported by CompareRawData, they are skipped by default.
+func SetCompareComments(compare bool) {
+	compareComments = compare
+}
+
+func skipRawLine(line string) bool {
+	return !compareComments && (strings.HasPrefix(line, "#") || len(line) == 0)
+}
+
+func CompareRawData(rawdata1 []byte, rawdata2 []byte, origin string, dest string) (*Result, error) {
+	result := NewResult(origin, dest, "raw")
+	result.setContent(rawdata1, rawdata2)
+	log.Info("Start basic line by line comparison")
+	for _, edit := range diffLines(result.oldText, result.newText) {
+		switch edit.op {
+		case opDelete:
+			line := result.oldText[edit.oldLine-1]
+			if skipRawLine(line) {
+				continue
 			}
-			if !found {
-				log.Warn("Line: ", line2, " not found in: ", origin, " line: ", i+1)
-				msg = fmt.Sprintf("@ line: %d\n", i+1)
-				if !common.StringInSlice(msg, report) {
-					report = append(report, msg)
-					msg = fmt.Sprintf("-%s\n", line2)
-					report = append(report, msg)
-				} else {
-					index = common.SliceIndex(msg, report)
-					msg = fmt.Sprintf("-%s\n", line2)
-					report = append(report[:index+2], msg)
-				}
-				diffFound = true
+			log.Warn("Line: ", RedactLine(line), " not found in: ", dest, " line: ", edit.oldLine)
+			result.Add(Entry{Kind: Removed, OldValue: line, OldLine: edit.oldLine})
+		case opInsert:
+			line := result.newText[edit.newLine-1]
+			if skipRawLine(line) {
+				continue
 			}
+			log.Warn("Line: ", RedactLine(line), " not found in: ", origin, " line: ", edit.newLine)
+			result.Add(Entry{Kind: Added, NewValue: line, NewLine: edit.newLine})
 		}
 	}
-	if diffFound {
+	if result.HasDifferences() {
 		log.Warn("File: ", origin, " has difference with: ", dest)
-		msg := fmt.Sprintf("Source file path: %s, difference with: %s\n", origin, dest)
-		report = append([]string{msg}, report...)
 	}
-	return report, nil
+	return result, nil
 }
 
 func GetConfigFromRemote(remoteCmd string, configPath string) ([]byte, error) {
@@ -332,8 +317,7 @@ func GetConfigFromRemote(remoteCmd string, configPath string) ([]byte, error) {
 	cmd := remoteCmd + " cat " + configPath
 	out, err := exec.Command("bash", "-c", cmd).Output()
 	if err != nil {
-		fmt.Println(string(out))
-		return out, err
+		return out, common.CollectionError("failed to get %s with: %s: %w", configPath, remoteCmd, err)
 	}
 	return []byte(out), nil
 }
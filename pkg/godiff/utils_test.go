@@ -17,7 +17,10 @@
 package godiff_test
 
 import (
+	"encoding/json"
+	"fmt"
 	"reflect"
+	"strings"
 	"testing"
 
 	"github.com/openstack-k8s-operators/os-diff/pkg/godiff"
@@ -28,12 +31,12 @@ import (
 func TestCompareYAML(t *testing.T) {
 	origin := []byte("key1: value1\nkey2: value2")
 	dest := []byte("key1: value1\nkey3: value3")
-	expected := []string{"+key2: value2\n", "-key3: value3\n"}
-	report, err := godiff.CompareYAML(origin, dest)
+	expected := []string{"-key2: value2\n", "+key3: value3\n"}
+	result, err := godiff.CompareYAML(origin, dest)
 	if err != nil {
 		t.Errorf("Unexpected error: %v", err)
 	}
-	if !reflect.DeepEqual(report, expected) {
+	if report := result.Report(); !reflect.DeepEqual(report, expected) {
 		t.Errorf("Report mismatch, got: %v, want: %v", report, expected)
 	}
 }
@@ -41,15 +44,69 @@ func TestCompareYAML(t *testing.T) {
 func TestCompareYAMLEqualMaps(t *testing.T) {
 	origin := []byte("key1: value1\nkey2: value2")
 	dest := []byte("key1: value1\nkey2: value2")
-	report, err := godiff.CompareYAML(origin, dest)
+	result, err := godiff.CompareYAML(origin, dest)
 	if err != nil {
 		t.Errorf("Unexpected error: %v", err)
 	}
-	if len(report) > 0 {
-		t.Errorf("Report should be empty for equal maps, got: %v", report)
+	if result.HasDifferences() {
+		t.Errorf("Report should be empty for equal maps, got: %v", result.Entries)
 	}
 }
 
+func TestCompareYAMLNested(t *testing.T) {
+	origin := []byte(`spec:
+  cinder:
+    template:
+      cinderAPI:
+        replicas: 1
+      containers:
+      - name: api
+        image: cinder-api:1
+      - name: scheduler
+        image: cinder-scheduler:1
+      args: [a, b]
+`)
+	dest := []byte(`spec:
+  cinder:
+    template:
+      cinderAPI:
+        replicas: 3
+      containers:
+      - name: scheduler
+        image: cinder-scheduler:1
+      - name: api
+        image: cinder-api:2
+      - name: volume
+        image: cinder-volume
+      args: [a, c, d]
+`)
+	result, err := godiff.CompareYAML(origin, dest)
+	assert.NoError(t, err)
+	assert.Equal(t, []godiff.Entry{
+		{Kind: godiff.Changed, Path: "spec.cinder.template.cinderAPI.replicas", OldValue: "1", NewValue: "3", OldLine: 5, NewLine: 5},
+		{Kind: godiff.Changed, Path: "spec.cinder.template.containers[name=api].image", OldValue: "cinder-api:1", NewValue: "cinder-api:2", OldLine: 8, NewLine: 10},
+		{Kind: godiff.Added, Path: "spec.cinder.template.containers[name=volume]", NewValue: "{name: volume, image: cinder-volume}", NewLine: 11},
+		{Kind: godiff.Changed, Path: "spec.cinder.template.args[1]", OldValue: "b", NewValue: "c", OldLine: 11, NewLine: 13},
+		{Kind: godiff.Added, Path: "spec.cinder.template.args[2]", NewValue: "d", NewLine: 13},
+	}, result.Entries)
+}
+
+func TestCompareYAMLTypes(t *testing.T) {
+	result, err := godiff.CompareYAML([]byte("replicas: 1\nports: {api: 80}\n"), []byte("replicas: \"1\"\nports: [80]\n"))
+	assert.NoError(t, err)
+	assert.Equal(t, []godiff.Entry{
+		{Kind: godiff.Changed, Path: "replicas", OldValue: "!!int 1", NewValue: "!!str 1", OldLine: 1, NewLine: 1},
+		{Kind: godiff.Changed, Path: "ports", OldValue: "{api: 80}", NewValue: "[80]", OldLine: 2, NewLine: 2},
+	}, result.Entries)
+
+	// Documents of a stream are compared by position
+	result, err = godiff.CompareYAML([]byte("a: 1\n---\nb: 2\n"), []byte("a: 1\n---\nb: 3\n"))
+	assert.NoError(t, err)
+	assert.Equal(t, []godiff.Entry{
+		{Kind: godiff.Changed, Path: "[1].b", OldValue: "2", NewValue: "3", OldLine: 3, NewLine: 3},
+	}, result.Entries)
+}
+
 // Test case for function CompareJSON
 func TestCompareJSON(t *testing.T) {
 	// Test case for comparing two identical JSON objects
@@ -85,8 +142,9 @@ func TestCompareJSON(t *testing.T) {
 	assert.NoError(t, err2)
 	assert.NotEmpty(t, diff2)
 	assert.Len(t, diff2, 3)
-	assert.Contains(t, diff2, "+key4")
-	assert.Contains(t, diff2, "-key3")
+	assert.Contains(t, diff2, godiff.Entry{Kind: godiff.Added, Path: "key4", NewValue: "value4"})
+	assert.Contains(t, diff2, godiff.Entry{Kind: godiff.Removed, Path: "key3", OldValue: "[a b c]"})
+	assert.Contains(t, diff2, godiff.Entry{Kind: godiff.Changed, Path: "key2", OldValue: "123", NewValue: "456"})
 
 	// Test case for comparing JSON objects with type mismatch
 	orgData3 := map[string]interface{}{
@@ -96,9 +154,66 @@ func TestCompareJSON(t *testing.T) {
 	destData3 := []interface{}{"value1", 123}
 	diff3, err3 := godiff.CompareJSON(orgData3, destData3, "")
 
-	// Assertion for type mismatch error
-	assert.Error(t, err3)
-	assert.Empty(t, diff3)
+	// Assertion for type mismatch reported as a change
+	assert.NoError(t, err3)
+	assert.Equal(t, []godiff.Entry{
+		{Kind: godiff.Changed, OldValue: `object {"key1":"value1","key2":123}`, NewValue: `array ["value1",123]`},
+	}, diff3)
+}
+
+func TestCompareJSONArrays(t *testing.T) {
+	defer godiff.SetJSONArrays(false, nil)
+	var org, dest interface{}
+	assert.NoError(t, json.Unmarshal([]byte(`{"command": "httpd", "config_files": [
+		{"source": "/a/nova.conf", "dest": "/etc/nova/nova.conf", "perm": "0600"},
+		{"source": "/a/api.conf", "dest": "/etc/nova/api.conf"}
+	], "ports": [1, "2", 3]}`), &org))
+	assert.NoError(t, json.Unmarshal([]byte(`{"command": "httpd", "config_files": [
+		{"source": "/a/api.conf", "dest": "/etc/nova/api.conf"},
+		{"source": "/a/nova.conf", "dest": "/etc/nova/nova.conf", "perm": "0640"},
+		{"source": "/a/policy.yaml", "dest": "/etc/nova/policy.yaml"}
+	], "ports": [3, 2, 1]}`), &dest))
+
+	tests := []struct {
+		name      string
+		unordered bool
+		keys      []string
+		expected  []godiff.Entry
+	}{
+		{
+			name: "by position",
+			expected: []godiff.Entry{
+				{Kind: godiff.Changed, Path: "config_files[0].dest", OldValue: "/etc/nova/nova.conf", NewValue: "/etc/nova/api.conf"},
+				{Kind: godiff.Removed, Path: "config_files[0].perm", OldValue: "0600"},
+				{Kind: godiff.Changed, Path: "config_files[0].source", OldValue: "/a/nova.conf", NewValue: "/a/api.conf"},
+				{Kind: godiff.Changed, Path: "config_files[1].dest", OldValue: "/etc/nova/api.conf", NewValue: "/etc/nova/nova.conf"},
+				{Kind: godiff.Changed, Path: "config_files[1].source", OldValue: "/a/api.conf", NewValue: "/a/nova.conf"},
+				{Kind: godiff.Added, Path: "config_files[1].perm", NewValue: "0640"},
+				{Kind: godiff.Added, Path: "config_files[2]", NewValue: `{"dest":"/etc/nova/policy.yaml","source":"/a/policy.yaml"}`},
+				{Kind: godiff.Changed, Path: "ports[0]", OldValue: "1", NewValue: "3"},
+				{Kind: godiff.Changed, Path: "ports[1]", OldValue: "string 2", NewValue: "number 2"},
+				{Kind: godiff.Changed, Path: "ports[2]", OldValue: "3", NewValue: "1"},
+			},
+		},
+		{
+			name:      "unordered and keyed",
+			unordered: true,
+			keys:      []string{"id", "dest"},
+			expected: []godiff.Entry{
+				{Kind: godiff.Changed, Path: "config_files[dest=/etc/nova/nova.conf].perm", OldValue: "0600", NewValue: "0640"},
+				{Kind: godiff.Added, Path: "config_files[dest=/etc/nova/policy.yaml]", NewValue: `{"dest":"/etc/nova/policy.yaml","source":"/a/policy.yaml"}`},
+				{Kind: godiff.Changed, Path: "ports[1]", OldValue: "string 2", NewValue: "number 2"},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			godiff.SetJSONArrays(tt.unordered, tt.keys)
+			diff, err := godiff.CompareJSON(org, dest, "")
+			assert.NoError(t, err)
+			assert.Equal(t, tt.expected, diff)
+		})
+	}
 }
 
 // Test case for function CompareRawData
@@ -110,16 +225,150 @@ func TestCompareRawData(t *testing.T) {
 
 	expectedReport := []string{
 		"Source file path: file1.txt, difference with: file2.txt\n",
-		"@ line: 2\n", "+line2\n",
-		"@ line: 3\n", "-line4\n",
+		"@@ -1,5 +1,5 @@\n", " line1\n", "-line2\n", " line3\n", "+line4\n", " #comment\n", " line5\n",
+		"\\ No newline at end of file\n",
 	}
 
-	report, err := godiff.CompareRawData(rawdata1, rawdata2, origin, dest)
+	result, err := godiff.CompareRawData(rawdata1, rawdata2, origin, dest)
 	if err != nil {
 		t.Errorf("Unexpected error: %v", err)
 	}
 
-	if !reflect.DeepEqual(report, expectedReport) {
+	if report := result.Report(); !reflect.DeepEqual(report, expectedReport) {
 		t.Errorf("Report mismatch, expected: %v, got: %v", expectedReport, report)
 	}
 }
+
+// Test case for function CompareRawData with moved, duplicated lines and comments
+func TestCompareRawDataLines(t *testing.T) {
+	testCases := []struct {
+		name     string
+		rawdata1 string
+		rawdata2 string
+		comments bool
+		expected []godiff.Entry
+	}{
+		{
+			name:     "moved line",
+			rawdata1: "a\nb\nc\n",
+			rawdata2: "b\nc\na\n",
+			expected: []godiff.Entry{
+				{Kind: godiff.Removed, OldValue: "a", OldLine: 1},
+				{Kind: godiff.Added, NewValue: "a", NewLine: 3},
+			},
+		},
+		{
+			name:     "duplicated line",
+			rawdata1: "a\nb\n",
+			rawdata2: "a\nb\nb\n",
+			expected: []godiff.Entry{
+				{Kind: godiff.Added, NewValue: "b", NewLine: 3},
+			},
+		},
+		{
+			name:     "comments skipped",
+			rawdata1: "# a\nb\n\n",
+			rawdata2: "# c\nb\n",
+			expected: []godiff.Entry{},
+		},
+		{
+			name:     "comments compared",
+			rawdata1: "# a\nb\n",
+			rawdata2: "# c\nb\n",
+			comments: true,
+			expected: []godiff.Entry{
+				{Kind: godiff.Removed, OldValue: "# a", OldLine: 1},
+				{Kind: godiff.Added, NewValue: "# c", NewLine: 1},
+			},
+		},
+	}
+	defer godiff.SetCompareComments(false)
+	for _, tc := range testCases {
+		godiff.SetCompareComments(tc.comments)
+		result, err := godiff.CompareRawData([]byte(tc.rawdata1), []byte(tc.rawdata2), "file1", "file2")
+		assert.NoError(t, err)
+		assert.Equal(t, tc.expected, result.Entries, tc.name)
+	}
+}
+
+// Test case for the hunks of the raw report
+func TestRawReportHunks(t *testing.T) {
+	var lines1, lines2 []string
+	for i := 1; i <= 20; i++ {
+		lines1 = append(lines1, fmt.Sprintf("line%d", i))
+		lines2 = append(lines2, fmt.Sprintf("line%d", i))
+	}
+	lines2[1] = "changed2"
+	lines2 = append(lines2[:15], lines2[16:]...)
+	result, err := godiff.CompareRawData([]byte(strings.Join(lines1, "\n")), []byte(strings.Join(lines2, "\n")), "", "")
+	assert.NoError(t, err)
+	assert.Equal(t, []string{
+		"@@ -1,5 +1,5 @@\n", " line1\n", "-line2\n", "+changed2\n", " line3\n", " line4\n", " line5\n",
+		"@@ -13,7 +13,6 @@\n", " line13\n", " line14\n", " line15\n", "-line16\n", " line17\n", " line18\n", " line19\n",
+	}, result.Report())
+}
+
+// Test case for function CompareIni
+func TestCompareIni(t *testing.T) {
+	rawdata1 := []byte("[DEFAULT]\ndebug=True\nlog_dir=/var/log\n[database]\nconnection=mysql://a\n")
+	rawdata2 := []byte("[DEFAULT]\ndebug=False\n[cache]\nenabled=True\n[database]\nconnection=mysql://a\n")
+
+	result, err := godiff.CompareIni(rawdata1, rawdata2, "file1.conf", "file2.conf", false, []string{})
+	assert.NoError(t, err)
+	assert.Equal(t, "ini", result.Format)
+	assert.Equal(t, []godiff.Entry{
+		{Kind: godiff.Changed, Section: "DEFAULT", Path: "debug", OldValue: "True", NewValue: "False", OldLine: 2, NewLine: 2},
+		{Kind: godiff.Removed, Section: "DEFAULT", Path: "log_dir", OldValue: "/var/log", OldLine: 3},
+		{Kind: godiff.Added, Section: "cache", NewLine: 3},
+		{Kind: godiff.Added, Section: "cache", Path: "enabled", NewValue: "True", NewLine: 4},
+	}, result.Entries)
+
+	expectedReport := []string{
+		"Source file path: file1.conf, difference with: file2.conf\n",
+		"[DEFAULT]\n-debug=True\n+debug=False\n",
+		"-log_dir=/var/log\n",
+		"+[cache]\n",
+		"+enabled=True\n",
+	}
+	assert.Equal(t, expectedReport, result.Report())
+
+	// Filters restrict the comparison to the given sections
+	result, err = godiff.CompareIni(rawdata1, rawdata2, "file1.conf", "file2.conf", false, []string{"database"})
+	assert.NoError(t, err)
+	assert.False(t, result.HasDifferences())
+}
+
+// Test case for function CompareIni with repeated keys (MultiStrOpt)
+func TestCompareIniMultiValues(t *testing.T) {
+	rawdata1 := []byte("[pci]\npassthrough_whitelist=a\npassthrough_whitelist=b\npassthrough_whitelist=b\n[DEFAULT]\nenabled_apis=osapi\n")
+	rawdata2 := []byte("[pci]\npassthrough_whitelist=c\npassthrough_whitelist=b\npassthrough_whitelist=a\n[DEFAULT]\nenabled_apis=osapi\nenabled_apis=metadata\n")
+
+	result, err := godiff.CompareIni(rawdata1, rawdata2, "file1.conf", "file2.conf", false, []string{})
+	assert.NoError(t, err)
+	assert.Equal(t, []godiff.Entry{
+		{Kind: godiff.Added, Section: "DEFAULT", Path: "enabled_apis", NewValue: "metadata", NewLine: 7},
+		{Kind: godiff.Removed, Section: "pci", Path: "passthrough_whitelist", OldValue: "b", OldLine: 4},
+		{Kind: godiff.Added, Section: "pci", Path: "passthrough_whitelist", NewValue: "c", NewLine: 2},
+	}, result.Entries)
+
+	// Same values in another order
+	rawdata2 = []byte("[pci]\npassthrough_whitelist=b\npassthrough_whitelist=a\npassthrough_whitelist=b\n[DEFAULT]\nenabled_apis=osapi\n")
+	result, err = godiff.CompareIni(rawdata1, rawdata2, "file1.conf", "file2.conf", false, []string{})
+	assert.NoError(t, err)
+	assert.False(t, result.HasDifferences())
+
+	godiff.SetMultiValuesOrdered(true)
+	defer godiff.SetMultiValuesOrdered(false)
+	result, err = godiff.CompareIni(rawdata1, rawdata2, "file1.conf", "file2.conf", false, []string{})
+	assert.NoError(t, err)
+	assert.Equal(t, []godiff.Entry{
+		{Kind: godiff.Changed, Section: "pci", Path: "passthrough_whitelist", OldValue: "a", NewValue: "b", OldLine: 2, NewLine: 2},
+		{Kind: godiff.Changed, Section: "pci", Path: "passthrough_whitelist", OldValue: "b", NewValue: "a", OldLine: 3, NewLine: 3},
+	}, result.Entries)
+
+	// Every value of a key missing on one side is reported
+	result, err = godiff.CompareIni(rawdata1, []byte("[DEFAULT]\nenabled_apis=osapi\n"), "file1.conf", "file2.conf", false, []string{})
+	assert.NoError(t, err)
+	assert.Len(t, result.Entries, 4)
+	assert.Equal(t, 4, result.Entries[3].OldLine)
+}
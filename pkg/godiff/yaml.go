@@ -0,0 +1,260 @@
+/*
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * Copyright 2023 Red Hat, Inc.
+ *
+ */
+package godiff
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Fields identifying the items of a sequence of mappings, like the
+// containers, volumes or env variables of the Kubernetes resources.
+var yamlSequenceKeys = []string{"name"}
+
+// CompareYAML compares YAML documents recursively. Differences are reported
+// at their full path, e.g. spec.cinder.template.cinderAPI.replicas, with the
+// line numbers of both files. Items of sequences of mappings with a name are
+// matched by name (containers[name=nova-api].image), other sequences are
+// compared by position (args[2]).
+func CompareYAML(origin []byte, dest []byte) (*Result, error) {
+	result := NewResult("", "", "yaml")
+	result.setContent(origin, dest)
+	docs1, err := decodeYAML(origin)
+	if err != nil {
+		return nil, fmt.Errorf("Error unmarshalling %s, error: %s", origin, err)
+	}
+	docs2, err := decodeYAML(dest)
+	if err != nil {
+		return nil, fmt.Errorf("Error unmarshalling %s, error: %s", dest, err)
+	}
+	multi := len(docs1) > 1 || len(docs2) > 1
+	for i := 0; i < len(docs1) || i < len(docs2); i++ {
+		// Documents of a multi-document stream are prefixed by their index
+		path := ""
+		if multi {
+			path = fmt.Sprintf("[%d]", i)
+		}
+		switch {
+		case i >= len(docs2):
+			result.yamlRemoved(path, docs1[i], docs1[i])
+		case i >= len(docs1):
+			result.yamlAdded(path, docs2[i], docs2[i])
+		default:
+			result.compareYAMLNodes(path, docs1[i], docs2[i])
+		}
+	}
+	return result, nil
+}
+
+// decodeYAML returns the root node of every document of the stream.
+func decodeYAML(data []byte) ([]*yaml.Node, error) {
+	var docs []*yaml.Node
+	decoder := yaml.NewDecoder(bytes.NewReader(data))
+	for {
+		var doc yaml.Node
+		err := decoder.Decode(&doc)
+		if errors.Is(err, io.EOF) {
+			return docs, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		if len(doc.Content) > 0 {
+			docs = append(docs, doc.Content[0])
+		}
+	}
+}
+
+func (r *Result) compareYAMLNodes(path string, node1 *yaml.Node, node2 *yaml.Node) {
+	node1, node2 = resolveAlias(node1), resolveAlias(node2)
+	if node1.Kind != node2.Kind {
+		r.yamlChanged(path, node1, node2)
+		return
+	}
+	switch node1.Kind {
+	case yaml.MappingNode:
+		r.compareYAMLMappings(path, node1, node2)
+	case yaml.SequenceNode:
+		r.compareYAMLSequences(path, node1, node2)
+	default:
+		if node1.Value != node2.Value || node1.ShortTag() != node2.ShortTag() {
+			r.yamlChanged(path, node1, node2)
+		}
+	}
+}
+
+func (r *Result) compareYAMLMappings(path string, node1 *yaml.Node, node2 *yaml.Node) {
+	values2 := map[string]*yaml.Node{}
+	for i := 0; i+1 < len(node2.Content); i += 2 {
+		values2[node2.Content[i].Value] = node2.Content[i+1]
+	}
+	keys1 := map[string]bool{}
+	for i := 0; i+1 < len(node1.Content); i += 2 {
+		key, value := node1.Content[i], node1.Content[i+1]
+		keys1[key.Value] = true
+		if value2, ok := values2[key.Value]; ok {
+			r.compareYAMLNodes(joinPath(path, key.Value), value, value2)
+		} else {
+			r.yamlRemoved(joinPath(path, key.Value), key, value)
+		}
+	}
+	for i := 0; i+1 < len(node2.Content); i += 2 {
+		key, value := node2.Content[i], node2.Content[i+1]
+		if !keys1[key.Value] {
+			r.yamlAdded(joinPath(path, key.Value), key, value)
+		}
+	}
+}
+
+func (r *Result) compareYAMLSequences(path string, node1 *yaml.Node, node2 *yaml.Node) {
+	if field := sequenceKey(node1, node2); field != "" {
+		items2 := map[string]*yaml.Node{}
+		for _, item := range node2.Content {
+			items2[mappingValue(item, field)] = item
+		}
+		names1 := map[string]bool{}
+		for _, item := range node1.Content {
+			name := mappingValue(item, field)
+			names1[name] = true
+			itemPath := fmt.Sprintf("%s[%s=%s]", path, field, name)
+			if item2, ok := items2[name]; ok {
+				r.compareYAMLNodes(itemPath, item, item2)
+			} else {
+				r.yamlRemoved(itemPath, item, item)
+			}
+		}
+		for _, item := range node2.Content {
+			name := mappingValue(item, field)
+			if !names1[name] {
+				r.yamlAdded(fmt.Sprintf("%s[%s=%s]", path, field, name), item, item)
+			}
+		}
+		return
+	}
+	for i := 0; i < len(node1.Content) || i < len(node2.Content); i++ {
+		itemPath := fmt.Sprintf("%s[%d]", path, i)
+		switch {
+		case i >= len(node2.Content):
+			r.yamlRemoved(itemPath, node1.Content[i], node1.Content[i])
+		case i >= len(node1.Content):
+			r.yamlAdded(itemPath, node2.Content[i], node2.Content[i])
+		default:
+			r.compareYAMLNodes(itemPath, node1.Content[i], node2.Content[i])
+		}
+	}
+}
+
+// sequenceKey returns the field of yamlSequenceKeys set, with unique values,
+// in all the items of both sequences, or an empty string.
+func sequenceKey(node1 *yaml.Node, node2 *yaml.Node) string {
+	for _, field := range yamlSequenceKeys {
+		if uniqueValues(node1, field) && uniqueValues(node2, field) {
+			return field
+		}
+	}
+	return ""
+}
+
+func uniqueValues(seq *yaml.Node, field string) bool {
+	seen := map[string]bool{}
+	for _, item := range seq.Content {
+		value := mappingValue(item, field)
+		if value == "" || seen[value] {
+			return false
+		}
+		seen[value] = true
+	}
+	return true
+}
+
+// mappingValue returns the scalar value of the field of a mapping node, or
+// an empty string.
+func mappingValue(node *yaml.Node, field string) string {
+	node = resolveAlias(node)
+	if node.Kind != yaml.MappingNode {
+		return ""
+	}
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		if node.Content[i].Value == field && node.Content[i+1].Kind == yaml.ScalarNode {
+			return node.Content[i+1].Value
+		}
+	}
+	return ""
+}
+
+func resolveAlias(node *yaml.Node) *yaml.Node {
+	for node.Kind == yaml.AliasNode && node.Alias != nil {
+		node = node.Alias
+	}
+	return node
+}
+
+// yamlRemoved records a removed value, start is the first node to show in
+// the diff: the mapping key or the sequence item.
+func (r *Result) yamlRemoved(path string, start *yaml.Node, value *yaml.Node) {
+	r.Add(Entry{Kind: Removed, Path: path, OldValue: yamlString(value), OldLine: start.Line})
+	r.setSpan(start.Line, 0, nodeEndLine(value), 0)
+}
+
+func (r *Result) yamlAdded(path string, start *yaml.Node, value *yaml.Node) {
+	r.Add(Entry{Kind: Added, Path: path, NewValue: yamlString(value), NewLine: start.Line})
+	r.setSpan(0, start.Line, 0, nodeEndLine(value))
+}
+
+func (r *Result) yamlChanged(path string, node1 *yaml.Node, node2 *yaml.Node) {
+	oldValue, newValue := yamlString(node1), yamlString(node2)
+	if oldValue == newValue {
+		// Same value with a different type, like 1 and "1"
+		oldValue = node1.ShortTag() + " " + oldValue
+		newValue = node2.ShortTag() + " " + newValue
+	}
+	r.Add(Entry{Kind: Changed, Path: path, OldValue: oldValue, NewValue: newValue, OldLine: node1.Line, NewLine: node2.Line})
+	r.setSpan(node1.Line, node2.Line, nodeEndLine(node1), nodeEndLine(node2))
+}
+
+// yamlString returns the value of a scalar, or the flow style YAML of a
+// mapping or a sequence.
+func yamlString(node *yaml.Node) string {
+	node = resolveAlias(node)
+	if node.Kind == yaml.ScalarNode {
+		return node.Value
+	}
+	flow := *node
+	flow.Style = yaml.FlowStyle
+	flow.HeadComment, flow.LineComment, flow.FootComment = "", "", ""
+	out, err := yaml.Marshal(&flow)
+	if err != nil {
+		return fmt.Sprintf("%v", node.Value)
+	}
+	return strings.TrimSpace(string(out))
+}
+
+// nodeEndLine returns the last line of a node and its children.
+func nodeEndLine(node *yaml.Node) int {
+	last := node.Line
+	for _, child := range node.Content {
+		if line := nodeEndLine(child); line > last {
+			last = line
+		}
+	}
+	return last
+}
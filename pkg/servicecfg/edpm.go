@@ -146,18 +146,27 @@ func LoadOvsExternalIds(ovsConfig string) map[string]string {
 	return srcMap
 }
 
-func CompareMappingConfig(srcMap map[string]string, configMapping map[string]string, edpmStruct OpenStackDataPlaneNodeSet) error {
+func CompareMappingConfig(srcMap map[string]string, configMapping map[string]string, edpmStruct OpenStackDataPlaneNodeSet, configFile string, crdFile string) (*godiff.Result, error) {
 	var report []string
 	var msg string
+	result := godiff.NewResult(configFile, crdFile, "mapping")
 	for k, v := range configMapping {
 		value := common.GetNestedFieldValue(edpmStruct.Spec.NodeTemplate.Ansible.AnsibleVars, common.SnakeToCamel(v))
 		if srcMap[k] != common.ConvertToString(value) {
-			msg = fmt.Sprintf("-%s=%s\n", k, srcMap[k])
+			msg = fmt.Sprintf("-%s=%s\n", k, godiff.RedactValue(k, srcMap[k]))
 			report = append(report, msg)
-			msg = fmt.Sprintf("+%s=%s\n", v, common.ConvertToString(value))
+			msg = fmt.Sprintf("+%s=%s\n", v, godiff.RedactValue(v, common.ConvertToString(value)))
 			report = append(report, msg)
+			result.Add(godiff.Entry{
+				Kind:     godiff.Changed,
+				Path:     k,
+				OldValue: srcMap[k],
+				NewValue: common.ConvertToString(value),
+			})
 		}
 	}
-	godiff.PrintReport(report)
-	return nil
+	if !godiff.IsJSONOutput() {
+		godiff.PrintReport(report)
+	}
+	return result, nil
 }
@@ -19,7 +19,6 @@ package servicecfg
 import (
 	"fmt"
 	"io/ioutil"
-	"log"
 	"os"
 	"path/filepath"
 	"strings"
@@ -56,24 +55,24 @@ type ConfigMapDataStruct struct {
 
 type ConfigMapConf string
 
-func DiffServiceConfigWithCRD(service string, crdFile string, configFile string, serviceCfgFile string) error {
+func DiffServiceConfigWithCRD(service string, crdFile string, configFile string, serviceCfgFile string) (bool, error) {
 	// Load config
-	var config common.Config
-	config, _ = common.LoadServiceConfigFile(serviceCfgFile)
+	config, err := common.LoadServiceConfigFile(serviceCfgFile)
+	if err != nil {
+		return false, common.UsageError("failed to load %s: %w", serviceCfgFile, err)
+	}
 	//Load files
 	src, err := ioutil.ReadFile(configFile)
 	if err != nil {
-		fmt.Println(err)
-		return err
+		return false, common.UsageError("%w", err)
 	}
 	yamlFile, err := ioutil.ReadFile(crdFile)
 	if err != nil {
-		return err
+		return false, common.UsageError("%w", err)
 	}
 	// Make sure crdFile is Yaml
 	if common.DetectType([]byte(crdFile)) != "yaml" {
-		fmt.Println("Error, file2 is not a Yaml or a CRD file. Please provide a correct file.")
-		return fmt.Errorf("wrong file2 type")
+		return false, common.UsageError("file2 is not a Yaml or a CRD file, please provide a correct file")
 	}
 	if service != "" {
 		if config.Services[service].ConfigMapping != nil {
@@ -84,86 +83,92 @@ func DiffServiceConfigWithCRD(service string, crdFile string, configFile string,
 				if common.DetectType(src) == "raw" {
 					fileMap, _ = LoadFilesIntoMap(configFile)
 				} else {
-					fmt.Println("File type not supported, only support format as: key=value or key: value.")
-					return nil
+					return false, common.UsageError("file type not supported, only support format as: key=value or key: value")
 				}
 			}
 			var edpmService OpenStackDataPlaneNodeSet
 			err = yaml.Unmarshal(yamlFile, &edpmService)
 			if err != nil {
-				panic(err)
+				return false, common.UsageError("failed to load %s: %w", crdFile, err)
+			}
+			if !godiff.IsJSONOutput() {
+				fmt.Println("Start to compare file contents for: " + configFile + " and " + crdFile)
+			}
+			result, err := CompareMappingConfig(fileMap, config.Services[service].ConfigMapping, edpmService, configFile, crdFile)
+			if err != nil {
+				return false, err
 			}
-			fmt.Println("Start to compare file contents for: " + configFile + " and " + crdFile)
-			return CompareMappingConfig(fileMap, config.Services[service].ConfigMapping, edpmService)
+			godiff.RecordResult(result, common.DetectType(src))
+			return result.HasDifferences(), nil
 		}
 	}
 
-	if common.DetectType(src) == "ini" {
-		customServiceConfigs, err := ExtractCustomServiceConfig(string(yamlFile))
-		if err != nil {
-			fmt.Println("Error:", err)
-			return err
-		}
-		_, err = CompareIniConfig(src, []byte(strings.Join(customServiceConfigs, "")), configFile, crdFile)
-		if err != nil {
-			return err
-		}
-	} else {
-		fmt.Println("Unsupported config file type, only support INI file.")
+	if common.DetectType(src) != "ini" {
+		return false, common.UsageError("unsupported config file type, only support INI file")
 	}
-	return nil
+	customServiceConfigs, err := ExtractCustomServiceConfig(string(yamlFile))
+	if err != nil {
+		return false, common.UsageError("failed to load %s: %w", crdFile, err)
+	}
+	result, err := CompareIniConfig(src, []byte(strings.Join(customServiceConfigs, "")), configFile, crdFile)
+	if err != nil {
+		return false, err
+	}
+	return result.HasDifferences(), nil
 }
 
-func DiffServiceConfigFromPod(service string, crdFile string, configFile string, serviceCfgFile string) error {
-	var config common.Config
-	config, _ = common.LoadServiceConfigFile(serviceCfgFile)
+func DiffServiceConfigFromPod(service string, crdFile string, configFile string, serviceCfgFile string) (bool, error) {
+	config, err := common.LoadServiceConfigFile(serviceCfgFile)
+	if err != nil {
+		return false, common.UsageError("failed to load %s: %w", serviceCfgFile, err)
+	}
 	yamlFile, err := ioutil.ReadFile(crdFile)
 	if err != nil {
-		return err
+		return false, common.UsageError("%w", err)
 	}
 	customServiceConfigs, err := ExtractCustomServiceConfig(string(yamlFile))
 	if err != nil {
-		fmt.Println("Error:", err)
-		return err
+		return false, common.UsageError("failed to load %s: %w", crdFile, err)
 	}
 	// Get service Config
 	podConfig, err := GetConfigFromPod(configFile, config.Services[service].PodName, config.Services[service].ContainerName)
 	if err != nil {
-		panic(err)
+		return false, err
 	}
 
-	_, err = CompareIniConfig(podConfig, []byte(strings.Join(customServiceConfigs, "")), configFile, crdFile)
+	result, err := CompareIniConfig(podConfig, []byte(strings.Join(customServiceConfigs, "")), configFile, crdFile)
 	if err != nil {
-		panic(err)
+		return false, err
 	}
-	return nil
+	return result.HasDifferences(), nil
 }
 
-func DiffServiceConfigFromPodman(service string, crdFile string, configFile string, serviceCfgFile string) error {
-	var config common.Config
-	config, _ = common.LoadServiceConfigFile(serviceCfgFile)
+func DiffServiceConfigFromPodman(service string, crdFile string, configFile string, serviceCfgFile string) (bool, error) {
+	config, err := common.LoadServiceConfigFile(serviceCfgFile)
+	if err != nil {
+		return false, common.UsageError("failed to load %s: %w", serviceCfgFile, err)
+	}
 	// Get ocpConfig
 	yamlFile, err := ioutil.ReadFile(crdFile)
 	if err != nil {
-		return err
+		return false, common.UsageError("%w", err)
 	}
 	customServiceConfigs, err := ExtractCustomServiceConfig(string(yamlFile))
 	if err != nil {
-		fmt.Println("Error:", err)
-		return err
+		return false, common.UsageError("failed to load %s: %w", crdFile, err)
 	}
 
 	// Get service Config
 	osConfig, err := GetConfigFromPodman(configFile, config.Services[service].PodmanName)
 	if err != nil {
-		panic(err)
+		return false, err
 	}
 
-	_, err = CompareIniConfig(osConfig, []byte(strings.Join(customServiceConfigs, "")), configFile, crdFile)
+	result, err := CompareIniConfig(osConfig, []byte(strings.Join(customServiceConfigs, "")), configFile, crdFile)
 	if err != nil {
-		panic(err)
+		return false, err
 	}
-	return nil
+	return result.HasDifferences(), nil
 }
 
 func GenerateConfigPatchFromIni(serviceName string, configFile string, outputFile string, serviceEnable bool) error {
@@ -218,39 +223,38 @@ func GenerateConfigPatch(serviceName string, config []byte, outputFile string, s
 	return nil
 }
 
-func DiffConfigMap(configMapName string, orgConfigPath string, fromRemote bool, remoteCmd string) error {
+func DiffConfigMap(configMapName string, orgConfigPath string, fromRemote bool, remoteCmd string) (bool, error) {
 	var config []byte
 	var err error
 	var isDir bool
 	var isConfigNameisDir bool
+	diffFound := false
 	// Get configMap
 	configMapStat, err := os.Stat(configMapName)
 	if err != nil {
 		config, err = GetOCConfigMap(configMapName)
 		if err != nil {
-			return err
+			return false, err
 		}
 	} else if !configMapStat.IsDir() {
 		config, err = os.ReadFile(configMapName)
 		if err != nil {
-			return err
+			return false, common.UsageError("%w", err)
 		}
 	} else {
-		return fmt.Errorf("Wrong configmap arguments, need file or oc get configmap/<name> instead.")
+		return false, common.UsageError("wrong configmap arguments, need file or oc get configmap/<name> instead")
 	}
 
 	isDir = false
 	if fromRemote {
 		isDir, err = RemoteStatDir(remoteCmd, orgConfigPath)
 		if err != nil {
-			fmt.Println("Error while trying to stat remote:", orgConfigPath, "no such file or directory.")
-			return err
+			return false, common.CollectionError("unable to stat remote %s: %w", orgConfigPath, err)
 		}
 	} else {
 		configPathStat, err := os.Stat(orgConfigPath)
 		if err != nil {
-			fmt.Println(err)
-			return err
+			return false, common.UsageError("%w", err)
 		}
 		isDir = configPathStat.IsDir()
 	}
@@ -258,12 +262,13 @@ func DiffConfigMap(configMapName string, orgConfigPath string, fromRemote bool,
 	var configMapdata ConfigMapDataStruct
 	err = yaml.Unmarshal(config, &configMapdata)
 	if err != nil {
-		log.Fatal(err)
+		return false, common.UsageError("failed to load configmap %s: %w", configMapName, err)
 	}
-	for key, _ := range configMapdata.Data {
+	for key := range configMapdata.Data {
+		var confPath string
 		if isDir {
 			// Check if orgConfigPath and confName exists
-			confPath := filepath.Join(orgConfigPath, key)
+			confPath = filepath.Join(orgConfigPath, key)
 			if fromRemote {
 				isConfigNameisDir, err = RemoteStatDir(remoteCmd, confPath)
 				if err != nil {
@@ -276,24 +281,26 @@ func DiffConfigMap(configMapName string, orgConfigPath string, fromRemote bool,
 				}
 				isConfigNameisDir = configNameStat.IsDir()
 			}
-			if !isConfigNameisDir {
-				configMapPath := filepath.Join(configMapName, key)
-				compareIniFromFileAndStringBuilder(configMapdata.Data[key], confPath, configMapPath, fromRemote, remoteCmd)
-			} else {
+			if isConfigNameisDir {
 				continue
 			}
 		} else {
-			fileName := filepath.Base(orgConfigPath)
-			if fileName == key {
-				configMapPath := filepath.Join(configMapName, key)
-				compareIniFromFileAndStringBuilder(configMapdata.Data[key], orgConfigPath, configMapPath, fromRemote, remoteCmd)
+			if filepath.Base(orgConfigPath) != key {
+				continue
 			}
+			confPath = orgConfigPath
 		}
+		configMapPath := filepath.Join(configMapName, key)
+		found, err := compareIniFromFileAndStringBuilder(configMapdata.Data[key], confPath, configMapPath, fromRemote, remoteCmd)
+		if err != nil {
+			return diffFound, err
+		}
+		diffFound = diffFound || found
 	}
-	return nil
+	return diffFound, nil
 }
 
-func compareIniFromFileAndStringBuilder(configString string, configFile string, path1 string, remote bool, remoteCmd string) error {
+func compareIniFromFileAndStringBuilder(configString string, configFile string, path1 string, remote bool, remoteCmd string) (bool, error) {
 	var sb strings.Builder
 	var configContent []byte
 	var err error
@@ -301,17 +308,17 @@ func compareIniFromFileAndStringBuilder(configString string, configFile string,
 	if !remote {
 		configContent, err = os.ReadFile(configFile)
 		if err != nil {
-			return err
+			return false, common.UsageError("%w", err)
 		}
 	} else {
 		configContent, err = godiff.GetConfigFromRemote(remoteCmd, configFile)
 		if err != nil {
-			return err
+			return false, err
 		}
 	}
-	_, err = CompareIniConfig([]byte(sb.String()), configContent, path1, configFile)
+	result, err := CompareIniConfig([]byte(sb.String()), configContent, path1, configFile)
 	if err != nil {
-		panic(err)
+		return false, err
 	}
-	return nil
+	return result.HasDifferences(), nil
 }
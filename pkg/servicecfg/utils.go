@@ -25,79 +25,101 @@ import (
 	"reflect"
 	"strings"
 
+	"github.com/openstack-k8s-operators/os-diff/pkg/collectcfg"
 	"github.com/openstack-k8s-operators/os-diff/pkg/common"
 	"github.com/openstack-k8s-operators/os-diff/pkg/godiff"
 	"gopkg.in/yaml.v3"
 )
 
-func CompareIniConfig(rawdata1 []byte, rawdata2 []byte, ocpConfig string, serviceConfig string) ([]string, error) {
+func CompareIniConfig(rawdata1 []byte, rawdata2 []byte, ocpConfig string, serviceConfig string) (*godiff.Result, error) {
 
 	// Set empty iniFilters
 	iniFilters := []string{}
-	report, err := godiff.CompareIni(rawdata1, rawdata2, ocpConfig, serviceConfig, false, iniFilters)
+	result, err := godiff.CompareIni(rawdata1, rawdata2, ocpConfig, serviceConfig, false, iniFilters)
 	if err != nil {
-		panic(err)
+		return nil, common.UsageError("%w", err)
 	}
-	godiff.PrintReport(report)
-	return report, nil
+	godiff.Classify(result, serviceConfig)
+	godiff.PublishResult(result, common.DetectType(rawdata1))
+	return result, nil
 }
 
 func GetConfigFromPod(serviceConfigPath string, podName string, containerName string) ([]byte, error) {
+	client, err := connectedKubeClient()
+	if err != nil {
+		return nil, err
+	}
+	fullName, err := GetPodFullName(podName)
+	if err != nil {
+		return nil, common.CollectionError("failed to get pod %s: %w", podName, err)
+	}
+	out, err := client.PodTransport(fullName, containerName).Exec("cat", serviceConfigPath)
+	if err != nil {
+		return out, common.CollectionError("failed to get %s from pod %s: %w", serviceConfigPath, fullName, err)
+	}
+	return out, nil
+}
 
-	if common.TestOCConnection() {
-		fullName, err := GetPodFullName(podName)
-		if err != nil {
-			return nil, err
-		}
-		cmd := exec.Command("oc", "exec", fullName, "-c", containerName, "--", "cat", serviceConfigPath)
-		out, err := cmd.CombinedOutput()
-		if err != nil {
-			fmt.Println(string(out))
-			return out, err
-		}
-		return []byte(out), nil
-
-	} else {
-		return nil, fmt.Errorf("OC is not connected, you need to logged in before.")
+// connectedKubeClient returns the Kubernetes client once its access to the
+// namespace checked.
+func connectedKubeClient() (*collectcfg.KubeClient, error) {
+	client, err := collectcfg.GetKubeClient()
+	if err == nil {
+		err = client.TestConnection()
+	}
+	if err != nil {
+		return nil, common.CollectionError("not connected to OpenShift, you need to logged in before: %w", err)
 	}
+	return client, nil
 }
 
-func GetConfigFromPodman(serviceConfigPath string, podmanName string) ([]byte, error) {
+// Connector to the TripleO hosts and host of the containers read by
+// GetConfigFromPodman, the empty host being the director host.
+var podmanConnect collectcfg.Connector
+var podmanHost string
 
-	cmd := exec.Command("ssh", "-F", "ssh.config", "standalone", "podman", "exec", podmanName, "cat ", serviceConfigPath)
-	out, err := cmd.CombinedOutput()
+// SetPodmanConnector sets the connection to the host of the TripleO
+// containers, as configured for the pull.
+func SetPodmanConnector(connect collectcfg.Connector, host string) {
+	podmanConnect = connect
+	podmanHost = host
+}
+
+// GetConfigFromPodman returns the content of a file of a TripleO container.
+func GetConfigFromPodman(serviceConfigPath string, podmanName string) ([]byte, error) {
+	if podmanConnect == nil {
+		return nil, common.UsageError("no connection configured to the host of the container %s", podmanName)
+	}
+	t, err := podmanConnect(podmanHost)
 	if err != nil {
-		fmt.Println(string(out))
-		return out, err
+		return nil, common.CollectionError("failed to connect to %s: %w", podmanHost, err)
 	}
-	return []byte(out), nil
+	out, err := collectcfg.ContainerFile(t, podmanName, serviceConfigPath)
+	if err != nil {
+		return out, common.CollectionError("failed to get %s from container %s: %w", serviceConfigPath, podmanName, err)
+	}
+	return out, nil
 }
 
 func GenerateOpenShiftConfig(outputConfigPath string, serviceConfigPath string) error {
 	return nil
 }
 
+// GetPodFullName returns the name of a running pod of the workload podName.
 func GetPodFullName(podName string) (string, error) {
-	// Get full pod name
-	cmd := "oc get pod | grep " + podName + " | grep -i running | cut -f 1 -d' '"
-	output, err := exec.Command("bash", "-c", cmd).Output()
-	if err != nil {
-		return string(output), err
-	}
-	return string(output[:len(output)-1]), nil
+	return collectcfg.GetPodId(podName, "")
 }
 
 func GetOCConfigMap(configMapName string) ([]byte, error) {
-	if common.TestOCConnection() {
-		// Get full pod name
-		cmd := "oc get configmap/" + configMapName + " -o yaml"
-		output, err := exec.Command("bash", "-c", cmd).Output()
-		if err != nil {
-			return output, err
-		}
-		return output, nil
+	client, err := connectedKubeClient()
+	if err != nil {
+		return nil, err
+	}
+	output, err := client.ConfigMap(strings.TrimPrefix(configMapName, "configmap/"))
+	if err != nil {
+		return output, common.CollectionError("failed to get configmap %s: %w", configMapName, err)
 	}
-	return nil, fmt.Errorf("oc is not connected, you need to logged in before")
+	return output, nil
 }
 
 func RemoteStatDir(sshCmd string, path string) (bool, error) {
@@ -124,6 +146,10 @@ func LoadServiceConfig(file string) ([]byte, error) {
 }
 
 func LoadFilesIntoMap(fileName string) (map[string]string, error) {
+	// KEY=VALUE files are read with their quoting and export handling
+	if data, err := os.ReadFile(fileName); err == nil && godiff.IsEnvFile(data) {
+		return godiff.ParseEnv(data)
+	}
 	result := make(map[string]string)
 
 	file, err := os.Open(fileName)